@@ -28,6 +28,7 @@ let currentReader = null;
 let scanCancelled = false;
 let msgListener   = null;
 let chainReader   = null;
+let chainID       = null;
 
 // ── Initialization ─────────────────────────────────────────────
 async function init() {
@@ -261,6 +262,18 @@ function renderResult(result) {
 
   resultSections.innerHTML = '';
   resultSections.dataset.result = JSON.stringify(result);
+
+  if (result.matched)
+    addSection('♻️ Готовый результат', [`${result.matched.note} (${result.matched.similarity}%)`], '#94a3b8');
+  if (result.coordinated)
+    addSection('🕸 Координированная сеть', [result.coordinated.warning, ...(result.coordinated.domains || [])], '#f87171');
+  if (result.source_domain) {
+    const d = result.source_domain;
+    const lines = [`${d.domain}: ${d.verdict}${d.category_label ? ' · ' + d.category_label : ''}`];
+    if (d.override) lines.push(`Решение редакции: ${d.override.reason}`);
+    (d.lists || []).forEach(l => lines.push(`Список «${l.source}»${l.notes ? ': ' + l.notes : ''}`));
+    addSection('🏷 Источник в списках', lines, d.verdict === 'надёжный' ? '#4ade80' : '#f87171');
+  }
   
   if (result.fact_check?.found_evidence?.length)
     addSection('✅ Найдены доказательства', result.fact_check.found_evidence, '#4ade80');
@@ -307,6 +320,8 @@ function addSection(title, items, color) {
 // ── Source chain ───────────────────────────────────────────────
 async function startChain(url) {
   chainNodes.innerHTML = '';
+  chainID = null;
+  document.getElementById('btn-chain-share')?.classList.add('hidden');
   chainStatus.textContent = '🔍 Инициализация...';
   showView('chain');
 
@@ -362,6 +377,9 @@ function handleChainEvent(type, ev) {
       break;
     case 'chain_done':
       chainStatus.textContent = ev.message ?? '✅ Готово';
+      if (ev.result) renderChainGraph(ev.result);
+      chainID = ev.result?.id ?? null;
+      if (chainID) document.getElementById('btn-chain-share')?.classList.remove('hidden');
       break;
     case 'chain_error':
       chainStatus.textContent = '❌ ' + (ev.message || 'Ошибка');
@@ -369,7 +387,27 @@ function handleChainEvent(type, ev) {
   }
 }
 
-function renderChainNode(node) {
+// После chain_done перерисовываем узлы: первоисточник сверху,
+// у остальных — откуда заимствован текст.
+function renderChainGraph(result) {
+  const nodes = result.nodes ?? [];
+  const byURL = Object.fromEntries(nodes.map(n => [n.url, n]));
+  chainNodes.innerHTML = '';
+  if (result.origin_reason) {
+    const reason = document.createElement('div');
+    reason.className = 'chain-origin-reason';
+    reason.textContent = '🧭 ' + result.origin_reason;
+    chainNodes.appendChild(reason);
+  }
+  [...nodes].sort((a, b) => (b.is_original ? 1 : 0) - (a.is_original ? 1 : 0)).forEach(node => {
+    const sources = (result.edges ?? [])
+      .filter(e => e.to === node.url && byURL[e.from])
+      .map(e => ({ domain: byURL[e.from].domain, overlap: e.overlap }));
+    renderChainNode(node, sources);
+  });
+}
+
+function renderChainNode(node, sources = []) {
   const div = document.createElement('div');
   div.className = 'chain-node' + (node.is_original ? ' is-original' : '');
 
@@ -378,7 +416,15 @@ function renderChainNode(node) {
 
   const scoreBadge = node.is_original
     ? '<span class="chain-badge-original">ОРИГИНАЛ</span>'
-    : `<span class="chain-score ${scoreClass}">${ds > 0 ? '+' + ds : '✓'} иск.</span>`;
+    : node.is_input
+      ? '<span class="chain-badge-original">ВАША ССЫЛКА</span>'
+      : `<span class="chain-score ${scoreClass}">${ds > 0 ? '+' + ds : '✓'} иск.</span>`;
+
+  const sourcesHTML = sources.length
+    ? '<div class="chain-node-sources">' +
+      sources.map(src => `← ${esc(src.domain)} · ${Math.round(src.overlap)}% совпадения`).join('<br>') +
+      '</div>'
+    : '';
 
   let distHTML = '';
   if (node.distortions?.length) {
@@ -402,6 +448,7 @@ function renderChainNode(node) {
     </div>
     ${node.title ? `<div class="chain-node-title">${esc(node.title)}</div>` : ''}
     ${node.summary ? `<div class="chain-node-summary">${esc(node.summary)}</div>` : ''}
+    ${sourcesHTML}
     ${distHTML}
   `;
   chainNodes.appendChild(div);
@@ -432,6 +479,23 @@ document.getElementById('btn-cancel-chain')?.addEventListener('click', () => {
 
 document.getElementById('btn-chain-new')?.addEventListener('click', () => showView('idle'));
 
+document.getElementById('btn-chain-share')?.addEventListener('click', async () => {
+  if (!chainID) return;
+  try {
+    const resp = await fetch(API_BASE + '/api/share', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ chain_id: chainID }),
+    });
+    if (!resp.ok) throw new Error('HTTP ' + resp.status);
+    const { url } = await resp.json();
+    await navigator.clipboard.writeText(url);
+    chainStatus.textContent = '🔗 Ссылка скопирована: ' + url;
+  } catch (err) {
+    chainStatus.textContent = '❌ Не удалось поделиться: ' + err.message;
+  }
+});
+
 btnAnalyzeUrl.addEventListener('click', async () => {
   const [tab] = await chrome.tabs.query({ active: true, currentWindow: true });
   startScan(tab);
@@ -527,7 +591,7 @@ function extractSources(result) {
   if (!Array.isArray(raw)) return [];
   return raw.map(s => {
     if (typeof s === 'string') return { title: s, url: s, description: '' };
-    return { title: s.title || '', url: s.url || '', description: s.description || '' };
+    return { title: s.title || '', url: s.url || '', description: s.description || '', tier: s.tier || '' };
   }).filter(s => {
     try { new URL(s.url); return true; } catch { return false; }
   });
@@ -555,7 +619,7 @@ function addSourcesSection(sources) {
       const domain = new URL(s.url).hostname.replace(/^www\./, '');
       const badge = document.createElement('span');
       badge.className = 'source-domain';
-      badge.textContent = domain;
+      badge.textContent = s.tier ? `${domain} · ${s.tier}` : domain;
       item.appendChild(badge);
     } catch {}
 
@@ -119,6 +119,14 @@ async function loadLimits() {
                         </div>
                     </div>
                     
+                    ${l.credits != null || l.used_today ? `
+                    <div class="limit-item">
+                        <div class="li-info">
+                            <span class="li-lbl">Credits</span>
+                            <span class="li-val">${l.credits != null ? formatN(l.credits) : '—'} · today ${l.used_today || 0}</span>
+                        </div>
+                    </div>` : ''}
+
                     <div class="reset-box">
                         <span>Reset Req: <b class="reset-timer" data-until="${l.reset_requests_at || 0}">${l.reset_requests || '—'}</b></span>
                         <span>Reset Tok: <b class="reset-timer" data-until="${l.reset_tokens_at || 0}">${l.reset_tokens || '—'}</b></span>
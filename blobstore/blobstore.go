@@ -0,0 +1,54 @@
+package blobstore
+
+import (
+	"errors"
+	"log"
+)
+
+// ErrNotFound возвращается, если объекта с таким ключом нет.
+var ErrNotFound = errors.New("объект не найден")
+
+// Store — хранилище неизменяемых объектов (снимков страниц и т.п.).
+type Store interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Exists(key string) (bool, error)
+}
+
+// Default — хранилище, выбранное при старте (nil — хранение отключено).
+var Default Store
+
+// Config — параметры выбора хранилища.
+type Config struct {
+	Kind        string // "fs" (по умолчанию), "s3" или "none"
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+func Init(cfg Config) {
+	switch cfg.Kind {
+	case "none":
+		log.Println("⚠️ Хранилище снимков отключено (BLOB_STORE=none)")
+		return
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			log.Println("⚠️ S3_ENDPOINT или S3_BUCKET не заданы, снимки страниц не сохраняются")
+			return
+		}
+		Default = NewS3Store(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle)
+		log.Printf("✓ Хранилище снимков: S3 %s/%s", cfg.S3Endpoint, cfg.S3Bucket)
+	default:
+		store, err := NewFSStore(cfg.Dir)
+		if err != nil {
+			log.Printf("⚠️ Хранилище снимков недоступно: %v", err)
+			return
+		}
+		Default = store
+		log.Printf("✓ Хранилище снимков: %s", cfg.Dir)
+	}
+}
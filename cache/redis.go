@@ -44,3 +44,23 @@ func Set(key string, value string, expiration time.Duration) error {
 	}
 	return RDB.Set(ctx, key, value, expiration).Err()
 }
+
+// Incr атомарно увеличивает счётчик и при первом увеличении ставит срок жизни.
+func Incr(key string, expiration time.Duration) (int64, error) {
+	if RDB == nil {
+		return 0, redis.Nil
+	}
+	n, err := RDB.Incr(ctx, key).Result()
+	if err == nil && n == 1 {
+		RDB.Expire(ctx, key, expiration)
+	}
+	return n, err
+}
+
+// Decr атомарно уменьшает счётчик (откат Incr).
+func Decr(key string) (int64, error) {
+	if RDB == nil {
+		return 0, redis.Nil
+	}
+	return RDB.Decr(ctx, key).Result()
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,11 +18,93 @@ type Config struct {
 	GroqAPIKeys           []string
 	GroqModel             string
 	SerperAPIKey          string
+	SearxngURL            string
+	BraveAPIKey           string
+	SearchLocales         string // "md:ru,us:en,md:ro" — страна:язык[:название]
+	SearchTimeout         time.Duration
+	SearchCacheTTL        time.Duration
+	SearchMaxPerRequest   int
+	SearchMaxPerDay       int
+	SearchQueryPlanner    string // heuristic | ai
+	SearchTrustedDomains  []string
+	SearchBlockedDomains  []string
+	SearchFactCheckers    []string
 	GoogleFactCheckAPIKey string
 	Port                  string
 	DbUrl                 string
 	RedisUrl              string
 	AdminToken            string
+
+	// Политика загрузки страниц по URL от пользователя
+	FetchMaxBytes     int64
+	FetchMaxRedirects int
+	FetchTimeout      time.Duration
+	FetchAllowHosts   []string
+	FetchDenyHosts    []string
+	FetchAllowPrivate bool
+
+	// «Вежливая» загрузка: User-Agent, robots.txt, лимиты на хост, HTTP-кэш
+	FetchUserAgent       string
+	FetchRespectRobots   bool
+	FetchHostConcurrency int
+	FetchHostInterval    time.Duration
+	FetchCacheTTL        time.Duration
+	ArchiveAPIURL        string // пусто — фолбек на Wayback Machine отключён
+
+	// Хранилище снимков страниц: fs (по умолчанию), s3 или none
+	BlobStore   string
+	BlobDir     string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+
+	// Фоновая перепроверка страниц с низкой оценкой на тихие правки (0 — выключено)
+	EditWatchInterval time.Duration
+	EditWatchMaxScore int
+	EditWatchMaxAge   time.Duration
+	EditWatchBatch    int
+
+	// Цепочки источников
+	ChainWorkers    int
+	ChainMaxBreadth int
+	ChainMaxDepth   int
+	ChainMaxNodes   int
+	ChainCacheTTL   time.Duration
+
+	// Кластеризация анализов в нарративы
+	NarrativeThreshold int // порог похожести, %
+	NarrativeWindow    time.Duration
+	NarrativeInterval  time.Duration
+	NarrativeBatch     int
+
+	// Поиск согласованных публикаций одного текста на разных доменах
+	CoordMinOverlap int // порог совпадения текстов, %
+	CoordWindow     time.Duration
+	CoordMinDomains int
+	CoordLookback   time.Duration
+	CoordInterval   time.Duration
+	CoordBatch      int
+
+	// Готовый результат для почти того же текста (simhash)
+	ReuseMinSimilarity int // % совпадающих бит, 0 — выключено
+	ReuseMaxAge        time.Duration
+
+	// Репутация доменов: байесовское сглаживание и затухание
+	DomainPrior       int // априорная оценка 0..10
+	DomainPriorWeight int // вес априорной оценки, в анализах
+	DomainHalfLife    time.Duration
+
+	// Пакетный поиск и снимок вердиктов доменов для клиентов
+	DomainLookupMax      int // доменов в одном запросе /api/domains/lookup
+	DomainSnapshotPeriod time.Duration
+
+	// Сбор ClaimReview со страниц фактчекеров
+	ClaimReviewSeeds    []string
+	ClaimReviewInterval time.Duration
+	ClaimReviewPerRun   int
 }
 
 func Load() (*Config, error) {
@@ -41,6 +126,11 @@ func Load() (*Config, error) {
 		}
 	}
 
+	archiveAPIURL := getEnvOrDefault("ARCHIVE_API_URL", "https://archive.org/wayback/available")
+	if os.Getenv("ARCHIVE_FALLBACK") == "false" {
+		archiveAPIURL = ""
+	}
+
 	return &Config{
 		OpenRouterAPIKey:      os.Getenv("OPENROUTER_API_KEY"),
 		OpenRouterModel:       getEnvOrDefault("OPENROUTER_MODEL", "nvidia/nemotron-3-nano-30b-a3b:free"),
@@ -49,11 +139,71 @@ func Load() (*Config, error) {
 		GroqAPIKeys:           groqKeys,
 		GroqModel:             getEnvOrDefault("GROQ_MODEL", "llama-3.3-70b-versatile"),
 		SerperAPIKey:          os.Getenv("SERPER_API_KEY"),
+		SearxngURL:            os.Getenv("SEARXNG_URL"),
+		BraveAPIKey:           os.Getenv("BRAVE_API_KEY"),
+		SearchLocales:         os.Getenv("SEARCH_LOCALES"),
+		SearchTimeout:         getEnvDuration("SEARCH_TIMEOUT", 10*time.Second),
+		SearchCacheTTL:        getEnvDuration("SEARCH_CACHE_TTL", 6*time.Hour),
+		SearchMaxPerRequest:   getEnvInt("SEARCH_MAX_PER_REQUEST", -1),
+		SearchMaxPerDay:       getEnvInt("SEARCH_MAX_PER_DAY", 0),
+		SearchQueryPlanner:    getEnvOrDefault("SEARCH_QUERY_PLANNER", "heuristic"),
+		SearchTrustedDomains:  getEnvList("SEARCH_TRUSTED_DOMAINS"),
+		SearchBlockedDomains:  getEnvList("SEARCH_BLOCKED_DOMAINS"),
+		SearchFactCheckers:    getEnvList("SEARCH_FACTCHECK_DOMAINS"),
 		GoogleFactCheckAPIKey: os.Getenv("GOOGLE_FACT_CHECK_API_KEY"),
 		Port:                  getEnvOrDefault("PORT", "8080"),
 		DbUrl:                 os.Getenv("DB_URL"),
 		RedisUrl:              os.Getenv("REDIS_URL"),
 		AdminToken:            getEnvOrDefault("ADMIN_TOKEN", "admin_secret_123"),
+		FetchMaxBytes:         int64(getEnvInt("FETCH_MAX_BYTES", 5<<20)),
+		FetchMaxRedirects:     getEnvInt("FETCH_MAX_REDIRECTS", 5),
+		FetchTimeout:          getEnvDuration("FETCH_TIMEOUT", 30*time.Second),
+		FetchAllowHosts:       getEnvList("FETCH_ALLOW_HOSTS"),
+		FetchDenyHosts:        getEnvList("FETCH_DENY_HOSTS"),
+		FetchAllowPrivate:     os.Getenv("FETCH_ALLOW_PRIVATE") == "true",
+		FetchUserAgent:        getEnvOrDefault("FETCH_USER_AGENT", "TextAnalyzerBot/1.0 (fact-checking service)"),
+		FetchRespectRobots:    os.Getenv("FETCH_RESPECT_ROBOTS") == "true",
+		FetchHostConcurrency:  getEnvInt("FETCH_HOST_CONCURRENCY", 2),
+		FetchHostInterval:     getEnvDuration("FETCH_HOST_INTERVAL", time.Second),
+		FetchCacheTTL:         getEnvDuration("FETCH_CACHE_TTL", 10*time.Minute),
+		ArchiveAPIURL:         archiveAPIURL,
+		BlobStore:             getEnvOrDefault("BLOB_STORE", "fs"),
+		BlobDir:               getEnvOrDefault("BLOB_DIR", "data/blobs"),
+		S3Endpoint:            os.Getenv("S3_ENDPOINT"),
+		S3Bucket:              os.Getenv("S3_BUCKET"),
+		S3Region:              getEnvOrDefault("S3_REGION", "us-east-1"),
+		S3AccessKey:           os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:           os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle:           os.Getenv("S3_PATH_STYLE") != "false",
+		EditWatchInterval:     getEnvDuration("EDIT_WATCH_INTERVAL", 0),
+		EditWatchMaxScore:     getEnvInt("EDIT_WATCH_MAX_SCORE", 4),
+		EditWatchMaxAge:       getEnvDuration("EDIT_WATCH_MAX_AGE", 30*24*time.Hour),
+		EditWatchBatch:        getEnvInt("EDIT_WATCH_BATCH", 20),
+		ChainWorkers:          getEnvInt("CHAIN_WORKERS", 3),
+		ChainMaxBreadth:       getEnvInt("CHAIN_MAX_BREADTH", 10),
+		ChainMaxDepth:         getEnvInt("CHAIN_MAX_DEPTH", 3),
+		ChainMaxNodes:         getEnvInt("CHAIN_MAX_NODES", 20),
+		ChainCacheTTL:         getEnvDuration("CHAIN_CACHE_TTL", 24*time.Hour),
+		NarrativeThreshold:    getEnvInt("NARRATIVE_MIN_SIMILARITY", 35),
+		NarrativeWindow:       getEnvDuration("NARRATIVE_WINDOW", 14*24*time.Hour),
+		NarrativeInterval:     getEnvDuration("NARRATIVE_INTERVAL", 10*time.Minute),
+		NarrativeBatch:        getEnvInt("NARRATIVE_BATCH", 200),
+		CoordMinOverlap:       getEnvInt("COORD_MIN_OVERLAP", 60),
+		CoordWindow:           getEnvDuration("COORD_WINDOW", 24*time.Hour),
+		CoordMinDomains:       getEnvInt("COORD_MIN_DOMAINS", 3),
+		CoordLookback:         getEnvDuration("COORD_LOOKBACK", 72*time.Hour),
+		CoordInterval:         getEnvDuration("COORD_INTERVAL", 30*time.Minute),
+		CoordBatch:            getEnvInt("COORD_BATCH", 1000),
+		ReuseMinSimilarity:    getEnvInt("REUSE_MIN_SIMILARITY", 90),
+		ReuseMaxAge:           getEnvDuration("REUSE_MAX_AGE", 7*24*time.Hour),
+		DomainPrior:           getEnvInt("DOMAIN_PRIOR", 5),
+		DomainPriorWeight:     getEnvInt("DOMAIN_PRIOR_WEIGHT", 5),
+		DomainHalfLife:        getEnvDuration("DOMAIN_HALF_LIFE", 90*24*time.Hour),
+		DomainLookupMax:       getEnvInt("DOMAIN_LOOKUP_MAX", 500),
+		DomainSnapshotPeriod:  getEnvDuration("DOMAIN_SNAPSHOT_INTERVAL", 10*time.Minute),
+		ClaimReviewSeeds:      getEnvList("CLAIMREVIEW_SEEDS"),
+		ClaimReviewInterval:   getEnvDuration("CLAIMREVIEW_INTERVAL", 6*time.Hour),
+		ClaimReviewPerRun:     getEnvInt("CLAIMREVIEW_PER_RUN", 30),
 	}, nil
 }
 
@@ -63,3 +213,32 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList разбирает список через запятую, пустые элементы отбрасываются.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -66,4 +66,228 @@ func InitDB(url string) {
 	if err != nil {
 		log.Fatalf("❌ Ошибка создания таблицы shared_results: %v", err)
 	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS snapshots (
+			id          TEXT PRIMARY KEY,
+			url         TEXT NOT NULL,
+			final_url   TEXT,
+			text_sha256 TEXT,
+			fetched_at  TIMESTAMPTZ DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS snapshots_url_idx ON snapshots (url, fetched_at DESC);
+		ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS snapshot_id TEXT;
+		ALTER TABLE shared_results   ADD COLUMN IF NOT EXISTS snapshot_id TEXT;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы snapshots: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE snapshots ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ DEFAULT NOW();
+		CREATE TABLE IF NOT EXISTS edit_events (
+			id                   SERIAL PRIMARY KEY,
+			url                  TEXT NOT NULL,
+			previous_snapshot_id TEXT,
+			snapshot_id          TEXT,
+			added                INTEGER DEFAULT 0,
+			removed              INTEGER DEFAULT 0,
+			changed              INTEGER DEFAULT 0,
+			diff                 JSONB,
+			detected_at          TIMESTAMPTZ DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS edit_events_url_idx ON edit_events (url, detected_at DESC);
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы edit_events: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS claim_reviews (
+			id             SERIAL PRIMARY KEY,
+			claim_text     TEXT NOT NULL,
+			claim_hash     TEXT NOT NULL,
+			claimant       TEXT,
+			claim_date     TIMESTAMPTZ,
+			rating         TEXT,
+			publisher      TEXT,
+			publisher_site TEXT,
+			review_url     TEXT NOT NULL,
+			title          TEXT,
+			review_date    TIMESTAMPTZ,
+			language       TEXT,
+			source         TEXT,
+			created_at     TIMESTAMPTZ DEFAULT NOW(),
+			updated_at     TIMESTAMPTZ DEFAULT NOW(),
+			search_tsv     TSVECTOR GENERATED ALWAYS AS
+				(to_tsvector('simple', claim_text || ' ' || COALESCE(title, ''))) STORED,
+			UNIQUE (review_url, claim_hash)
+		);
+		CREATE INDEX IF NOT EXISTS claim_reviews_tsv_idx ON claim_reviews USING GIN (search_tsv);
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы claim_reviews: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS rated_analyses INTEGER DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS sum_rating     INTEGER DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS avg_rating     FLOAT;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка обновления таблицы domain_stats: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS chains (
+			id         TEXT PRIMARY KEY,
+			input_url  TEXT NOT NULL,
+			origin_url TEXT,
+			topic      TEXT,
+			breadth    INTEGER,
+			depth      INTEGER,
+			node_count INTEGER,
+			result     JSONB NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS chains_input_url_idx ON chains (input_url, created_at DESC);
+		ALTER TABLE shared_results ADD COLUMN IF NOT EXISTS chain_id TEXT;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы chains: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS narratives (
+			id            SERIAL PRIMARY KEY,
+			label         TEXT,
+			keywords      TEXT[],
+			terms         JSONB,
+			article_count INTEGER DEFAULT 0,
+			sum_score     INTEGER DEFAULT 0,
+			avg_score     FLOAT,
+			first_seen    TIMESTAMPTZ DEFAULT NOW(),
+			last_seen     TIMESTAMPTZ DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS narratives_last_seen_idx ON narratives (last_seen DESC);
+		CREATE TABLE IF NOT EXISTS narrative_members (
+			narrative_id      INTEGER REFERENCES narratives(id) ON DELETE CASCADE,
+			analysis_id       INTEGER,
+			url               TEXT,
+			domain            TEXT,
+			title             TEXT,
+			credibility_score INTEGER,
+			similarity        FLOAT,
+			added_at          TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (narrative_id, analysis_id)
+		);
+		CREATE INDEX IF NOT EXISTS narrative_members_added_idx ON narrative_members (added_at DESC);
+		ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS narrative_id INTEGER;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы narratives: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+		CREATE TABLE IF NOT EXISTS coordinated_clusters (
+			id              SERIAL PRIMARY KEY,
+			sample          TEXT,
+			article_count   INTEGER DEFAULT 0,
+			domain_count    INTEGER DEFAULT 0,
+			first_published TIMESTAMPTZ,
+			last_published  TIMESTAMPTZ,
+			created_at      TIMESTAMPTZ DEFAULT NOW(),
+			updated_at      TIMESTAMPTZ DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS coordinated_members (
+			cluster_id   INTEGER REFERENCES coordinated_clusters(id) ON DELETE CASCADE,
+			analysis_id  INTEGER,
+			url          TEXT,
+			domain       TEXT,
+			published_at TIMESTAMPTZ,
+			sketch       BIGINT[],
+			PRIMARY KEY (cluster_id, analysis_id)
+		);
+		CREATE INDEX IF NOT EXISTS coordinated_members_analysis_idx ON coordinated_members (analysis_id);
+		CREATE INDEX IF NOT EXISTS coordinated_members_domain_idx ON coordinated_members (domain);
+		CREATE INDEX IF NOT EXISTS coordinated_members_sketch_idx ON coordinated_members USING GIN (sketch);
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS coordinated_clusters INTEGER DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS coordinated_at       TIMESTAMPTZ;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы coordinated_clusters: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS simhash     BIGINT;
+		ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS reused_from INTEGER;
+		CREATE INDEX IF NOT EXISTS analysis_results_simhash_idx ON analysis_results (created_at DESC) WHERE simhash IS NOT NULL;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка обновления таблицы analysis_results: %v", err)
+	}
+
+	// Затухающие суммы для репутации. Оценок отдельных анализов в
+	// domain_stats нет, поэтому Σx² заполняется средним и априорным
+	// разбросом (σ = 2, как reputationPriorSD), а не нулевой дисперсией.
+	_, err = DB.Exec(`
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS decayed_weight FLOAT DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS decayed_sum    FLOAT DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS decayed_sq     FLOAT DEFAULT 0;
+		ALTER TABLE domain_stats ADD COLUMN IF NOT EXISTS decayed_at     TIMESTAMPTZ;
+		UPDATE domain_stats SET
+			decayed_weight = total_analyses,
+			decayed_sum    = sum_scores,
+			decayed_sq     = sum_scores::float * avg_score + total_analyses * 4.0,
+			decayed_at     = last_analyzed_at
+		WHERE decayed_at IS NULL AND total_analyses > 0;
+		CREATE TABLE IF NOT EXISTS domain_stats_history (
+			domain          TEXT NOT NULL,
+			day             DATE NOT NULL,
+			analyses        INTEGER DEFAULT 0,
+			sum_scores      INTEGER DEFAULT 0,
+			avg_score       FLOAT,
+			reputation      FLOAT,
+			reputation_low  FLOAT,
+			reputation_high FLOAT,
+			PRIMARY KEY (domain, day)
+		);
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы domain_stats_history: %v", err)
+	}
+
+	// Курируемые списки доменов и ручные решения администратора
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS domain_lists (
+			domain      TEXT NOT NULL,
+			category    TEXT NOT NULL,
+			source      TEXT NOT NULL,
+			notes       TEXT,
+			imported_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (domain, source)
+		);
+		CREATE INDEX IF NOT EXISTS domain_lists_source_idx ON domain_lists (source);
+
+		CREATE TABLE IF NOT EXISTS domain_overrides (
+			domain     TEXT PRIMARY KEY,
+			verdict    TEXT,
+			category   TEXT,
+			reason     TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		);
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка создания таблицы domain_lists: %v", err)
+	}
+
+	// ClaimReview публикуются только по анализу, сохранённому на сервере
+	_, err = DB.Exec(`
+		ALTER TABLE shared_results ADD COLUMN IF NOT EXISTS analysis_id INTEGER;
+	`)
+	if err != nil {
+		log.Fatalf("❌ Ошибка обновления таблицы shared_results: %v", err)
+	}
 }
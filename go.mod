@@ -2,13 +2,15 @@ module text-analyzer
 
 go 1.24.0
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/gorilla/websocket v1.5.3
+	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.11.2
+	golang.org/x/net v0.50.0
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/go-redis/redis/v8 v8.11.5 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/lib/pq v1.11.2 // indirect
-	golang.org/x/net v0.50.0 // indirect
 )
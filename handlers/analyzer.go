@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"text-analyzer/models"
 	"text-analyzer/services"
 	"time"
@@ -58,15 +59,20 @@ func (h *AnalyzerHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, err := analyzeOptions(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var result *models.AnalysisResponse
-	var err error
 
 	if req.URL != "" {
 		log.Printf("[HANDLER] 🌐 Анализ URL: %s", req.URL)
-		result, err = h.service.AnalyzeURL(req.URL)
+		result, err = h.service.AnalyzeURLWithOptions(req.URL, opts)
 	} else if req.Text != "" {
 		log.Printf("[HANDLER] 📝 Анализ текста (%d символов)", len(req.Text))
-		result, err = h.service.AnalyzeText(req.Text)
+		result, err = h.service.AnalyzeTextWithOptions(req.Text, opts)
 	} else {
 		http.Error(w, "Необходимо указать 'text' или 'url'", http.StatusBadRequest)
 		return
@@ -118,6 +124,10 @@ func (h *AnalyzerHandler) AnalyzeStream(w http.ResponseWriter, r *http.Request)
 	} else if r.Method == http.MethodGet {
 		req.URL = r.URL.Query().Get("url")
 		req.Text = r.URL.Query().Get("text")
+		if locales := r.URL.Query().Get("locales"); locales != "" {
+			req.Locales = strings.Split(locales, ",")
+		}
+		req.Fresh = r.URL.Query().Get("fresh") == "true"
 	} else {
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
@@ -127,6 +137,11 @@ func (h *AnalyzerHandler) AnalyzeStream(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Необходимо указать 'text' или 'url'", http.StatusBadRequest)
 		return
 	}
+	opts, err := analyzeOptions(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// SSE заголовки
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -153,13 +168,12 @@ func (h *AnalyzerHandler) AnalyzeStream(w http.ResponseWriter, r *http.Request)
 	sendEvent("start", "🚀 Начинаю проверку...")
 
 	var result *models.AnalysisResponse
-	var err error
 
 	if req.URL != "" {
-		result, err = h.service.AnalyzeURL(req.URL, sendProgress)
+		result, err = h.service.AnalyzeURLWithOptions(req.URL, opts, sendProgress)
 	} else {
 		sendProgress(fmt.Sprintf("📄 Текст получен (%d символов), начинаю проверку...", len(req.Text)))
-		result, err = h.service.AnalyzeText(req.Text, sendProgress)
+		result, err = h.service.AnalyzeTextWithOptions(req.Text, opts, sendProgress)
 	}
 
 	if err != nil {
@@ -172,6 +186,22 @@ func (h *AnalyzerHandler) AnalyzeStream(w http.ResponseWriter, r *http.Request)
 	sendEvent("done", "✅ Проверка завершена!")
 }
 
+// analyzeOptions разбирает параметры запроса, влияющие на анализ.
+func analyzeOptions(req models.AnalysisRequest) (services.AnalyzeOptions, error) {
+	opts := services.AnalyzeOptions{Fresh: req.Fresh}
+	if len(req.Locales) > 0 {
+		locales, err := services.ParseSearchLocales(strings.Join(req.Locales, ","))
+		if err != nil {
+			return opts, err
+		}
+		if len(locales) > 5 {
+			return opts, fmt.Errorf("не больше 5 локалей поиска")
+		}
+		opts.SearchLocales = locales
+	}
+	return opts, nil
+}
+
 func (h *AnalyzerHandler) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -181,6 +211,9 @@ func (h *AnalyzerHandler) Health(w http.ResponseWriter, r *http.Request) {
 func (h *AnalyzerHandler) Limits(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if h.service != nil {
+		h.service.RefreshSearchLimits()
+	}
 	json.NewEncoder(w).Encode(services.GetRateLimits())
 }
 
@@ -3,9 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"text-analyzer/services"
 )
 
@@ -19,7 +21,7 @@ func NewChainHandler(service *services.ChainService) *ChainHandler {
 }
 
 // Stream — SSE endpoint POST /api/chain/stream.
-// Принимает { "url": "..." }, стримит chain_* события.
+// Принимает { "url": "...", "breadth": 5, "depth": 1, "fresh": false }, стримит chain_* события.
 func (h *ChainHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
@@ -35,7 +37,10 @@ func (h *ChainHandler) Stream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		URL string `json:"url"`
+		URL     string `json:"url"`
+		Breadth int    `json:"breadth,omitempty"` // производных статей на узел
+		Depth   int    `json:"depth,omitempty"`   // уровней расширения
+		Fresh   bool   `json:"fresh,omitempty"`   // построить заново, минуя кэш
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
 		http.Error(w, "Необходимо указать 'url'", http.StatusBadRequest)
@@ -68,9 +73,70 @@ func (h *ChainHandler) Stream(w http.ResponseWriter, r *http.Request) {
 		sendSSE(ev.Type, string(data))
 	}
 
-	if err := h.service.BuildChain(ctx, req.URL, emit); err != nil {
+	opts := services.ChainOptions{Breadth: req.Breadth, Depth: req.Depth, Fresh: req.Fresh}
+	if err := h.service.BuildChain(ctx, req.URL, opts, emit); err != nil {
 		log.Printf("[CHAIN] ❌ Ошибка: %v", err)
 		errData, _ := json.Marshal(map[string]string{"message": err.Error()})
 		sendSSE("chain_error", string(errData))
 	}
 }
+
+// Get — GET /api/chain/{id} → сохранённая цепочка (ChainResult).
+// ?format=dot|graphml|jgf — граф для Gephi / yEd / Graphviz, ?format=svg — картинка для отчёта.
+func (h *ChainHandler) Get(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/chain/"), "/")
+	if id == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "не найдено"})
+		return
+	}
+
+	chain, err := services.LoadChain(id)
+	if errors.Is(err, services.ErrChainNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "хранилище недоступно"})
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		json.NewEncoder(w).Encode(chain)
+	case "dot":
+		chainDownload(w, id, "dot", "text/vnd.graphviz; charset=utf-8")
+		w.Write([]byte(services.ChainDOT(chain)))
+	case "graphml":
+		data, err := services.ChainGraphML(chain)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		chainDownload(w, id, "graphml", "application/graphml+xml; charset=utf-8")
+		w.Write(data)
+	case "jgf":
+		chainDownload(w, id, "json", "application/vnd.jgf+json")
+		json.NewEncoder(w).Encode(services.ChainJGF(chain))
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		w.Write([]byte(services.ChainSVG(chain)))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "format: json, dot, graphml, jgf или svg"})
+	}
+}
+
+// chainDownload выставляет заголовки для скачивания экспорта цепочки.
+func chainDownload(w http.ResponseWriter, id, ext, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chain-%s.%s"`, id, ext))
+}
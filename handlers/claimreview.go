@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"text-analyzer/models"
+	"text-analyzer/services"
+)
+
+// ClaimReviewHandler — поиск по базе проверок фактов и её пополнение.
+type ClaimReviewHandler struct {
+	fetcher *services.ContentFetcher
+}
+
+func NewClaimReviewHandler(fetcher *services.ContentFetcher) *ClaimReviewHandler {
+	return &ClaimReviewHandler{fetcher: fetcher}
+}
+
+// Search — GET /api/claim-reviews?q=<текст>[&limit=N]
+func (h *ClaimReviewHandler) Search(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "параметр q обязателен"})
+		return
+	}
+	limit := 10
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 50 {
+		limit = n
+	}
+
+	matches := services.MatchClaimReviews(q, nil, limit)
+	if matches == nil {
+		matches = []models.ClaimReviewMatch{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"matches": matches,
+		"total":   len(matches),
+	})
+}
+
+type claimReviewImport struct {
+	Reviews []models.ClaimReview `json:"reviews"` // ручной импорт
+	URLs    []string             `json:"urls"`    // страницы с разметкой ClaimReview
+}
+
+// Import — POST /api/admin/claim-reviews
+func (h *ClaimReviewHandler) Import(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req claimReviewImport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "неверный JSON"})
+		return
+	}
+
+	var reviews []models.ClaimReview
+	for _, rv := range req.Reviews {
+		if strings.TrimSpace(rv.ClaimText) == "" || rv.URL == "" {
+			continue
+		}
+		rv.Source = "manual"
+		if rv.PublisherSite == "" {
+			rv.PublisherSite = services.URLHost(rv.URL)
+		}
+		reviews = append(reviews, rv)
+	}
+
+	errs := map[string]string{}
+	for _, u := range req.URLs {
+		found, _, err := h.fetcher.ScrapeClaimReviews(u)
+		if err != nil {
+			errs[u] = err.Error()
+			continue
+		}
+		if len(found) == 0 {
+			errs[u] = "разметка ClaimReview не найдена"
+			continue
+		}
+		reviews = append(reviews, found...)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": services.SaveClaimReviews(reviews),
+		"errors":   errs,
+	})
+}
@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"text-analyzer/database"
+	"text-analyzer/models"
 	"text-analyzer/services"
 )
 
-type DomainHandler struct{}
+type DomainHandler struct {
+	snapshots *services.DomainSnapshotter
+	lookupMax int
+}
 
-func NewDomainHandler() *DomainHandler { return &DomainHandler{} }
+func NewDomainHandler(snapshots *services.DomainSnapshotter, lookupMax int) *DomainHandler {
+	return &DomainHandler{snapshots: snapshots, lookupMax: lookupMax}
+}
 
 type DomainStats struct {
 	Domain         string  `json:"domain"`
@@ -18,16 +26,46 @@ type DomainStats struct {
 	AvgScore       float64 `json:"avg_score"`
 	Verdict        string  `json:"verdict"`
 	LastAnalyzedAt string  `json:"last_analyzed_at"`
+	// Средний вердикт на единой шкале 1–5 (только анализы с оценкой на шкале)
+	AvgRating  *float64 `json:"avg_rating,omitempty"`
+	RatingName string   `json:"rating_name,omitempty"`
+	// В скольких сетях согласованных публикаций замечен домен
+	CoordinatedClusters int `json:"coordinated_clusters,omitempty"`
+	// Сглаженная оценка с доверительным интервалом; по ней выносится verdict
+	Reputation services.DomainReputation     `json:"reputation"`
+	Trend      *services.DomainTrend         `json:"trend,omitempty"`
+	History    []services.DomainHistoryPoint `json:"history,omitempty"`
+	// Курируемые списки и ручное решение важнее вычисленной оценки
+	Curation      *models.DomainCuration `json:"curation,omitempty"`
+	VerdictSource string                 `json:"verdict_source"` // override | curated | computed
+	// Издатель на платформе — её имя; у платформы — сводка по издателям
+	Parent     string                 `json:"parent,omitempty"`
+	Platform   bool                   `json:"platform,omitempty"`
+	Publishers int                    `json:"publishers,omitempty"`
+	Children   []services.DomainChild `json:"children,omitempty"`
+}
+
+func (s *DomainStats) applyReputation(sums services.ReputationSums) {
+	s.Reputation = sums.Reputation()
+	s.Verdict = services.DomainVerdict(s.Reputation.Score)
+}
+
+// applyCuration заменяет вычисленный вердикт курируемым, если он есть.
+func (s *DomainStats) applyCuration(c *models.DomainCuration) {
+	s.VerdictSource = "computed"
+	if c == nil {
+		return
+	}
+	s.Curation = c
+	if c.Verdict != "" {
+		s.Verdict, s.VerdictSource = c.Verdict, c.VerdictSource
+	}
 }
 
-func verdictFromScore(avg float64) string {
-	switch {
-	case avg >= 7:
-		return "надёжный"
-	case avg >= 4:
-		return "сомнительный"
-	default:
-		return "ненадёжный"
+func (s *DomainStats) applyRating(avg sql.NullFloat64) {
+	if avg.Valid {
+		s.AvgRating = &avg.Float64
+		s.RatingName = services.RatingNameForValue(avg.Float64)
 	}
 }
 
@@ -36,7 +74,12 @@ func domainCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 }
 
-// GetDomain — GET /api/domain/<domain>
+// GetDomain — GET /api/domain/<domain>[?days=90] — статистика, репутация,
+// тренд за 30 дней, дневной ряд за days дней и курируемые сведения.
+// Домен без анализов, но из списков, тоже отдаётся. Поддомены сводятся
+// к регистрируемому домену, издатель на платформе запрашивается как
+// /api/domain/t.me/channel, а платформа (/api/domain/t.me) отдаёт
+// сводку по всем своим издателям.
 func (h *DomainHandler) GetDomain(w http.ResponseWriter, r *http.Request) {
 	domainCORSHeaders(w)
 	raw := strings.TrimPrefix(r.URL.Path, "/api/domain/")
@@ -47,17 +90,49 @@ func (h *DomainHandler) GetDomain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	days := 90
+	if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n > 0 && n <= 730 {
+		days = n
+	}
+
 	var s DomainStats
+	var avgRating sql.NullFloat64
+	var sums services.ReputationSums
 	err := database.DB.QueryRow(`
-		SELECT domain, total_analyses, avg_score, last_analyzed_at
+		SELECT domain, total_analyses, avg_score, last_analyzed_at, avg_rating,
+			COALESCE(coordinated_clusters, 0), `+services.ReputationColumns+`
 		FROM domain_stats WHERE domain = $1
-	`, domain).Scan(&s.Domain, &s.TotalAnalyses, &s.AvgScore, &s.LastAnalyzedAt)
+	`, domain).Scan(append([]interface{}{&s.Domain, &s.TotalAnalyses, &s.AvgScore, &s.LastAnalyzedAt, &avgRating, &s.CoordinatedClusters}, sums.Dest()...)...)
+	curation := services.LookupCuration(domain)
+	var publishers []services.DomainChild
+	if services.IsPlatform(domain) {
+		agg, total, sumScores, children, count, aggErr := services.PlatformAggregate(domain, 20)
+		if aggErr == nil && total > 0 {
+			if err != nil {
+				s = DomainStats{Domain: domain}
+			}
+			err = nil
+			s.TotalAnalyses, s.AvgScore, sums = total, float64(sumScores)/float64(total), agg
+			s.Publishers, publishers = count, children
+		}
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "домен не найден"})
-		return
+		if curation == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "домен не найден"})
+			return
+		}
+		s = DomainStats{Domain: domain}
+		sums = services.ReputationSums{}
+	}
+	s.applyReputation(sums)
+	s.applyRating(avgRating)
+	s.applyCuration(curation)
+	s.Parent, s.Platform, s.Children = services.ParentDomain(domain), services.IsPlatform(domain), publishers
+	if history, err := services.DomainHistory(domain, days); err == nil && !s.Platform {
+		trend := services.DomainTrendOf(history, s.Reputation.Score, 30)
+		s.Trend, s.History = &trend, history
 	}
-	s.Verdict = verdictFromScore(s.AvgScore)
 	json.NewEncoder(w).Encode(s)
 }
 
@@ -70,7 +145,8 @@ func (h *DomainHandler) GetTopDomains(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rows, err := database.DB.Query(`
-		SELECT domain, total_analyses, avg_score, last_analyzed_at
+		SELECT domain, total_analyses, avg_score, last_analyzed_at, avg_rating,
+			COALESCE(coordinated_clusters, 0), ` + services.ReputationColumns + `
 		FROM domain_stats
 		ORDER BY total_analyses DESC
 		LIMIT 20
@@ -84,12 +160,24 @@ func (h *DomainHandler) GetTopDomains(w http.ResponseWriter, r *http.Request) {
 	var list []DomainStats
 	for rows.Next() {
 		var s DomainStats
-		rows.Scan(&s.Domain, &s.TotalAnalyses, &s.AvgScore, &s.LastAnalyzedAt)
-		s.Verdict = verdictFromScore(s.AvgScore)
+		var avgRating sql.NullFloat64
+		var sums services.ReputationSums
+		rows.Scan(append([]interface{}{&s.Domain, &s.TotalAnalyses, &s.AvgScore, &s.LastAnalyzedAt, &avgRating, &s.CoordinatedClusters}, sums.Dest()...)...)
+		s.applyReputation(sums)
+		s.applyRating(avgRating)
+		s.Parent, s.Platform = services.ParentDomain(s.Domain), services.IsPlatform(s.Domain)
 		list = append(list, s)
 	}
 	if list == nil {
 		list = []DomainStats{}
 	}
+	domains := make([]string, len(list))
+	for i := range list {
+		domains[i] = list[i].Domain
+	}
+	curations := services.LookupCurations(domains)
+	for i := range list {
+		list[i].applyCuration(curations[list[i].Domain])
+	}
 	json.NewEncoder(w).Encode(list)
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"text-analyzer/models"
+	"text-analyzer/services"
+)
+
+// ImportLists — POST /api/admin/domain-lists[?source=...&format=csv&replace=true]
+// Тело — CSV (domain,category,source,notes) или JSON: массив записей либо
+// {"source": "...", "entries": [...]}. replace=true заменяет прежние записи
+// тех же источников.
+func (h *DomainHandler) ImportLists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "не удалось прочитать тело запроса"})
+		return
+	}
+	q := r.URL.Query()
+	source := strings.TrimSpace(q.Get("source"))
+
+	var entries []models.DomainListEntry
+	if q.Get("format") == "csv" || strings.Contains(r.Header.Get("Content-Type"), "csv") {
+		entries, err = services.ParseDomainListCSV(strings.NewReader(string(body)), source)
+	} else {
+		entries, err = services.ParseDomainListJSON(body, source)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	imported, errs := services.ImportDomainList(entries, q.Get("replace") == "true")
+	if imported > 0 {
+		go h.snapshots.Rebuild()
+	}
+	if errs == nil {
+		errs = []string{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": imported,
+		"errors":   errs,
+	})
+}
+
+// Overrides — /api/admin/domain-overrides
+//
+//	GET                  — все ручные решения
+//	POST {domain, verdict, category, reason} — закрепить вердикт
+//	DELETE ?domain=...   — снять решение
+func (h *DomainHandler) Overrides(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		list, err := services.ListDomainOverrides()
+		if err != nil {
+			http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var o models.DomainOverride
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&o); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "неверный JSON"})
+			return
+		}
+		saved, err := services.SetDomainOverride(o)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		go h.snapshots.Rebuild()
+		json.NewEncoder(w).Encode(saved)
+
+	case http.MethodDelete:
+		err := services.DeleteDomainOverride(r.URL.Query().Get("domain"))
+		if errors.Is(err, services.ErrOverrideNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+			return
+		}
+		go h.snapshots.Rebuild()
+		json.NewEncoder(w).Encode(map[string]bool{"deleted": true})
+
+	default:
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
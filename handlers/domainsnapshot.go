@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text-analyzer/services"
+)
+
+type domainLookupRequest struct {
+	Domains []string `json:"domains"` // домены или URL
+}
+
+// Lookup — POST /api/domains/lookup {"domains": [...]} — значки сразу для
+// многих доменов. Ответ: {"domains": {"<как в запросе>": {...}}, "unknown": [...]}.
+func (h *DomainHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req domainLookupRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "неверный JSON"})
+		return
+	}
+	if len(req.Domains) > h.lookupMax {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("не больше %d доменов за запрос", h.lookupMax)})
+		return
+	}
+
+	seen := map[string]bool{}
+	var inputs []string
+	for _, d := range req.Domains {
+		d = strings.TrimSpace(d)
+		if d != "" && !seen[d] {
+			seen[d] = true
+			inputs = append(inputs, d)
+		}
+	}
+	found := services.LookupDomainBadges(inputs)
+	unknown := []string{}
+	for _, d := range inputs {
+		if _, ok := found[d]; !ok {
+			unknown = append(unknown, d)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"domains": found,
+		"unknown": unknown,
+	})
+}
+
+// Snapshot — GET /api/domains/snapshot[?since=<version>] — вердикты всех
+// известных доменов для офлайн-кэша клиента. ETag — версия снимка; с since
+// отдаются только изменения, а если разница уже не хранится — полный снимок.
+func (h *DomainHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	w.Header().Set("Access-Control-Expose-Headers", "ETag")
+	if !h.snapshots.Enabled() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "снимок доменов отключён (DOMAIN_SNAPSHOT_INTERVAL=0 или нет БД)"})
+		return
+	}
+	version, raw, gz := h.snapshots.Full()
+	if version == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "снимок доменов ещё не готов"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d"`, version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.snapshots.Interval().Seconds())))
+	w.Header().Set("Vary", "Accept-Encoding")
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if r.Header.Get("If-None-Match") == etag || since == version {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	gzipOK := strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	if since > 0 {
+		if diff, ok := h.snapshots.Since(since); ok {
+			raw, _ := json.Marshal(diff)
+			if gzipOK {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(services.GzipBytes(raw))
+				return
+			}
+			w.Write(raw)
+			return
+		}
+	}
+
+	if gzipOK {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gz)
+		return
+	}
+	w.Write(raw)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"text-analyzer/services"
+)
+
+// EditsHandler отдаёт историю правок проанализированных страниц.
+type EditsHandler struct{}
+
+func NewEditsHandler() *EditsHandler { return &EditsHandler{} }
+
+// Get — GET /api/edits?url=<url>[&limit=N]
+func (h *EditsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	pageURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if pageURL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "параметр url обязателен"})
+		return
+	}
+	limit := 20
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 100 {
+		limit = n
+	}
+
+	events, err := services.GetEditHistory(pageURL, limit)
+	if err != nil {
+		http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":   pageURL,
+		"edits": events,
+		"total": len(events),
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"text-analyzer/services"
+	"time"
+)
+
+// NarrativeHandler — нарративы: группы анализов об одной и той же истории.
+type NarrativeHandler struct{}
+
+func NewNarrativeHandler() *NarrativeHandler {
+	return &NarrativeHandler{}
+}
+
+// List — GET /api/narratives[?days=7&limit=20] → набирающие обороты нарративы.
+func (h *NarrativeHandler) List(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	days := 7
+	if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n > 0 && n <= 365 {
+		days = n
+	}
+	limit := 20
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 100 {
+		limit = n
+	}
+
+	narratives, err := services.TrendingNarratives(time.Duration(days)*24*time.Hour, limit)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "хранилище недоступно"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":       days,
+		"narratives": narratives,
+	})
+}
+
+// Get — GET /api/narratives/{id}[?limit=100] → нарратив со статьями и доменами.
+func (h *NarrativeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	domainCORSHeaders(w)
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/narratives/"), "/"), 10, 64)
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "неверный id"})
+		return
+	}
+	limit := 100
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 500 {
+		limit = n
+	}
+
+	narrative, err := services.GetNarrative(id, limit)
+	if errors.Is(err, services.ErrNarrativeNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "хранилище недоступно"})
+		return
+	}
+	json.NewEncoder(w).Encode(narrative)
+}
@@ -1,17 +1,25 @@
 package handlers
 
 import (
+	"bytes"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"text-analyzer/database"
+	"text-analyzer/models"
+	"text-analyzer/services"
+	"time"
 )
 
 type ShareHandler struct {
-	baseURL string
+	baseURL   string
+	publisher services.LDOrganization // автор наших ClaimReview
 }
 
 func NewShareHandler() *ShareHandler {
@@ -19,7 +27,11 @@ func NewShareHandler() *ShareHandler {
 	if base == "" {
 		base = "http://localhost:8080"
 	}
-	return &ShareHandler{baseURL: base}
+	name := os.Getenv("PUBLISHER_NAME")
+	if name == "" {
+		name = "ANALYST"
+	}
+	return &ShareHandler{baseURL: base, publisher: services.LDOrganization{Name: name, URL: base}}
 }
 
 func newShareID() string {
@@ -29,6 +41,9 @@ func newShareID() string {
 }
 
 // Create — POST /api/share → {"id":"…","url":"…"}
+// Тело — результат анализа либо {"chain_id":"…"} для сохранённой цепочки источников.
+// Если в результате есть analysis_id, публикуется анализ из analysis_results,
+// а не присланное тело.
 func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
@@ -52,10 +67,52 @@ func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ссылка на снимок страницы, если результат его содержит
+	var ref struct {
+		SnapshotID string `json:"snapshot_id"`
+		ChainID    string `json:"chain_id"`
+		AnalysisID int64  `json:"analysis_id"`
+	}
+	json.Unmarshal(raw, &ref)
+
+	// Цепочку публикуем из хранилища, а не из тела запроса
+	if ref.ChainID != "" {
+		chain, err := services.LoadChain(ref.ChainID)
+		if err != nil {
+			http.Error(w, `{"error":"chain not found"}`, http.StatusNotFound)
+			return
+		}
+		raw, _ = json.Marshal(chain)
+	}
+
+	// Анализ тоже берём из analysis_results: присланное тело могли изменить,
+	// а по публикации строятся ClaimReview
+	var analysisID sql.NullInt64
+	if ref.AnalysisID > 0 && ref.ChainID == "" {
+		var stored []byte
+		err := database.DB.QueryRow(`SELECT id, result FROM analysis_results WHERE id = $1`, ref.AnalysisID).Scan(&analysisID, &stored)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, `{"error":"analysis not found"}`, http.StatusNotFound)
+			return
+		}
+		var res models.AnalysisResponse
+		if err == nil {
+			err = json.Unmarshal(stored, &res)
+		}
+		if err != nil {
+			http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+			return
+		}
+		// id присваивается после записи результата, в самом JSON его нет
+		res.AnalysisID = analysisID.Int64
+		raw, _ = json.Marshal(res)
+		ref.SnapshotID = res.SnapshotID
+	}
+
 	id := newShareID()
 	_, err := database.DB.Exec(
-		`INSERT INTO shared_results (id, result) VALUES ($1, $2)`,
-		id, []byte(raw),
+		`INSERT INTO shared_results (id, result, snapshot_id, chain_id, analysis_id) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5)`,
+		id, []byte(raw), ref.SnapshotID, ref.ChainID, analysisID,
 	)
 	if err != nil {
 		http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
@@ -67,7 +124,50 @@ func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"id": id, "url": shareURL})
 }
 
+// sharedResult — опубликованный результат. AnalysisID — проверенная при
+// публикации ссылка на анализ в analysis_results (0 — такого анализа нет).
+type sharedResult struct {
+	Raw        []byte
+	AnalysisID int64
+	Created    time.Time
+}
+
+// loadShared возвращает сохранённый результат и время публикации.
+func loadShared(id string) (*sharedResult, error) {
+	var s sharedResult
+	var analysisID sql.NullInt64
+	err := database.DB.QueryRow(
+		`SELECT result, analysis_id, created_at FROM shared_results WHERE id = $1 AND expires_at > NOW()`,
+		id,
+	).Scan(&s.Raw, &analysisID, &s.Created)
+	s.AnalysisID = analysisID.Int64
+	return &s, err
+}
+
+// claimReviews строит ClaimReview по анализу, сохранённому на сервере.
+// Публикация без ссылки на анализ — это тело, присланное клиентом, ему не
+// доверяем: разметки нет.
+func (h *ShareHandler) claimReviews(id string, analysisID int64, created time.Time) []services.ClaimReviewLD {
+	if analysisID <= 0 {
+		return nil
+	}
+	var raw []byte
+	if err := database.DB.QueryRow(`SELECT result FROM analysis_results WHERE id = $1`, analysisID).Scan(&raw); err != nil {
+		return nil
+	}
+	return h.buildClaimReviews(id, raw, created)
+}
+
+func (h *ShareHandler) buildClaimReviews(id string, raw []byte, created time.Time) []services.ClaimReviewLD {
+	var res models.AnalysisResponse
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil
+	}
+	return services.BuildClaimReviews(&res, h.baseURL+"/s/"+id, created, h.publisher)
+}
+
 // GetResult — GET /api/share/:id → raw JSON result
+// GET /api/share/:id?format=claimreview → schema.org ClaimReview (JSON-LD)
 func (h *ShareHandler) GetResult(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	id := strings.TrimPrefix(r.URL.Path, "/api/share/")
@@ -76,28 +176,96 @@ func (h *ShareHandler) GetResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var raw []byte
-	err := database.DB.QueryRow(
-		`SELECT result FROM shared_results WHERE id = $1 AND expires_at > NOW()`,
-		id,
-	).Scan(&raw)
+	shared, err := loadShared(id)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"error": "не найдено или истёк срок"})
 		return
 	}
+	if r.URL.Query().Get("format") == "claimreview" {
+		reviews := h.claimReviews(id, shared.AnalysisID, shared.Created)
+		for i := range reviews {
+			reviews[i].Context = "https://schema.org"
+		}
+		if reviews == nil {
+			reviews = []services.ClaimReviewLD{}
+		}
+		w.Header().Set("Content-Type", "application/ld+json")
+		json.NewEncoder(w).Encode(reviews)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(raw)
+	w.Write(shared.Raw)
 }
 
 // ShowPage — GET /s/:id → serves admin/share.html
+// со встроенной разметкой ClaimReview для поисковиков.
 func (h *ShareHandler) ShowPage(w http.ResponseWriter, r *http.Request) {
 	data, err := os.ReadFile("admin/share.html")
 	if err != nil {
 		http.Error(w, "share page not found", http.StatusInternalServerError)
 		return
 	}
+	id := strings.TrimPrefix(r.URL.Path, "/s/")
+	if id != "" && database.DB != nil {
+		if shared, err := loadShared(id); err == nil {
+			if reviews := h.claimReviews(id, shared.AnalysisID, shared.Created); len(reviews) > 0 {
+				graph, _ := json.Marshal(map[string]interface{}{
+					"@context": "https://schema.org",
+					"@graph":   reviews,
+				})
+				script := append([]byte(`<script type="application/ld+json">`), graph...)
+				script = append(script, []byte("</script>\n</head>")...)
+				data = bytes.Replace(data, []byte("</head>"), script, 1)
+			}
+		}
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write(data)
 }
+
+// Feed — GET /api/claimreview/feed[?limit=N] → лента ClaimReview
+// по последним опубликованным анализам (schema.org DataFeed). Публикации
+// без сохранённого анализа в ленту не попадают.
+func (h *ShareHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/ld+json")
+	limit := 50
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 500 {
+		limit = n
+	}
+
+	feed := services.LDDataFeed{
+		Context:         "https://schema.org",
+		Type:            "DataFeed",
+		Name:            h.publisher.Name + " — проверенные утверждения",
+		DateModified:    time.Now().UTC().Format(time.RFC3339),
+		DataFeedElement: []services.ClaimReviewLD{},
+	}
+	if database.DB != nil {
+		rows, err := database.DB.Query(`
+			SELECT s.id, a.result, s.created_at
+			FROM shared_results s
+			JOIN analysis_results a ON a.id = s.analysis_id
+			WHERE s.expires_at > NOW() AND s.chain_id IS NULL
+			ORDER BY s.created_at DESC
+			LIMIT $1
+		`, limit)
+		if err != nil {
+			http.Error(w, `{"error":"db error"}`, http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			var raw []byte
+			var created time.Time
+			if rows.Scan(&id, &raw, &created) != nil {
+				continue
+			}
+			feed.DataFeedElement = append(feed.DataFeedElement, h.buildClaimReviews(id, raw, created)...)
+		}
+	}
+	json.NewEncoder(w).Encode(feed)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"text-analyzer/blobstore"
+	"text-analyzer/services"
+)
+
+// SnapshotHandler отдаёт сохранённые снимки страниц (только чтение).
+type SnapshotHandler struct{}
+
+func NewSnapshotHandler() *SnapshotHandler { return &SnapshotHandler{} }
+
+var snapshotIDRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Get — GET /api/snapshot/<id> → метаданные и извлечённый текст,
+// GET /api/snapshot/<id>/raw → исходный HTML (как text/plain, без исполнения).
+func (h *SnapshotHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/snapshot/")
+	id, suffix, _ := strings.Cut(rest, "/")
+	if !snapshotIDRe.MatchString(id) || (suffix != "" && suffix != "raw") {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if suffix == "raw" {
+		raw, err := services.GetSnapshotRaw(id)
+		if err != nil {
+			snapshotError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Write(raw)
+		return
+	}
+
+	snap, err := services.GetSnapshot(id)
+	if err != nil {
+		snapshotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	json.NewEncoder(w).Encode(snap)
+}
+
+func snapshotError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, blobstore.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "снимок не найден"})
+		return
+	}
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"text-analyzer/blobstore"
 	"text-analyzer/cache"
 	"text-analyzer/config"
 	"text-analyzer/database"
@@ -27,6 +28,22 @@ func main() {
 
 	database.InitDB(cfg.DbUrl)
 	cache.InitRedis(cfg.RedisUrl)
+	blobstore.Init(blobstore.Config{
+		Kind:        cfg.BlobStore,
+		Dir:         cfg.BlobDir,
+		S3Endpoint:  cfg.S3Endpoint,
+		S3Bucket:    cfg.S3Bucket,
+		S3Region:    cfg.S3Region,
+		S3AccessKey: cfg.S3AccessKey,
+		S3SecretKey: cfg.S3SecretKey,
+		S3PathStyle: cfg.S3PathStyle,
+	})
+	services.InitReputation(services.ReputationConfig{
+		Prior:    float64(cfg.DomainPrior),
+		Weight:   float64(cfg.DomainPriorWeight),
+		HalfLife: cfg.DomainHalfLife,
+	})
+	services.MigrateDomainEntities()
 
 	if cfg.UseGroq {
 		log.Printf("  - Режим: Groq ⚡")
@@ -39,11 +56,6 @@ func main() {
 		}
 	}
 	log.Printf("  - Порт: %s", cfg.Port)
-	if cfg.SerperAPIKey != "" {
-		log.Printf("  - Serper API: включен ✓")
-	} else {
-		log.Printf("  - Serper API: отключен")
-	}
 
 	promptConfig, err := services.LoadPromptConfig("config/prompts.json")
 	if err != nil {
@@ -51,12 +63,62 @@ func main() {
 	}
 	log.Printf("✓ Промпт конфигурация загружена")
 
-	contentFetcher := services.NewContentFetcher()
+	fetchPolicy := &services.FetchPolicy{
+		MaxBodyBytes: cfg.FetchMaxBytes,
+		MaxRedirects: cfg.FetchMaxRedirects,
+		Timeout:      cfg.FetchTimeout,
+		AllowHosts:   cfg.FetchAllowHosts,
+		DenyHosts:    cfg.FetchDenyHosts,
+		AllowPrivate: cfg.FetchAllowPrivate,
+	}
+	if fetchPolicy.AllowPrivate {
+		log.Printf("  - ⚠ FETCH_ALLOW_PRIVATE=true: загрузка с внутренних адресов разрешена")
+	}
+	contentFetcher := services.NewContentFetcher(fetchPolicy, services.CrawlConfig{
+		UserAgent:       cfg.FetchUserAgent,
+		RespectRobots:   cfg.FetchRespectRobots,
+		HostConcurrency: cfg.FetchHostConcurrency,
+		HostInterval:    cfg.FetchHostInterval,
+		CacheTTL:        cfg.FetchCacheTTL,
+		ArchiveAPIURL:   cfg.ArchiveAPIURL,
+	})
+	log.Printf("  - User-Agent загрузчика: %s (robots.txt: %v)", cfg.FetchUserAgent, cfg.FetchRespectRobots)
+
+	services.NewEditWatcher(contentFetcher, cfg.EditWatchInterval, cfg.EditWatchMaxScore, cfg.EditWatchMaxAge, cfg.EditWatchBatch).Start()
+	services.NewClaimReviewCrawler(contentFetcher, cfg.ClaimReviewSeeds, cfg.ClaimReviewInterval, cfg.ClaimReviewPerRun).Start()
 
-	var serperClient *services.SerperClient
+	var searchProviders []services.SearchProvider
 	if cfg.SerperAPIKey != "" {
-		serperClient = services.NewSerperClient(cfg.SerperAPIKey)
-		log.Printf("✓ Serper клиент инициализирован")
+		searchProviders = append(searchProviders, services.NewSerperClient(cfg.SerperAPIKey))
+	}
+	if cfg.SearxngURL != "" {
+		searchProviders = append(searchProviders, services.NewSearxngClient(cfg.SearxngURL))
+	}
+	if cfg.BraveAPIKey != "" {
+		searchProviders = append(searchProviders, services.NewBraveClient(cfg.BraveAPIKey))
+	}
+	searchLocales, err := services.ParseSearchLocales(cfg.SearchLocales)
+	if err != nil {
+		log.Fatal("❌ Ошибка в SEARCH_LOCALES:", err)
+	}
+	searchService := services.NewSearchService(services.SearchConfig{
+		Locales:          searchLocales,
+		Timeout:          cfg.SearchTimeout,
+		CacheTTL:         cfg.SearchCacheTTL,
+		MaxPerRequest:    cfg.SearchMaxPerRequest,
+		MaxPerDay:        cfg.SearchMaxPerDay,
+		TrustedDomains:   cfg.SearchTrustedDomains,
+		BlockedDomains:   cfg.SearchBlockedDomains,
+		FactCheckDomains: cfg.SearchFactCheckers,
+	}, searchProviders...)
+	if searchService.Enabled() {
+		var localeNames []string
+		for _, l := range searchService.Locales() {
+			localeNames = append(localeNames, l.Name)
+		}
+		log.Printf("✓ Веб-поиск: %s (%s)", strings.Join(searchService.ProviderNames(), ", "), strings.Join(localeNames, ", "))
+	} else {
+		log.Printf("  - Веб-поиск: отключен")
 	}
 
 	var factCheckClient *services.GoogleFactCheckClient
@@ -68,12 +130,31 @@ func main() {
 	}
 
 	var analyzerService *services.AnalyzerService
+	narratives := services.NewNarrativeClusterer(services.NarrativeConfig{
+		MinSimilarity: float64(cfg.NarrativeThreshold) / 100,
+		Window:        cfg.NarrativeWindow,
+		Interval:      cfg.NarrativeInterval,
+		Batch:         cfg.NarrativeBatch,
+	})
+	reuseConfig := services.ReuseConfig{
+		MinSimilarity: float64(cfg.ReuseMinSimilarity) / 100,
+		MaxAge:        cfg.ReuseMaxAge,
+	}
+	coordination := services.NewCoordinationDetector(services.CoordinationConfig{
+		MinOverlap: float64(cfg.CoordMinOverlap) / 100,
+		Window:     cfg.CoordWindow,
+		MinDomains: cfg.CoordMinDomains,
+		Lookback:   cfg.CoordLookback,
+		Interval:   cfg.CoordInterval,
+		Batch:      cfg.CoordBatch,
+	})
 
 	switch {
 	case cfg.UseGroq:
 		log.Println("⚡ Инициализация Groq клиента...")
 		groqClient := services.NewGroqClient(cfg.GroqAPIKeys, cfg.GroqModel, promptConfig)
-		analyzerService = services.NewAnalyzerService(groqClient, contentFetcher, serperClient, factCheckClient, promptConfig)
+		planner := services.NewQueryPlanner(groqClient, cfg.SearchQueryPlanner == "ai")
+		analyzerService = services.NewAnalyzerService(groqClient, contentFetcher, searchService, planner, factCheckClient, narratives, coordination, reuseConfig, promptConfig)
 		log.Println("✓ Groq режим активирован")
 
 	default:
@@ -82,7 +163,8 @@ func main() {
 		}
 		log.Println("☁ Инициализация OpenRouter клиента...")
 		openRouterClient := services.NewOpenRouterClient(cfg.OpenRouterAPIKey, cfg.OpenRouterModel, cfg.OpenRouterModelBackup, promptConfig)
-		analyzerService = services.NewAnalyzerService(openRouterClient, contentFetcher, serperClient, factCheckClient, promptConfig)
+		planner := services.NewQueryPlanner(openRouterClient, cfg.SearchQueryPlanner == "ai")
+		analyzerService = services.NewAnalyzerService(openRouterClient, contentFetcher, searchService, planner, factCheckClient, narratives, coordination, reuseConfig, promptConfig)
 		log.Println("✓ OpenRouter режим активирован")
 	}
 
@@ -95,19 +177,37 @@ func main() {
 				return services.NewOpenRouterClient(cfg.OpenRouterAPIKey, cfg.OpenRouterModel, cfg.OpenRouterModelBackup, promptConfig)
 			}
 		}(),
+		analyzerService, // общая с анализами очередь запросов к модели
 		contentFetcher,
-		serperClient,
+		searchService,
+		services.ChainConfig{
+			Workers:    cfg.ChainWorkers,
+			MaxBreadth: cfg.ChainMaxBreadth,
+			MaxDepth:   cfg.ChainMaxDepth,
+			MaxNodes:   cfg.ChainMaxNodes,
+			CacheTTL:   cfg.ChainCacheTTL,
+		},
 	)
 
+	narratives.Start()
+	coordination.Start()
+	domainSnapshots := services.NewDomainSnapshotter(cfg.DomainSnapshotPeriod)
+	domainSnapshots.Start()
+
 	analyzerHandler := handlers.NewAnalyzerHandler(analyzerService)
 	chainHandler := handlers.NewChainHandler(chainService)
-	domainHandler := handlers.NewDomainHandler()
+	domainHandler := handlers.NewDomainHandler(domainSnapshots, cfg.DomainLookupMax)
 	shareHandler := handlers.NewShareHandler()
+	snapshotHandler := handlers.NewSnapshotHandler()
+	editsHandler := handlers.NewEditsHandler()
+	claimReviewHandler := handlers.NewClaimReviewHandler(contentFetcher)
+	narrativeHandler := handlers.NewNarrativeHandler()
 	adminHandler := handlers.NewAdminHandler(cfg, analyzerService)
 	dockerHandler := handlers.NewDockerHandler(adminHandler)
 	log.Println("✓ Сервисы инициализированы")
 
 	http.HandleFunc("/api/chain/stream", chainHandler.Stream)
+	http.HandleFunc("/api/chain/", chainHandler.Get)
 	http.HandleFunc("/api/ext/hash", analyzerHandler.ExtHash)
 	http.HandleFunc("/api/analyze", analyzerHandler.Analyze)
 	http.HandleFunc("/api/analyze/stream", analyzerHandler.AnalyzeStream)
@@ -116,9 +216,17 @@ func main() {
 	http.HandleFunc("/api/limits", analyzerHandler.Limits)
 	http.HandleFunc("/api/domain/", domainHandler.GetDomain)
 	http.HandleFunc("/api/domains/top", domainHandler.GetTopDomains)
+	http.HandleFunc("/api/domains/lookup", domainHandler.Lookup)
+	http.HandleFunc("/api/domains/snapshot", domainHandler.Snapshot)
 	http.HandleFunc("/api/share", shareHandler.Create)
 	http.HandleFunc("/api/share/", shareHandler.GetResult)
 	http.HandleFunc("/s/", shareHandler.ShowPage)
+	http.HandleFunc("/api/claimreview/feed", shareHandler.Feed)
+	http.HandleFunc("/api/snapshot/", snapshotHandler.Get)
+	http.HandleFunc("/api/edits", editsHandler.Get)
+	http.HandleFunc("/api/claim-reviews", claimReviewHandler.Search)
+	http.HandleFunc("/api/narratives", narrativeHandler.List)
+	http.HandleFunc("/api/narratives/", narrativeHandler.Get)
 
 	// Admin API
 	http.HandleFunc("/api/admin/stats", adminHandler.AuthMiddleware(adminHandler.GetStats))
@@ -126,6 +234,9 @@ func main() {
 	http.HandleFunc("/api/admin/pause", adminHandler.AuthMiddleware(adminHandler.Pause))
 	http.HandleFunc("/api/admin/resume", adminHandler.AuthMiddleware(adminHandler.Resume))
 	http.HandleFunc("/api/admin/status", adminHandler.AuthMiddleware(adminHandler.GetStatus))
+	http.HandleFunc("/api/admin/claim-reviews", adminHandler.AuthMiddleware(claimReviewHandler.Import))
+	http.HandleFunc("/api/admin/domain-lists", adminHandler.AuthMiddleware(domainHandler.ImportLists))
+	http.HandleFunc("/api/admin/domain-overrides", adminHandler.AuthMiddleware(domainHandler.Overrides))
 
 	// Docker management API
 	http.HandleFunc("/api/admin/docker/containers", adminHandler.AuthMiddleware(dockerHandler.ListContainers))
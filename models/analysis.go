@@ -1,31 +1,50 @@
 package models
 
+import "time"
+
 type AnalysisRequest struct {
-	Text string `json:"text,omitempty"`
-	URL  string `json:"url,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	Locales []string `json:"locales,omitempty"` // локали поиска "страна:язык", например ["md:ru", "us:en"]
+	Fresh   bool     `json:"fresh,omitempty"`   // не брать готовый результат из кэша или похожего анализа
 }
 
 type AnalysisResponse struct {
-	Summary            string       `json:"summary"`
-	SourceURL          string       `json:"source_url,omitempty"`
-	FactCheck          FactCheck    `json:"fact_check"`
-	Manipulations      []string     `json:"manipulations"`
-	LogicalIssues      []string     `json:"logical_issues"`
-	CredibilityScore   int          `json:"credibility_score"`
-	ScoreBreakdown     string       `json:"score_breakdown,omitempty"`
-	FinalVerdict       string       `json:"final_verdict,omitempty"`
-	VerdictExplanation string       `json:"verdict_explanation,omitempty"`
-	Reasoning          string       `json:"reasoning"`
-	Sources            []Source     `json:"sources,omitempty"`
-	Verification       Verification `json:"verification,omitempty"`
-	Usage              *TokenUsage  `json:"usage,omitempty"`
-	RawResponse        string       `json:"raw_response,omitempty"`
+	AnalysisID         int64              `json:"analysis_id,omitempty"`
+	Summary            string             `json:"summary"`
+	SourceURL          string             `json:"source_url,omitempty"`
+	FetchFallback      string             `json:"fetch_fallback,omitempty"` // ld+json | amp | wayback | meta
+	FetchedFrom        string             `json:"fetched_from,omitempty"`   // откуда фактически взят текст, если не source_url
+	ArchivedAt         string             `json:"archived_at,omitempty"`    // время снимка Wayback Machine
+	SnapshotID         string             `json:"snapshot_id,omitempty"`    // сохранённая копия страницы
+	StealthEdit        *EditEvent         `json:"stealth_edit,omitempty"`   // правка с прошлой проверки URL
+	Citations          *CitationReport    `json:"citations,omitempty"`      // проверка исходящих ссылок статьи
+	ClaimReviews       []ClaimReviewMatch `json:"claim_reviews,omitempty"`  // найденные проверки фактчекеров
+	Coordinated        *CoordinatedMatch  `json:"coordinated,omitempty"`    // текст публиковала сеть сайтов
+	MatchedAnalysis    *AnalysisMatch     `json:"matched,omitempty"`        // результат взят из анализа почти того же текста
+	SourceDomain       *DomainCuration    `json:"source_domain,omitempty"`  // домен статьи в курируемых списках
+	FactCheck          FactCheck          `json:"fact_check"`
+	Manipulations      []string           `json:"manipulations"`
+	LogicalIssues      []string           `json:"logical_issues"`
+	CredibilityScore   int                `json:"credibility_score"`
+	ScoreBreakdown     string             `json:"score_breakdown,omitempty"`
+	FinalVerdict       string             `json:"final_verdict,omitempty"`
+	Rating             *NormalizedRating  `json:"rating,omitempty"` // вердикт на единой шкале
+	VerdictExplanation string             `json:"verdict_explanation,omitempty"`
+	Reasoning          string             `json:"reasoning"`
+	Sources            []Source           `json:"sources,omitempty"`
+	SearchQueries      []SearchQuery      `json:"search_queries,omitempty"` // запросы, по которым искали подтверждения
+	Verification       Verification       `json:"verification,omitempty"`
+	Usage              *TokenUsage        `json:"usage,omitempty"`
+	RawResponse        string             `json:"raw_response,omitempty"`
 }
 
 type TokenUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	SearchCalls      int `json:"search_calls,omitempty"`      // платных обращений к поисковикам
+	SearchCacheHits  int `json:"search_cache_hits,omitempty"` // ответов из кэша поиска
 }
 
 type Verification struct {
@@ -39,6 +58,17 @@ type Source struct {
 	Title       string `json:"title"`
 	URL         string `json:"url"`
 	Description string `json:"description"`
+	Query       string `json:"query,omitempty"` // поисковый запрос, которым найден источник
+	Claim       string `json:"claim,omitempty"` // проверяемое утверждение
+	Tier        string `json:"tier,omitempty"`  // надёжность источника: факт-чекер, проверенный, надёжный
+}
+
+// SearchQuery — поисковый запрос, составленный для проверки текста или утверждения.
+type SearchQuery struct {
+	Query  string `json:"query"`
+	Lang   string `json:"lang,omitempty"`   // пусто — искать во всех локалях
+	Claim  string `json:"claim,omitempty"`  // пусто — запрос по тексту целиком
+	Source string `json:"source,omitempty"` // ai | heuristic
 }
 
 type FactCheck struct {
@@ -57,3 +87,177 @@ type ChatResponse struct {
 	Response string      `json:"response"`
 	Usage    *TokenUsage `json:"usage,omitempty"`
 }
+
+// EditEvent — обнаруженная правка страницы между двумя проверками одного URL.
+type EditEvent struct {
+	ID                 int64             `json:"id,omitempty"`
+	URL                string            `json:"url"`
+	PreviousSnapshotID string            `json:"previous_snapshot_id,omitempty"`
+	SnapshotID         string            `json:"snapshot_id,omitempty"`
+	DetectedAt         time.Time         `json:"detected_at"`
+	Added              int               `json:"added"`
+	Removed            int               `json:"removed"`
+	Changed            int               `json:"changed"`
+	Changes            []ParagraphChange `json:"changes"`
+}
+
+// ParagraphChange — изменение одного абзаца: added | removed | changed.
+type ParagraphChange struct {
+	Type     string `json:"type"`
+	Position int    `json:"position"` // номер абзаца в новой версии
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+// CitationReport — отчёт о ссылках на источники в статье.
+type CitationReport struct {
+	Total           int        `json:"total"`
+	Working         int        `json:"working"`
+	Dead            int        `json:"dead"`
+	SelfReferential int        `json:"self_referential"`
+	LowReputation   int        `json:"low_reputation"`
+	Citations       []Citation `json:"citations"`
+	UnlinkedClaims  []string   `json:"unlinked_claims,omitempty"` // «по данным исследования…» без ссылки
+	Summary         string     `json:"-"`                         // сводка для контекста модели
+}
+
+// Citation — одна исходящая ссылка.
+type Citation struct {
+	URL             string   `json:"url"`
+	FinalURL        string   `json:"final_url,omitempty"` // после редиректов
+	Domain          string   `json:"domain"`
+	Anchor          string   `json:"anchor,omitempty"`
+	Status          string   `json:"status"` // ok | dead | unreachable | blocked | unchecked (тот же сайт или не успели)
+	StatusCode      int      `json:"status_code,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	SelfReferential bool     `json:"self_referential,omitempty"`
+	LowReputation   bool     `json:"low_reputation,omitempty"`
+	DomainScore     *float64 `json:"domain_score,omitempty"`
+	DomainAnalyses  int      `json:"domain_analyses,omitempty"`
+}
+
+// ClaimReview — проверка утверждения фактчекером (schema.org/ClaimReview).
+type ClaimReview struct {
+	ID            int64      `json:"id,omitempty"`
+	ClaimText     string     `json:"claim"`
+	Claimant      string     `json:"claimant,omitempty"`
+	ClaimDate     *time.Time `json:"claim_date,omitempty"`
+	Rating        string     `json:"rating"` // оценка как у фактчекера: «Fals», «Mostly False», ...
+	Publisher     string     `json:"publisher,omitempty"`
+	PublisherSite string     `json:"publisher_site,omitempty"`
+	URL           string     `json:"url"`
+	Title         string     `json:"title,omitempty"`
+	ReviewDate    *time.Time `json:"review_date,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	Source        string     `json:"source,omitempty"` // google | jsonld | manual
+
+	Normalized *NormalizedRating `json:"normalized_rating,omitempty"` // оценка на единой шкале
+}
+
+// ClaimReviewMatch — проверка, похожая на утверждение из текста.
+type ClaimReviewMatch struct {
+	ClaimReview
+	Similarity  float64 `json:"similarity"`             // 0..1
+	MatchedText string  `json:"matched_text,omitempty"` // фрагмент текста, с которым совпало
+}
+
+// NormalizedRating — оценка на единой порядковой шкале (общей для наших
+// вердиктов и оценок фактчекеров).
+type NormalizedRating struct {
+	Label      string  `json:"label"`              // false | mostly_false | mixed | mostly_true | true | unproven | unknown
+	Value      int     `json:"value"`              // 1 (ложь) … 5 (правда); 0 — вне шкалы
+	Name       string  `json:"name"`               // название по-русски
+	Confidence float64 `json:"confidence"`         // 0..1 — насколько уверенно распознана исходная оценка
+	Original   string  `json:"original,omitempty"` // исходная формулировка
+}
+
+// Narrative — группа анализов, продвигающих одну и ту же историю.
+type Narrative struct {
+	ID           int64             `json:"id"`
+	Label        string            `json:"label"`
+	Keywords     []string          `json:"keywords"`
+	ArticleCount int               `json:"article_count"`
+	RecentCount  int               `json:"recent_count,omitempty"` // статей за запрошенный период
+	DomainCount  int               `json:"domain_count"`
+	AvgScore     float64           `json:"avg_credibility"`
+	FirstSeen    time.Time         `json:"first_seen"`
+	LastSeen     time.Time         `json:"last_seen"`
+	Members      []NarrativeMember `json:"members,omitempty"`
+	Domains      []NarrativeDomain `json:"domains,omitempty"`
+}
+
+type NarrativeMember struct {
+	AnalysisID       int64     `json:"analysis_id"`
+	URL              string    `json:"url,omitempty"`
+	Domain           string    `json:"domain,omitempty"`
+	Title            string    `json:"title"`
+	CredibilityScore int       `json:"credibility_score"`
+	Similarity       float64   `json:"similarity"`
+	AddedAt          time.Time `json:"added_at"`
+}
+
+type NarrativeDomain struct {
+	Domain   string  `json:"domain"`
+	Articles int     `json:"articles"`
+	AvgScore float64 `json:"avg_credibility"`
+}
+
+// NarrativeRef — краткая ссылка на нарратив в ответе анализа.
+type NarrativeRef struct {
+	ID           int64   `json:"id"`
+	Label        string  `json:"label"`
+	ArticleCount int     `json:"article_count"`
+	Similarity   float64 `json:"similarity"`
+	IsNew        bool    `json:"is_new"`
+}
+
+// CoordinatedMatch — текст совпал с известной сетью согласованных публикаций:
+// почти тот же текст за короткое время вышел на нескольких доменах.
+type CoordinatedMatch struct {
+	ClusterID    int64     `json:"cluster_id"`
+	Overlap      float64   `json:"overlap"` // % совпадения с публикацией сети
+	ArticleCount int       `json:"article_count"`
+	DomainCount  int       `json:"domain_count"`
+	Domains      []string  `json:"domains"` // первые домены сети по дате публикации
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Warning      string    `json:"warning"`
+}
+
+// AnalysisMatch — результат взят из ранее сохранённого анализа почти того же
+// текста (simhash), модель заново не вызывалась.
+type AnalysisMatch struct {
+	AnalysisID int64     `json:"analysis_id"`
+	Similarity float64   `json:"similarity"` // % совпадающих бит simhash
+	AnalyzedAt time.Time `json:"analyzed_at"`
+	Note       string    `json:"note"`
+}
+
+// DomainListEntry — запись курируемого списка доменов.
+type DomainListEntry struct {
+	Domain   string `json:"domain"`
+	Category string `json:"category"` // propaganda | satire | state_media | verified
+	Source   string `json:"source"`   // чей это список
+	Notes    string `json:"notes,omitempty"`
+}
+
+// DomainOverride — ручное решение администратора по домену.
+type DomainOverride struct {
+	Domain    string    `json:"domain"`
+	Verdict   string    `json:"verdict,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DomainCuration — что о домене известно помимо наших анализов. Verdict
+// отсюда важнее вычисленной репутации.
+type DomainCuration struct {
+	Domain        string            `json:"domain"`
+	Category      string            `json:"category,omitempty"`
+	CategoryLabel string            `json:"category_label,omitempty"`
+	Verdict       string            `json:"verdict,omitempty"`
+	VerdictSource string            `json:"verdict_source,omitempty"` // override | curated
+	Lists         []DomainListEntry `json:"lists,omitempty"`
+	Override      *DomainOverride   `json:"override,omitempty"`
+}
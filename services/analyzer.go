@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -23,8 +24,12 @@ type AIClient interface {
 type AnalyzerService struct {
 	client       AIClient
 	fetcher      *ContentFetcher
-	serper       *SerperClient
+	search       *SearchService
+	planner      *QueryPlanner
 	factCheck    *GoogleFactCheckClient
+	narratives   *NarrativeClusterer
+	coordination *CoordinationDetector
+	reuse        ReuseConfig
 	promptConfig *PromptConfig
 
 	// Semaphore: max 1 concurrent AI request, rest wait in queue
@@ -34,31 +39,136 @@ type AnalyzerService struct {
 	IsPaused atomic.Bool
 }
 
-func NewAnalyzerService(client AIClient, fetcher *ContentFetcher, serper *SerperClient, factCheck *GoogleFactCheckClient, promptConfig *PromptConfig) *AnalyzerService {
+func NewAnalyzerService(client AIClient, fetcher *ContentFetcher, search *SearchService, planner *QueryPlanner, factCheck *GoogleFactCheckClient, narratives *NarrativeClusterer, coordination *CoordinationDetector, reuse ReuseConfig, promptConfig *PromptConfig) *AnalyzerService {
 	return &AnalyzerService{
 		client:       client,
 		fetcher:      fetcher,
-		serper:       serper,
+		search:       search,
+		planner:      planner,
 		factCheck:    factCheck,
+		narratives:   narratives,
+		coordination: coordination,
+		reuse:        reuse,
 		promptConfig: promptConfig,
 		sem:          make(chan struct{}, 1),
 	}
 }
 
+// AILimiter — общая очередь запросов к модели. Анализы и цепочки источников
+// ждут один и тот же слот, чтобы вместе не превышать лимиты провайдера.
+type AILimiter interface {
+	AcquireAI(ctx context.Context) (release func(), err error)
+}
+
+// AcquireAI занимает слот запроса к модели, который используют и анализы;
+// ожидание прерывается вместе с ctx. Ожидающие учитываются в позиции очереди.
+func (s *AnalyzerService) AcquireAI(ctx context.Context) (func(), error) {
+	s.waiting.Add(1)
+	select {
+	case s.sem <- struct{}{}:
+		return func() {
+			<-s.sem
+			s.waiting.Add(-1)
+		}, nil
+	case <-ctx.Done():
+		s.waiting.Add(-1)
+		return nil, ctx.Err()
+	}
+}
+
+// addUsage прибавляет к расходу токенов total расход вспомогательного
+// запроса к модели (например, планирования поиска).
+func addUsage(total, u *models.TokenUsage) *models.TokenUsage {
+	if u == nil {
+		return total
+	}
+	if total == nil {
+		total = &models.TokenUsage{}
+	}
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.TotalTokens += u.TotalTokens
+	return total
+}
+
+// RefreshSearchLimits обновляет остаток кредитов поисковиков для /api/limits.
+func (s *AnalyzerService) RefreshSearchLimits() {
+	s.search.RefreshLimits()
+}
+
 // NewAnalyzerServiceGroq — алиас для удобства (тот же конструктор)
-func NewAnalyzerServiceGroq(client *GroqClient, fetcher *ContentFetcher, serper *SerperClient, factCheck *GoogleFactCheckClient, promptConfig *PromptConfig) *AnalyzerService {
-	return NewAnalyzerService(client, fetcher, serper, factCheck, promptConfig)
+func NewAnalyzerServiceGroq(client *GroqClient, fetcher *ContentFetcher, search *SearchService, planner *QueryPlanner, factCheck *GoogleFactCheckClient, narratives *NarrativeClusterer, coordination *CoordinationDetector, reuse ReuseConfig, promptConfig *PromptConfig) *AnalyzerService {
+	return NewAnalyzerService(client, fetcher, search, planner, factCheck, narratives, coordination, reuse, promptConfig)
+}
+
+// analyzeInput — исходные данные одного анализа.
+type analyzeInput struct {
+	Text       string
+	URL        string       // пусто для анализа произвольного текста
+	Fetch      *FetchResult // как была загружена страница (для URL)
+	SnapshotID string
+	Citations  *models.CitationReport
+	Options    AnalyzeOptions
+}
+
+// AnalyzeOptions — параметры конкретного запроса.
+type AnalyzeOptions struct {
+	SearchLocales []SearchLocale // пусто — локали из конфигурации
+	Fresh         bool           // анализировать заново, не беря готовый результат
+}
+
+// applySource переносит в ответ сведения об источнике текста. Ответ из
+// кэша мог сохраниться для другого источника того же текста — его сведения
+// стираются.
+func (in analyzeInput) applySource(response *models.AnalysisResponse) {
+	response.SourceURL, response.SnapshotID, response.FetchedFrom = "", "", ""
+	response.FetchFallback, response.ArchivedAt = "", ""
+	response.Citations = nil
+	if in.URL == "" {
+		return
+	}
+	response.SourceURL = in.URL
+	response.SnapshotID = in.SnapshotID
+	response.Citations = in.Citations
+	if in.Fetch != nil {
+		response.FetchFallback = in.Fetch.Fallback
+		response.ArchivedAt = in.Fetch.ArchivedAt
+		if in.Fetch.URL != in.URL {
+			response.FetchedFrom = in.Fetch.URL
+		}
+	}
+}
+
+// publishedAt — дата публикации страницы из её метаданных, если известна.
+func (in analyzeInput) publishedAt() *time.Time {
+	if in.Fetch == nil {
+		return nil
+	}
+	return in.Fetch.PublishedAt
 }
 
 func (s *AnalyzerService) AnalyzeText(text string, progress ...func(string)) (*models.AnalysisResponse, error) {
+	return s.AnalyzeTextWithOptions(text, AnalyzeOptions{}, progress...)
+}
+
+func (s *AnalyzerService) AnalyzeTextWithOptions(text string, opts AnalyzeOptions, progress ...func(string)) (*models.AnalysisResponse, error) {
+	var progressFn func(string)
+	if len(progress) > 0 {
+		progressFn = progress[0]
+	}
+	return s.analyze(analyzeInput{Text: text, Options: opts}, progressFn)
+}
+
+func (s *AnalyzerService) analyze(in analyzeInput, progress func(string)) (*models.AnalysisResponse, error) {
 	if s.IsPaused.Load() {
 		return nil, fmt.Errorf("анализ временно приостановлен администратором")
 	}
 
+	text := in.Text
 	report := func(msg string) {
 		log.Printf("[ANALYZER] %s", msg)
-		if len(progress) > 0 && progress[0] != nil {
-			progress[0](msg)
+		if progress != nil {
+			progress(msg)
 		}
 	}
 	report(fmt.Sprintf("📄 Читаю текст... %d символов", len(text)))
@@ -66,41 +176,98 @@ func (s *AnalyzerService) AnalyzeText(text string, progress ...func(string)) (*m
 	// Кэширование в Redis
 	textHash := sha256.Sum256([]byte(text))
 	cacheKey := "analysis:" + hex.EncodeToString(textHash[:])
-
-	if cachedResult, err := cache.Get(cacheKey); err == nil {
-		report("🚀 Найден результат в кэше Redis!")
-		var response models.AnalysisResponse
-		if err := json.Unmarshal([]byte(cachedResult), &response); err == nil {
-			return &response, nil
+	search := s.search.WithLocales(in.Options.SearchLocales).NewSession()
+	if len(in.Options.SearchLocales) > 0 {
+		var keys []string
+		for _, l := range in.Options.SearchLocales {
+			keys = append(keys, l.key())
+		}
+		cacheKey += ":" + strings.Join(keys, ",")
+	}
+
+	fingerprint, hasFingerprint := textSimhash(text)
+	if !in.Options.Fresh {
+		if cachedResult, err := cache.Get(cacheKey); err == nil {
+			report("🚀 Найден результат в кэше Redis!")
+			var response models.AnalysisResponse
+			if err := json.Unmarshal([]byte(cachedResult), &response); err == nil {
+				in.applySource(&response)
+				// Снимок страницы, сделанный для этого запроса, связывается
+				// с анализом
+				if in.SnapshotID != "" {
+					s.finish(in, text, cacheKey, fingerprint, &response, report)
+				}
+				return &response, nil
+			}
+		}
+		// Почти такой же текст уже проверяли — отдаём готовый результат.
+		// С выбранными локалями поиск другой, поэтому результат не переиспользуется.
+		if hasFingerprint && len(in.Options.SearchLocales) == 0 {
+			if match, stored := findNearDuplicate(fingerprint, s.reuse); match != nil {
+				report(fmt.Sprintf("♻️ Текст совпадает с анализом #%d на %.0f%% — использую готовый результат (\"fresh\": true — проверить заново)", match.AnalysisID, match.Similarity))
+				match.Note = fmt.Sprintf("совпадает с ранее проверенным анализом #%d", match.AnalysisID)
+				response := reusedResponse(stored, match)
+				in.applySource(response)
+				s.finish(in, text, cacheKey, fingerprint, response, report)
+				return response, nil
+			}
 		}
 	}
 
 	var searchContext string
-	if s.serper != nil && s.serper.APIKey != "" {
+	var searchQueries, queries []models.SearchQuery
+	var planUsage *models.TokenUsage
+	if in.Citations != nil {
+		searchContext = "\n\n--- ССЫЛКИ НА ИСТОЧНИКИ В СТАТЬЕ ---\n" + in.Citations.Summary
+	}
+	if search.Enabled() {
+		report("🧭 Составляю поисковые запросы...")
+		queries, planUsage = s.planner.PlanText(context.Background(), s, text, search.Langs())
+		searchQueries = append(searchQueries, queries...)
 		report("🔍 Ищу факты по теме в интернете...")
-		searchResults, err := s.serper.SearchForFactCheck(text)
-		if err != nil {
+		res := search.SearchQueries(queries, 5)
+		reportSearchErrors(res, report)
+		if len(queries) == 0 {
+			report("⚠ Не удалось составить поисковые запросы, продолжаю без контекста")
+		} else if err := res.Err(); err != nil {
 			report("⚠ Поиск в сети недоступен, продолжаю без него")
-		} else if searchResults != "" {
-			searchContext = "\n\n--- ИНФОРМАЦИЯ ИЗ ИНТЕРНЕТА ДЛЯ ПРОВЕРКИ ФАКТОВ ---\n" + searchResults
-			report("✓ Нашёл дополнительный контекст из сети")
+		} else if len(res.Results) > 0 {
+			searchContext += "\n\n--- ИНФОРМАЦИЯ ИЗ ИНТЕРНЕТА ДЛЯ ПРОВЕРКИ ФАКТОВ ---\n" + FormatSearchContext(search.RankResults(res.Results), 10)
+			report(fmt.Sprintf("✓ Нашёл дополнительный контекст из сети (%d запросов)", len(queries)))
 		} else {
 			report("⚠ По теме ничего не нашлось, продолжаю без контекста")
 		}
 	}
 
-	// Google Fact Check Tools API — проверка по международной базе фейков
+	// Проверки фактчекеров: Google Fact Check Tools (по поисковым запросам)
+	// и локальная база ClaimReview
+	var googleReviews []models.ClaimReview
 	if s.factCheck != nil && s.factCheck.APIKey != "" {
 		report("🕵️ Проверяю по базе Google Fact Check...")
-		factCheckResults, err := s.factCheck.Search(text)
-		if err != nil {
+		if queries == nil {
+			queries, planUsage = s.planner.PlanText(context.Background(), s, text, search.Langs())
+			searchQueries = append(searchQueries, queries...)
+		}
+		failed := 0
+		for _, q := range queries[:min(len(queries), 3)] {
+			reviews, err := s.factCheck.Search(q.Query, q.Lang)
+			if err != nil {
+				failed++
+				continue
+			}
+			googleReviews = append(googleReviews, reviews...)
+		}
+		if failed > 0 && failed == min(len(queries), 3) {
 			report("⚠ Google Fact Check недоступен, продолжаю без него")
-		} else if factCheckResults != "" {
-			searchContext += factCheckResults
-			report("✅ Найдены записи в базе проверки фактов!")
-		} else {
-			report("ℹ В базе Google Fact Check совпадений не найдено")
 		}
+		SaveClaimReviews(googleReviews)
+	}
+	claimReviews := MatchClaimReviews(text, googleReviews, 5)
+	if len(claimReviews) > 0 {
+		searchContext += FormatClaimReviews(claimReviews)
+		report(fmt.Sprintf("✅ Найдены проверки фактчекеров: %d", len(claimReviews)))
+	} else if len(googleReviews) > 0 {
+		report("ℹ Похожих проверок фактов не найдено")
 	}
 
 	// Queue: wait for a free slot (max 1 concurrent AI request).
@@ -150,7 +317,11 @@ func (s *AnalyzerService) AnalyzeText(text string, progress ...func(string)) (*m
 	}
 
 	response.RawResponse = rawResponse
-	response.Usage = tokenUsage
+	response.Usage = addUsage(tokenUsage, planUsage)
+	response.SearchQueries = searchQueries
+	response.ClaimReviews = claimReviews
+	response.Rating = NormalizeVerdict(&response)
+	in.applySource(&response)
 
 	report(fmt.Sprintf("📊 Достоверность: %d/10 · манипуляций: %d · логических ошибок: %d",
 		response.CredibilityScore, len(response.Manipulations), len(response.LogicalIssues)))
@@ -162,9 +333,9 @@ func (s *AnalyzerService) AnalyzeText(text string, progress ...func(string)) (*m
 		report("🟢 Контент выглядит достоверно")
 	}
 
-	if response.CredibilityScore <= 7 && s.serper != nil && s.serper.APIKey != "" {
+	if response.CredibilityScore <= 7 && search.Enabled() {
 		report("🔎 Проверяю по независимым источникам...")
-		verification, err := s.verifyAndFindTruth(text, &response)
+		verification, err := s.verifyAndFindTruth(search, text, &response, report)
 		if err != nil {
 			report("⚠ Не удалось провести перекрёстную проверку")
 		} else {
@@ -177,28 +348,62 @@ func (s *AnalyzerService) AnalyzeText(text string, progress ...func(string)) (*m
 		}
 	}
 
-	// Сохраняем в кэш Redis на 24 часа
-	if resJSON, err := json.Marshal(response); err == nil {
-		cache.Set(cacheKey, string(resJSON), 24*time.Hour)
+	if calls, hits := search.Stats(); calls+hits > 0 {
+		if response.Usage == nil {
+			response.Usage = &models.TokenUsage{}
+		}
+		response.Usage.SearchCalls = calls
+		response.Usage.SearchCacheHits = hits
+		report(fmt.Sprintf("🔍 Поисковых запросов: %d (из кэша: %d)", calls, hits))
+	}
+
+	s.finish(in, text, cacheKey, fingerprint, &response, report)
+	return &response, nil
+}
+
+// finish проверяет текст на согласованные публикации, сохраняет результат
+// в БД (с simhash текста, 0 — без отпечатка) и в кэш Redis.
+func (s *AnalyzerService) finish(in analyzeInput, text, cacheKey string, fingerprint uint64, response *models.AnalysisResponse, report func(string)) {
+	if match := s.coordination.Match(text); match != nil {
+		response.Coordinated = match
+		report(fmt.Sprintf("🕸 %s (совпадение %.0f%%)", match.Warning, match.Overlap))
 	}
 
 	// Сохраняем в БД Postgres
 	if database.DB != nil {
 		resJSON, _ := json.Marshal(response)
-		_, err := database.DB.Exec("INSERT INTO analysis_results (text, url, result) VALUES ($1, $2, $3)",
-			text, response.SourceURL, resJSON)
+		var simhash, reusedFrom interface{}
+		if fingerprint != 0 {
+			simhash = int64(fingerprint)
+		}
+		if response.MatchedAnalysis != nil {
+			reusedFrom = response.MatchedAnalysis.AnalysisID
+		}
+		err := database.DB.QueryRow(`
+			INSERT INTO analysis_results (text, url, result, snapshot_id, published_at, simhash, reused_from)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id
+		`, text, response.SourceURL, resJSON, in.SnapshotID, in.publishedAt(), simhash, reusedFrom).Scan(&response.AnalysisID)
 		if err != nil {
 			report(fmt.Sprintf("⚠️ Ошибка сохранения в БД: %v", err))
 		} else {
 			report("💾 Результат сохранен в базу данных")
+			s.narratives.Notify()
 		}
 	}
 
+	// Сохраняем в кэш Redis на 24 часа
+	if resJSON, err := json.Marshal(response); err == nil {
+		cache.Set(cacheKey, string(resJSON), 24*time.Hour)
+	}
+
 	report("✅ Готово!")
-	return &response, nil
 }
 
 func (s *AnalyzerService) AnalyzeURL(url string, progress ...func(string)) (*models.AnalysisResponse, error) {
+	return s.AnalyzeURLWithOptions(url, AnalyzeOptions{}, progress...)
+}
+
+func (s *AnalyzerService) AnalyzeURLWithOptions(url string, opts AnalyzeOptions, progress ...func(string)) (*models.AnalysisResponse, error) {
 	report := func(msg string) {
 		log.Printf("[ANALYZER] %s", msg)
 		if len(progress) > 0 && progress[0] != nil {
@@ -208,40 +413,83 @@ func (s *AnalyzerService) AnalyzeURL(url string, progress ...func(string)) (*mod
 
 	report("🌐 Загружаю страницу...")
 
-	content, err := s.fetcher.FetchURL(url)
+	fetched, err := s.fetcher.Fetch(url)
 	if err != nil {
 		report(fmt.Sprintf("❌ Не удалось загрузить страницу: %v", err))
 		return nil, err
 	}
+	content := fetched.Text
 
+	switch fetched.Fallback {
+	case "wayback":
+		report(fmt.Sprintf("🏛 Оригинал недоступен — использую архивную копию Wayback Machine от %s", fetched.ArchivedAt))
+	case "amp":
+		report("⚡ Текст получен из AMP-версии страницы")
+	case "ld+json", "meta":
+		report(fmt.Sprintf("⚠ Текст страницы не найден, использую метаданные (%s)", fetched.Fallback))
+	}
 	report(fmt.Sprintf("✓ Страница загружена, читаю контент... (%d символов)", len(content)))
+
+	prev := findPreviousVersion(url)
+	snapshotID := SaveSnapshot(url, fetched)
+	if snapshotID != "" {
+		report("📸 Снимок страницы сохранён как доказательство")
+	}
+	edit := DetectEdit(url, prev, snapshotID, fetched)
+	if edit != nil {
+		report(fmt.Sprintf("✏️ Страница изменилась с прошлой проверки: +%d −%d ~%d абзацев", edit.Added, edit.Removed, edit.Changed))
+	}
+	// Страницы фактчекеров пополняют базу проверок
+	if fetched.page != nil {
+		if n := SaveClaimReviews(ExtractClaimReviews(string(fetched.page.Body), fetched.URL)); n > 0 {
+			report(fmt.Sprintf("🕵️ На странице есть разметка ClaimReview — сохранено проверок: %d", n))
+		}
+	}
+
+	report("🔗 Проверяю ссылки на источники в статье...")
+	citations := s.fetcher.CheckCitations(url, fetched)
+	if citations != nil {
+		report(fmt.Sprintf("✓ Ссылок: %d · рабочих: %d · битых: %d · без ссылки: %d утверждений",
+			citations.Total, citations.Working, citations.Dead, len(citations.UnlinkedClaims)))
+	}
+
 	report("🔬 Начинаю анализ содержимого...")
 
 	var progressFn func(string)
 	if len(progress) > 0 {
 		progressFn = progress[0]
 	}
-	response, err := s.AnalyzeText(content, progressFn)
+	response, err := s.analyze(analyzeInput{Text: content, URL: url, Fetch: fetched, SnapshotID: snapshotID, Citations: citations, Options: opts}, progressFn)
 	if err != nil {
 		return nil, err
 	}
+	response.StealthEdit = edit
+	if cur := LookupCuration(NormalizeDomain(url)); cur != nil && cur.Verdict != "" {
+		response.SourceDomain = cur
+		log.Printf("[ANALYZER] 🏷 Домен %s: %s (%s)", cur.Domain, cur.Verdict, cur.VerdictSource)
+	}
 
-	response.SourceURL = url
 	// Update domain reputation stats
-	UpsertDomainStats(url, response.CredibilityScore)
+	UpsertDomainStats(url, response.CredibilityScore, response.Rating)
 
-	// Обновляем запись в БД с правильным URL (AnalyzeText сохранил без URL)
-	if database.DB != nil {
-		resJSON, _ := json.Marshal(response)
-		_, dbErr := database.DB.Exec(
-			"UPDATE analysis_results SET url=$1, result=$2 WHERE id=(SELECT MAX(id) FROM analysis_results WHERE url='')",
-			url, resJSON)
-		if dbErr != nil {
-			log.Printf("[ANALYZER] ⚠ Ошибка обновления URL в БД: %v", dbErr)
+	return response, nil
+}
+
+// reportSearchErrors сообщает об ошибках поиска; поиски, на которые не
+// хватило бюджета, — одной строкой.
+func reportSearchErrors(res *MultiSearchResult, report func(string)) {
+	skipped, reason := 0, ""
+	for _, e := range res.Errors {
+		if e.Budget {
+			skipped++
+			reason = e.Error
+			continue
 		}
+		report(fmt.Sprintf("⚠ Поиск %s (%s): %s", e.Provider, e.Locale, e.Error))
+	}
+	if skipped > 0 {
+		report(fmt.Sprintf("⚠ Пропущено поисков: %d — %s", skipped, reason))
 	}
-
-	return response, nil
 }
 
 func extractJSON(text string) string {
@@ -454,7 +702,7 @@ func aggressiveRepairJSON(jsonStr string) string {
 }
 
 // verifyAndFindTruth - проверяет статью и ищет настоящую информацию
-func (s *AnalyzerService) verifyAndFindTruth(text string, analysis *models.AnalysisResponse) (*models.Verification, error) {
+func (s *AnalyzerService) verifyAndFindTruth(search *SearchService, text string, analysis *models.AnalysisResponse, report func(string)) (*models.Verification, error) {
 	log.Printf("[VERIFIER] 🔍 Начинаю глубокую верификацию...")
 
 	verification := &models.Verification{
@@ -490,54 +738,60 @@ func (s *AnalyzerService) verifyAndFindTruth(text string, analysis *models.Analy
 		return verification, nil
 	}
 
+	if len(keywords) > 3 { // Ограничиваем 3 утверждениями
+		keywords = keywords[:3]
+	}
 	log.Printf("[VERIFIER] 🔑 Ключевые утверждения для проверки: %v", keywords)
 
 	// Ищем настоящую информацию в интернете
+	// Слот модели уже занят этим анализом
+	queries, planUsage := s.planner.PlanClaims(context.Background(), nil, text, keywords, search.Langs())
+	analysis.Usage = addUsage(analysis.Usage, planUsage)
+	analysis.SearchQueries = append(analysis.SearchQueries, queries...)
+	res := search.SearchQueries(queries, 5)
+	reportSearchErrors(res, report)
+	log.Printf("[VERIFIER] ✓ Найдено %d результатов", len(res.Results))
+
 	var allResults []string
 	var verifiedSources []models.Source
-
-	for i, claim := range keywords {
-		if i >= 3 { // Ограничиваем 3 запросами
-			break
+	perClaim := map[string]int{}
+	for _, result := range search.RankResults(res.Results) {
+		// «Ненадёжные» источники не подтверждают факты
+		if result.Tier == TierUnreliable {
+			continue
 		}
-
-		log.Printf("[VERIFIER] 🌐 Проверяю утверждение %d: %s", i+1, claim)
-
-		results, err := s.serper.SearchMultiLanguage(claim)
-		if err != nil {
-			log.Printf("[VERIFIER] ⚠ Ошибка поиска: %v", err)
+		// Берем топ-3 результата на утверждение
+		if perClaim[result.Claim] >= 3 {
 			continue
 		}
+		perClaim[result.Claim]++
 
-		if len(results) > 0 {
-			log.Printf("[VERIFIER] ✓ Найдено %d результатов", len(results))
-
-			// Берем топ-3 результата
-			for j, result := range results {
-				if j >= 3 {
-					break
-				}
-
-				allResults = append(allResults, fmt.Sprintf(
-					"• %s\n  Источник: %s\n  %s",
-					result.Title, result.Link, result.Snippet,
-				))
+		allResults = append(allResults, fmt.Sprintf(
+			"• %s\n  Источник: %s (%s)\n  %s",
+			result.Title, result.Link, tierLabel(result), result.Snippet,
+		))
 
-				verifiedSources = append(verifiedSources, models.Source{
-					Title:       result.Title,
-					URL:         result.Link,
-					Description: result.Snippet,
-				})
-			}
+		if !IsDeserving(result.Tier) {
+			continue
 		}
+		verifiedSources = append(verifiedSources, models.Source{
+			Title:       result.Title,
+			URL:         result.Link,
+			Description: result.Snippet,
+			Query:       result.Query,
+			Claim:       result.Claim,
+			Tier:        result.Tier,
+		})
 	}
 
 	if len(allResults) > 0 {
-		verification.RealInformation = "НАСТОЯЩАЯ ИНФОРМАЦИЯ ИЗ ПРОВЕРЕННЫХ ИСТОЧНИКОВ:\n\n" +
+		verification.RealInformation = "ИНФОРМАЦИЯ ИЗ ИСТОЧНИКОВ (надёжность указана в скобках):\n\n" +
 			strings.Join(allResults, "\n\n")
-		verification.VerifiedSources = verifiedSources
+		if len(verifiedSources) > 0 {
+			verification.VerifiedSources = verifiedSources
+		}
 
-		log.Printf("[VERIFIER] ✅ Найдена настоящая информация из %d источников", len(verifiedSources))
+		log.Printf("[VERIFIER] ✅ Найдена информация из %d источников, надёжных: %d", len(allResults), len(verifiedSources))
 	} else {
 		verification.RealInformation = "Не удалось найти достоверную информацию для проверки утверждений из статьи."
 		log.Printf("[VERIFIER] ⚠ Настоящая информация не найдена")
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"text-analyzer/models"
+)
+
+// Ответ из кэша сохранён для страницы; тот же текст, присланный без URL или
+// с другой страницы, не должен получить её источник.
+func TestApplySourceReplacesCachedSource(t *testing.T) {
+	cached := func() *models.AnalysisResponse {
+		return &models.AnalysisResponse{
+			Summary:       "Плафонирование цен",
+			SourceURL:     "https://example.md/news/1",
+			SnapshotID:    "snap-1",
+			Citations:     &models.CitationReport{Total: 3},
+			FetchFallback: "wayback",
+			ArchivedAt:    "20240101000000",
+			FetchedFrom:   "https://web.archive.org/web/2024/https://example.md/news/1",
+		}
+	}
+
+	response := cached()
+	analyzeInput{Text: simhashArticle}.applySource(response)
+	if response.SourceURL != "" || response.SnapshotID != "" || response.Citations != nil ||
+		response.FetchFallback != "" || response.ArchivedAt != "" || response.FetchedFrom != "" {
+		t.Errorf("text input kept the cached source: %+v", response)
+	}
+	if response.Summary == "" {
+		t.Error("assessment must be kept")
+	}
+
+	response = cached()
+	citations := &models.CitationReport{Total: 1}
+	analyzeInput{
+		Text:       simhashArticle,
+		URL:        "https://other.md/a",
+		Fetch:      &FetchResult{URL: "https://other.md/a"},
+		SnapshotID: "snap-2",
+		Citations:  citations,
+	}.applySource(response)
+	if response.SourceURL != "https://other.md/a" || response.SnapshotID != "snap-2" || response.Citations != citations {
+		t.Errorf("URL input: source_url=%q snapshot=%q", response.SourceURL, response.SnapshotID)
+	}
+	if response.FetchFallback != "" || response.ArchivedAt != "" || response.FetchedFrom != "" {
+		t.Errorf("fetch details of the cached page leaked: %+v", response)
+	}
+}
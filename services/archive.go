@@ -0,0 +1,177 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultArchiveAPIURL — Wayback Machine availability API.
+const DefaultArchiveAPIURL = "https://archive.org/wayback/available"
+
+// waybackAvailability — ответ availability API.
+type waybackAvailability struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// waybackTimestampRe находит метку времени в пути снимка: /web/20240101123456/...
+var waybackTimestampRe = regexp.MustCompile(`/web/(\d{14})/`)
+
+// rawSnapshotURL переключает ссылку снимка на режим id_ — оригинальный HTML
+// без панели Wayback и переписанных ссылок.
+func rawSnapshotURL(snapshotURL string) string {
+	return waybackTimestampRe.ReplaceAllString(snapshotURL, "/web/${1}id_/")
+}
+
+// tryWayback ищет ближайший снимок страницы в Wayback Machine и извлекает из него текст.
+func (f *ContentFetcher) tryWayback(pageURL string) *FetchResult {
+	if f.crawl.ArchiveAPIURL == "" {
+		return nil
+	}
+	log.Printf("[FETCHER] 🏛 Ищу архивную копию в Wayback Machine...")
+
+	apiURL := f.crawl.ArchiveAPIURL + "?url=" + url.QueryEscape(pageURL)
+	page, err := f.get(apiURL, f.client, "")
+	if err != nil || page.StatusCode != http.StatusOK {
+		log.Printf("[FETCHER] ⚠ Wayback API недоступен: %v", err)
+		return nil
+	}
+
+	var avail waybackAvailability
+	if err := json.Unmarshal(page.Body, &avail); err != nil {
+		log.Printf("[FETCHER] ⚠ Ошибка парсинга ответа Wayback: %v", err)
+		return nil
+	}
+	closest := avail.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available || closest.URL == "" {
+		log.Printf("[FETCHER] ℹ Архивных копий нет")
+		return nil
+	}
+
+	snapshotURL := rawSnapshotURL(closest.URL)
+	// API иногда отдаёт http-ссылки на web.archive.org
+	if strings.HasPrefix(snapshotURL, "http://web.archive.org/") {
+		snapshotURL = "https://" + strings.TrimPrefix(snapshotURL, "http://")
+	}
+	log.Printf("[FETCHER] 🏛 Найден снимок от %s: %s", closest.Timestamp, snapshotURL)
+
+	res := f.fetchAlternate(snapshotURL, "wayback")
+	if res != nil {
+		res.ArchivedAt = closest.Timestamp
+	}
+	return res
+}
+
+// tryAMP пробует AMP-версию страницы: сначала по <link rel="amphtml">,
+// затем по соглашению «URL + /amp».
+func (f *ContentFetcher) tryAMP(pageURL string, body []byte) *FetchResult {
+	var candidates []string
+	if body != nil {
+		if href := findAmpLink(string(body)); href != "" {
+			if resolved := resolveURL(pageURL, href); resolved != "" {
+				candidates = append(candidates, resolved)
+			}
+		}
+	}
+	if u, err := url.Parse(pageURL); err == nil && !strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/amp") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/amp"
+		u.Fragment = ""
+		if len(candidates) == 0 || candidates[0] != u.String() {
+			candidates = append(candidates, u.String())
+		}
+	}
+
+	for _, ampURL := range candidates {
+		log.Printf("[FETCHER] ⚡ Пробую AMP-версию: %s", ampURL)
+		if res := f.fetchAlternate(ampURL, "amp"); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// fetchAlternate загружает альтернативную копию страницы и возвращает результат,
+// только если в ней достаточно текста.
+func (f *ContentFetcher) fetchAlternate(altURL, fallback string) *FetchResult {
+	page, err := f.get(altURL, f.client, "")
+	if err != nil {
+		log.Printf("[FETCHER] ⚠ %s: %v", fallback, err)
+		return nil
+	}
+	if page.StatusCode != http.StatusOK {
+		log.Printf("[FETCHER] ⚠ %s: статус %d", fallback, page.StatusCode)
+		return nil
+	}
+
+	text := f.extractText(string(page.Body))
+	if len(text) < 200 {
+		text = f.extractLdJson(string(page.Body))
+	}
+	if len(text) < 200 {
+		log.Printf("[FETCHER] ⚠ %s: слишком мало текста (%d символов)", fallback, len(text))
+		return nil
+	}
+
+	log.Printf("[FETCHER] ✓ Фолбек %s сработал: %d символов", fallback, len(text))
+	return &FetchResult{Text: text, URL: page.URL, Fallback: fallback, page: page}
+}
+
+// findAmpLink ищет <link rel="amphtml" href="...">.
+func findAmpLink(htmlStr string) string {
+	doc, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		return ""
+	}
+	var href string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if href != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, h string
+			for _, attr := range n.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "href":
+					h = attr.Val
+				}
+			}
+			if rel == "amphtml" && h != "" {
+				href = h
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return href
+}
+
+// resolveURL превращает относительную ссылку в абсолютную относительно base.
+func resolveURL(base, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return b.ResolveReference(r).String()
+}
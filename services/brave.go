@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// BraveClient — Brave Search API (веб-результаты и новости).
+type BraveClient struct {
+	APIKey string
+}
+
+type braveResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Age         string `json:"age"`
+}
+
+type braveResponse struct {
+	Web struct {
+		Results []braveResult `json:"results"`
+	} `json:"web"`
+	News struct {
+		Results []braveResult `json:"results"`
+	} `json:"news"`
+}
+
+func NewBraveClient(apiKey string) *BraveClient {
+	return &BraveClient{APIKey: apiKey}
+}
+
+func (b *BraveClient) Name() string { return "brave" }
+
+func (b *BraveClient) Search(ctx context.Context, query string, locale SearchLocale, num int) ([]SearchResult, error) {
+	log.Printf("[BRAVE] 🔍 Поиск: \"%s\" (%s)", query, locale.Name)
+
+	params := url.Values{}
+	params.Set("q", query)
+	if num > 0 {
+		params.Set("count", strconv.Itoa(num))
+	}
+	if locale.Hl != "" {
+		params.Set("search_lang", locale.Hl)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.search.brave.com/res/v1/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.APIKey)
+
+	resp, err := searchHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Brave API вернул %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var br braveResponse
+	if err := json.Unmarshal(body, &br); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	var results []SearchResult
+	for _, r := range append(br.Web.Results, br.News.Results...) {
+		results = append(results, SearchResult{Title: r.Title, Link: r.URL, Snippet: r.Description, Date: r.Age})
+	}
+	log.Printf("[BRAVE] ✓ Найдено результатов: %d", len(results))
+	return results, nil
+}
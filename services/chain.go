@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,20 +23,30 @@ type ChainNode struct {
 	URL              string       `json:"url"`
 	Title            string       `json:"title"`
 	Domain           string       `json:"domain"`
-	PublishedHint    string       `json:"published_hint"` // дата если видна в тексте
-	IsOriginal       bool         `json:"is_original"`
+	PublishedHint    string       `json:"published_hint"`         // дата если видна в тексте
+	PublishedAt      *time.Time   `json:"published_at,omitempty"` // из метаданных страницы
+	ModifiedAt       *time.Time   `json:"modified_at,omitempty"`
+	IsOriginal       bool         `json:"is_original"` // вероятный первоисточник (известен к chain_done)
+	IsInput          bool         `json:"is_input"`    // ссылка, присланная пользователем
 	CredibilityScore int          `json:"credibility_score"`
 	KeyClaims        []string     `json:"key_claims"`
 	Distortions      []Distortion `json:"distortions"`
 	DistortionScore  int          `json:"distortion_score"` // 0=без искажений, 10=полностью перевран
 	Summary          string       `json:"summary"`
+	Depth            int          `json:"depth"`                // 0 — исходная статья
+	ParentURL        string       `json:"parent_url,omitempty"` // с каким узлом сравнивали
 }
 
-// ChainResult — итоговое дерево цепочки.
+// ChainResult — итоговый граф цепочки: узлы и рёбра заимствований.
 type ChainResult struct {
-	Topic       string      `json:"topic"`
-	OriginalURL string      `json:"original_url"`
-	Nodes       []ChainNode `json:"nodes"`
+	ID           string      `json:"id,omitempty"` // для GET /api/chain/{id} и ссылок /s/
+	CreatedAt    time.Time   `json:"created_at"`
+	Topic        string      `json:"topic"`
+	InputURL     string      `json:"input_url"`
+	OriginalURL  string      `json:"original_url"` // вероятный первоисточник
+	OriginReason string      `json:"origin_reason"`
+	Nodes        []ChainNode `json:"nodes"`
+	Edges        []ChainEdge `json:"edges"`
 }
 
 // ChainEvent передаётся клиенту по SSE по мере обработки.
@@ -46,26 +57,122 @@ type ChainEvent struct {
 	Result  *ChainResult `json:"result,omitempty"`
 }
 
+// ChainConfig — ограничения на размер цепочки и параллельность.
+type ChainConfig struct {
+	Workers    int // статей, загружаемых одновременно (запросы к модели — через общую очередь)
+	MaxBreadth int // верхний предел breadth в запросе
+	MaxDepth   int // верхний предел depth в запросе
+	MaxNodes   int // всего узлов в цепочке
+	CacheTTL   time.Duration
+}
+
+// ChainOptions — параметры конкретного запроса.
+type ChainOptions struct {
+	Breadth int  // производных статей на узел (0 — по умолчанию)
+	Depth   int  // уровней расширения: 1 — только пересказы оригинала (0 — по умолчанию)
+	Fresh   bool // не брать цепочку из кэша
+}
+
+const (
+	defaultChainBreadth = 5
+	defaultChainDepth   = 1
+)
+
 // ChainService строит цепочку источников для заданного URL.
 type ChainService struct {
 	client  AIClient
+	limiter AILimiter // nil — без общей очереди
 	fetcher *ContentFetcher
-	serper  *SerperClient
+	search  *SearchService
+	cfg     ChainConfig
 }
 
-func NewChainService(client AIClient, fetcher *ContentFetcher, serper *SerperClient) *ChainService {
-	return &ChainService{client: client, fetcher: fetcher, serper: serper}
+func NewChainService(client AIClient, limiter AILimiter, fetcher *ContentFetcher, search *SearchService, cfg ChainConfig) *ChainService {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 3
+	}
+	if cfg.MaxBreadth <= 0 {
+		cfg.MaxBreadth = 10
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 3
+	}
+	if cfg.MaxNodes <= 0 {
+		cfg.MaxNodes = 20
+	}
+	return &ChainService{client: client, limiter: limiter, fetcher: fetcher, search: search, cfg: cfg}
+}
+
+// analyze отправляет промпт модели, дождавшись слота в общей очереди.
+func (s *ChainService) analyze(ctx context.Context, prompt string) (string, error) {
+	if s.limiter != nil {
+		release, err := s.limiter.AcquireAI(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	}
+	raw, _, err := s.client.Analyze(prompt)
+	return raw, err
+}
+
+// limits приводит параметры запроса к допустимым.
+func (s *ChainService) limits(opts ChainOptions) ChainOptions {
+	if opts.Breadth <= 0 {
+		opts.Breadth = defaultChainBreadth
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = defaultChainDepth
+	}
+	opts.Breadth = min(opts.Breadth, s.cfg.MaxBreadth)
+	opts.Depth = min(opts.Depth, s.cfg.MaxDepth)
+	return opts
+}
+
+// chainCandidate — найденная статья, которую нужно сравнить с узлом-родителем.
+type chainCandidate struct {
+	URL    string
+	Title  string
+	Parent *ChainNode
+	Depth  int
 }
 
 // BuildChain — основной метод. Стримит ChainEvent через emit по мере работы.
-func (s *ChainService) BuildChain(ctx context.Context, inputURL string, emit func(ChainEvent)) error {
-	emit(ChainEvent{Type: "chain_start", Message: "🔍 Загружаю исходную статью..."})
+// Уровень за уровнем: ищет пересказы узлов предыдущего уровня (для оригинала —
+// по теме, для производных — по их искажённым утверждениям) и параллельно
+// сравнивает найденные статьи с родителем.
+func (s *ChainService) BuildChain(ctx context.Context, inputURL string, opts ChainOptions, emit func(ChainEvent)) error {
+	opts = s.limits(opts)
+	var emitMu sync.Mutex
+	emitSafe := func(ev ChainEvent) {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		emit(ev)
+	}
+	emitSafe(ChainEvent{Type: "chain_start", Message: "🔍 Загружаю исходную статью..."})
+
+	if !opts.Fresh {
+		if cached := cachedChain(inputURL, opts); cached != nil {
+			emitSafe(ChainEvent{Type: "chain_progress", Message: "♻ Цепочка для этой ссылки уже построена — отдаю сохранённую"})
+			for i := range cached.Nodes {
+				emitSafe(ChainEvent{Type: "chain_node", Node: &cached.Nodes[i]})
+			}
+			emitSafe(ChainEvent{
+				Type:    "chain_done",
+				Message: fmt.Sprintf("✅ Цепочка из кэша · %d источников", len(cached.Nodes)),
+				Result:  cached,
+			})
+			return nil
+		}
+	}
 
-	// 1. Загружаем оригинал
-	content, err := s.fetcher.FetchURL(inputURL)
+	// 1. Загружаем исходную статью
+	page, err := s.fetcher.Fetch(inputURL)
 	if err != nil {
 		return fmt.Errorf("не удалось загрузить статью: %w", err)
 	}
+	content := page.Text
+	shingles := map[string]map[uint64]bool{inputURL: textShingles(content)}
 	if len(content) < 100 {
 		return fmt.Errorf("недостаточно текста для анализа")
 	}
@@ -74,105 +181,220 @@ func (s *ChainService) BuildChain(ctx context.Context, inputURL string, emit fun
 		content = string(runes[:4000])
 	}
 
-	emit(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Загружено %d симв., извлекаю тему и утверждения...", len(content))})
+	emitSafe(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Загружено %d симв., извлекаю тему и утверждения...", len(content))})
 
 	// 2. Извлекаем тему, поисковый запрос и ключевые утверждения оригинала
-	topic, searchQuery, originalClaims, err := s.extractTopicAndClaims(content)
+	topic, searchQuery, originalClaims, err := s.extractTopicAndClaims(ctx, content)
 	if err != nil {
 		return fmt.Errorf("ошибка извлечения темы: %w", err)
 	}
 
-	emit(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Тема: «%s» · ищу похожие публикации...", chainTruncate(topic, 60))})
+	emitSafe(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Тема: «%s» · ищу похожие публикации (ширина %d, глубина %d)...", chainTruncate(topic, 60), opts.Breadth, opts.Depth)})
 
-	// Узел оригинала
+	// Узел исходной статьи. Первоисточник ли это — станет ясно в конце,
+	// достоверность берём из истории домена.
 	originalNode := ChainNode{
-		URL:              inputURL,
-		Title:            topic,
-		Domain:           chainExtractDomain(inputURL),
-		IsOriginal:       true,
-		CredibilityScore: 8,
-		KeyClaims:        originalClaims,
-		Distortions:      []Distortion{},
-		DistortionScore:  0,
-		Summary:          "Исходная статья — точка отсчёта",
+		URL:             inputURL,
+		Title:           topic,
+		Domain:          chainExtractDomain(inputURL),
+		PublishedAt:     page.PublishedAt,
+		ModifiedAt:      page.ModifiedAt,
+		IsInput:         true,
+		KeyClaims:       originalClaims,
+		Distortions:     []Distortion{},
+		DistortionScore: 0,
+		Summary:         "Исходная статья — точка отсчёта",
 	}
-	emit(ChainEvent{Type: "chain_node", Node: &originalNode})
-
-	if s.serper == nil || s.serper.APIKey == "" {
-		return fmt.Errorf("Serper API недоступен — поиск похожих статей невозможен")
+	if ds, ok := LookupDomainScores([]string{NormalizeDomain(inputURL)})[NormalizeDomain(inputURL)]; ok && ds.Total > 0 {
+		originalNode.CredibilityScore = int(ds.Score + 0.5)
 	}
+	emitSafe(ChainEvent{Type: "chain_node", Node: &originalNode})
 
-	// 3. Ищем похожие статьи через Serper
-	emit(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("🌐 Поиск: «%s»...", searchQuery)})
-
-	results, err := s.serper.SearchMultiLanguage(searchQuery)
-	if err != nil {
-		return fmt.Errorf("ошибка поиска: %w", err)
+	// Без поиска цепочка состоит только из оригинала.
+	search := s.search.NewSession()
+	if !search.Enabled() {
+		emitSafe(ChainEvent{Type: "chain_progress", Message: "⚠ Поиск не настроен — похожие публикации не ищу"})
 	}
 
-	emit(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Найдено %d ссылок · анализирую по очереди...", len(results))})
-
-	// 4. Анализируем каждую найденную статью
 	nodes := []ChainNode{originalNode}
-	analyzed := 0
-	const maxNodes = 5
 	originalDomain := chainExtractDomain(inputURL)
+	seen := map[string]bool{searchDedupKey(inputURL): true}
+	frontier := []*ChainNode{&originalNode}
 
-	for _, result := range results {
+	for depth := 1; depth <= opts.Depth && len(frontier) > 0 && search.Enabled(); depth++ {
 		if ctx.Err() != nil {
 			break
 		}
-		if analyzed >= maxNodes {
-			break
+
+		// 3. Ищем пересказы каждого узла уровня
+		var candidates []chainCandidate
+		for _, parent := range frontier {
+			query := searchQuery
+			if !parent.IsInput {
+				query = distortionQuery(parent)
+			}
+			if query == "" {
+				continue
+			}
+			emitSafe(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("🌐 Поиск: «%s»...", query)})
+			results, err := search.SearchMultiLanguage(query)
+			if err != nil {
+				log.Printf("[CHAIN] ⚠ Ошибка поиска: %v", err)
+				emitSafe(ChainEvent{Type: "chain_progress", Message: "⚠ Поиск похожих публикаций недоступен"})
+				continue
+			}
+			added := 0
+			for _, result := range results {
+				key := searchDedupKey(result.Link)
+				if added >= opts.Breadth || len(nodes)+len(candidates) >= s.cfg.MaxNodes {
+					break
+				}
+				if seen[key] || chainExtractDomain(result.Link) == originalDomain {
+					continue
+				}
+				seen[key] = true
+				candidates = append(candidates, chainCandidate{URL: result.Link, Title: result.Title, Parent: parent, Depth: depth})
+				added++
+			}
 		}
-		if chainExtractDomain(result.Link) == originalDomain {
-			continue
+		if len(candidates) == 0 {
+			break
 		}
-
-		emit(ChainEvent{
-			Type:    "chain_progress",
-			Message: fmt.Sprintf("🔎 Проверяю %s...", chainExtractDomain(result.Link)),
-		})
-
-		node, err := s.analyzeRelatedArticle(ctx, result.Link, result.Title, originalClaims)
-		if err != nil {
-			log.Printf("[CHAIN] ⚠ %s: %v", result.Link, err)
-			continue
+		emitSafe(ChainEvent{Type: "chain_progress", Message: fmt.Sprintf("✓ Уровень %d: найдено %d ссылок · анализирую параллельно...", depth, len(candidates))})
+
+		// 4. Анализируем найденные статьи параллельно
+		level, levelShingles := s.analyzeCandidates(ctx, candidates, emitSafe)
+		frontier = frontier[:0]
+		for i := range level {
+			nodes = append(nodes, level[i])
+			shingles[level[i].URL] = levelShingles[i]
+			// Дальше расширяем только узлы с искажениями
+			if len(level[i].Distortions) > 0 {
+				frontier = append(frontier, &level[i])
+			}
 		}
-		if !node.IsSameStory {
-			log.Printf("[CHAIN] ↷ %s — другая тема, пропускаю", result.Link)
-			continue
+		if len(nodes) >= s.cfg.MaxNodes {
+			break
 		}
+	}
 
-		chainNode := ChainNode{
-			URL:              result.Link,
-			Title:            node.Title,
-			Domain:           chainExtractDomain(result.Link),
-			IsOriginal:       false,
-			CredibilityScore: node.CredibilityScore,
-			KeyClaims:        node.KeyClaims,
-			Distortions:      node.Distortions,
-			DistortionScore:  node.DistortionScore,
-			Summary:          node.Summary,
-			PublishedHint:    node.PublishedHint,
+	// 5. Граф заимствований и вероятный первоисточник
+	edges, origin, reason := buildChainGraph(nodes, shingles)
+	for i := range nodes {
+		nodes[i].IsOriginal = i == origin
+	}
+	if edges == nil {
+		edges = []ChainEdge{}
+	}
+	log.Printf("[CHAIN] ✓ Первоисточник: %s (%s)", nodes[origin].URL, reason)
+
+	result := &ChainResult{
+		Topic:        topic,
+		InputURL:     inputURL,
+		OriginalURL:  nodes[origin].URL,
+		OriginReason: reason,
+		Nodes:        nodes,
+		Edges:        edges,
+	}
+	// Прерванную цепочку не сохраняем — она неполная
+	if ctx.Err() == nil {
+		if err := SaveChain(result, opts, s.cfg.CacheTTL); err != nil {
+			log.Printf("[CHAIN] ⚠ Ошибка сохранения цепочки: %v", err)
+		} else {
+			log.Printf("[CHAIN] 💾 Цепочка сохранена: %s", result.ID)
 		}
-		nodes = append(nodes, chainNode)
-		analyzed++
-		emit(ChainEvent{Type: "chain_node", Node: &chainNode})
 	}
 
-	emit(ChainEvent{
+	emitSafe(ChainEvent{
 		Type:    "chain_done",
-		Message: fmt.Sprintf("✅ Цепочка построена · %d источников проанализировано", len(nodes)),
-		Result: &ChainResult{
-			Topic:       topic,
-			OriginalURL: inputURL,
-			Nodes:       nodes,
-		},
+		Message: fmt.Sprintf("✅ Цепочка построена · %d источников проанализировано · первоисточник: %s", len(nodes), nodes[origin].Domain),
+		Result:  result,
 	})
 	return nil
 }
 
+// analyzeCandidates сравнивает статьи с их родителями пулом из cfg.Workers
+// воркеров. Каждый готовый узел сразу уходит клиенту событием chain_node.
+// Вместе с узлами возвращает шинглы их текстов.
+func (s *ChainService) analyzeCandidates(ctx context.Context, candidates []chainCandidate, emit func(ChainEvent)) ([]ChainNode, []map[uint64]bool) {
+	results := make([]*ChainNode, len(candidates))
+	texts := make([]map[uint64]bool, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.cfg.Workers, len(candidates)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				c := candidates[i]
+				emit(ChainEvent{
+					Type:    "chain_progress",
+					Message: fmt.Sprintf("🔎 Проверяю %s...", chainExtractDomain(c.URL)),
+				})
+
+				node, page, err := s.analyzeRelatedArticle(ctx, c.URL, c.Title, c.Parent.KeyClaims)
+				if err != nil {
+					log.Printf("[CHAIN] ⚠ %s: %v", c.URL, err)
+					continue
+				}
+				if !node.IsSameStory {
+					log.Printf("[CHAIN] ↷ %s — другая тема, пропускаю", c.URL)
+					continue
+				}
+
+				chainNode := &ChainNode{
+					URL:              c.URL,
+					Title:            node.Title,
+					Domain:           chainExtractDomain(c.URL),
+					IsOriginal:       false,
+					CredibilityScore: node.CredibilityScore,
+					KeyClaims:        node.KeyClaims,
+					Distortions:      node.Distortions,
+					DistortionScore:  node.DistortionScore,
+					Summary:          node.Summary,
+					PublishedHint:    node.PublishedHint,
+					PublishedAt:      page.PublishedAt,
+					ModifiedAt:       page.ModifiedAt,
+					Depth:            c.Depth,
+					ParentURL:        c.Parent.URL,
+				}
+				results[i] = chainNode
+				texts[i] = textShingles(page.Text)
+				emit(ChainEvent{Type: "chain_node", Node: chainNode})
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var nodes []ChainNode
+	var shingles []map[uint64]bool
+	for i, n := range results {
+		if n != nil {
+			nodes = append(nodes, *n)
+			shingles = append(shingles, texts[i])
+		}
+	}
+	return nodes, shingles
+}
+
+// distortionQuery — поисковый запрос по искажённым утверждениям узла.
+func distortionQuery(node *ChainNode) string {
+	var changed []string
+	for _, d := range node.Distortions {
+		if d.Changed != "" {
+			changed = append(changed, d.Changed)
+		}
+	}
+	return heuristicQuery(strings.Join(changed, ". "))
+}
+
 // articleAnalysis — внутренний результат парсинга ответа AI на запрос сравнения.
 type articleAnalysis struct {
 	IsSameStory      bool         `json:"is_same_story"`
@@ -186,17 +408,19 @@ type articleAnalysis struct {
 }
 
 // analyzeRelatedArticle загружает статью и сравнивает с оригиналом через AI.
-func (s *ChainService) analyzeRelatedArticle(ctx context.Context, articleURL, title string, originalClaims []string) (*articleAnalysis, error) {
+// Возвращает и загруженную страницу — её текст и даты нужны для графа.
+func (s *ChainService) analyzeRelatedArticle(ctx context.Context, articleURL, title string, originalClaims []string) (*articleAnalysis, *FetchResult, error) {
 	fetchCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 	_ = fetchCtx
 
-	content, err := s.fetcher.FetchURL(articleURL)
+	page, err := s.fetcher.Fetch(articleURL)
 	if err != nil {
-		return nil, fmt.Errorf("fetch: %w", err)
+		return nil, nil, fmt.Errorf("fetch: %w", err)
 	}
+	content := page.Text
 	if len(content) < 80 {
-		return nil, fmt.Errorf("слишком мало текста")
+		return nil, nil, fmt.Errorf("слишком мало текста")
 	}
 	if len([]rune(content)) > 3000 {
 		runes := []rune(content)
@@ -240,9 +464,9 @@ distortion_score: 0=без искажений, 10=смысл полностью
 Если статьи на разные темы — is_same_story: false, остальные поля пустые.`,
 		claimsStr, content)
 
-	rawResponse, _, err := s.client.Analyze(prompt)
+	rawResponse, err := s.analyze(ctx, prompt)
 	if err != nil {
-		return nil, fmt.Errorf("AI: %w", err)
+		return nil, nil, fmt.Errorf("AI: %w", err)
 	}
 
 	jsonStr := extractJSON(rawResponse)
@@ -250,7 +474,7 @@ distortion_score: 0=без искажений, 10=смысл полностью
 
 	var analysis articleAnalysis
 	if err := json.Unmarshal([]byte(jsonStr), &analysis); err != nil {
-		return nil, fmt.Errorf("parse: %w", err)
+		return nil, nil, fmt.Errorf("parse: %w", err)
 	}
 	if analysis.Title == "" {
 		analysis.Title = title
@@ -258,11 +482,11 @@ distortion_score: 0=без искажений, 10=смысл полностью
 	if analysis.Distortions == nil {
 		analysis.Distortions = []Distortion{}
 	}
-	return &analysis, nil
+	return &analysis, page, nil
 }
 
 // extractTopicAndClaims извлекает тему, поисковый запрос и ключевые утверждения.
-func (s *ChainService) extractTopicAndClaims(text string) (topic, searchQuery string, claims []string, err error) {
+func (s *ChainService) extractTopicAndClaims(ctx context.Context, text string) (topic, searchQuery string, claims []string, err error) {
 	prompt := fmt.Sprintf(`Проанализируй статью и верни ТОЛЬКО JSON без markdown:
 
 %s
@@ -274,7 +498,7 @@ func (s *ChainService) extractTopicAndClaims(text string) (topic, searchQuery st
   "key_claims": ["главное утверждение 1", "главное утверждение 2", "главное утверждение 3"]
 }`, text)
 
-	rawResponse, _, err := s.client.Analyze(prompt)
+	rawResponse, err := s.analyze(ctx, prompt)
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text-analyzer/models"
+	"time"
+)
+
+// chainTestAI отвечает по метке статьи в промпте и запоминает, сколько
+// запросов к модели шло одновременно.
+type chainTestAI struct {
+	articles     map[string]articleAnalysis
+	active, peak atomic.Int32
+}
+
+func (a *chainTestAI) Analyze(prompt string) (string, *models.TokenUsage, error) {
+	n := a.active.Add(1)
+	defer a.active.Add(-1)
+	for {
+		p := a.peak.Load()
+		if n <= p || a.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if strings.Contains(prompt, "Проанализируй статью") {
+		return `{"topic":"Plafonarea prețurilor","search_query":"plafonare preturi energie","key_claims":["Prețul la energie va fi plafonat"]}`, nil, nil
+	}
+	for marker, analysis := range a.articles {
+		if strings.Contains(prompt, marker) {
+			raw, _ := json.Marshal(analysis)
+			return string(raw), nil, nil
+		}
+	}
+	return `{"is_same_story":false}`, nil, nil
+}
+
+// chainTestSearch отдаёт результаты по ключевому слову в запросе.
+type chainTestSearch struct {
+	byKeyword map[string][]string
+	mu        sync.Mutex
+	queries   []string
+}
+
+func (s *chainTestSearch) Name() string { return "chain-test" }
+
+func (s *chainTestSearch) Search(_ context.Context, query string, _ SearchLocale, _ int) ([]SearchResult, error) {
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	s.mu.Unlock()
+	for kw, links := range s.byKeyword {
+		if strings.Contains(strings.ToLower(query), kw) {
+			return results(links...), nil
+		}
+	}
+	return nil, nil
+}
+
+func chainPage(marker string) string {
+	return "<html><body><article><p>" + marker + " " +
+		strings.Repeat("Guvernul a anunțat plafonarea prețului la energia electrică pentru consumatorii casnici. ", 4) +
+		"</p></article></body></html>"
+}
+
+func TestBuildChainLimitsAndExpansionOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, chainPage("MARKER"+strings.ReplaceAll(r.URL.Path, "/", "-")))
+	}))
+	defer srv.Close()
+	port := strings.TrimPrefix(srv.URL, "http://127.0.0.1")
+	input := "http://localhost" + port + "/orig"
+	link := func(name string) string { return srv.URL + "/" + name }
+
+	changed := func(text string) []Distortion {
+		return []Distortion{{Type: "change", Original: "plafonat", Changed: text}}
+	}
+	ai := &chainTestAI{articles: map[string]articleAnalysis{
+		"MARKER-a1": {IsSameStory: true, Title: "a1", Distortions: changed("Prețul se dublează la Zorilor")},
+		"MARKER-a2": {IsSameStory: true, Title: "a2"},
+		"MARKER-a3": {IsSameStory: false},
+		"MARKER-a4": {IsSameStory: true, Title: "a4"},
+		"MARKER-b1": {IsSameStory: true, Title: "b1", Distortions: changed("Facturile cresc la Ialoveni")},
+		"MARKER-b2": {IsSameStory: true, Title: "b2"},
+		"MARKER-b3": {IsSameStory: true, Title: "b3"},
+		"MARKER-b4": {IsSameStory: true, Title: "b4"},
+	}}
+	provider := &chainTestSearch{byKeyword: map[string][]string{
+		// ссылка на сайт оригинала пропускается и не занимает место в ширине
+		"plafonare": {"http://localhost" + port + "/other", link("a1"), link("a2"), link("a3"), link("a4")},
+		"zorilor":   {link("a2"), link("b1"), link("b2"), link("b3"), link("b4")},
+		"ialoveni":  {link("c1")},
+	}}
+	search := NewSearchService(SearchConfig{Locales: DefaultSearchLocales[:1]}, provider)
+
+	crawl := DefaultCrawlConfig()
+	crawl.HostInterval = 0
+	crawl.ArchiveAPIURL = ""
+	policy := DefaultFetchPolicy()
+	policy.AllowPrivate = true
+	fetcher := NewContentFetcher(policy, crawl)
+
+	analyzer := &AnalyzerService{sem: make(chan struct{}, 1)}
+	chain := NewChainService(ai, analyzer, fetcher, search, ChainConfig{Workers: 3})
+
+	var result *ChainResult
+	err := chain.BuildChain(context.Background(), input, ChainOptions{Breadth: 3, Depth: 2, Fresh: true}, func(ev ChainEvent) {
+		if ev.Type == "chain_done" {
+			result = ev.Result
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("no chain_done event")
+	}
+
+	// Уровень 1: a1, a2 (a3 — другая тема, a4 за пределами ширины);
+	// уровень 2 — пересказы a1 (единственного узла с искажениями): b1..b3.
+	want := []struct {
+		title  string
+		depth  int
+		parent string
+	}{
+		{"Plafonarea prețurilor", 0, ""},
+		{"a1", 1, input},
+		{"a2", 1, input},
+		{"b1", 2, link("a1")},
+		{"b2", 2, link("a1")},
+		{"b3", 2, link("a1")},
+	}
+	if len(result.Nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d: %+v", len(result.Nodes), len(want), result.Nodes)
+	}
+	for i, w := range want {
+		n := result.Nodes[i]
+		if n.Title != w.title || n.Depth != w.depth || n.ParentURL != w.parent {
+			t.Errorf("node %d = {%s depth %d parent %s}, want {%s depth %d parent %s}",
+				i, n.Title, n.Depth, n.ParentURL, w.title, w.depth, w.parent)
+		}
+	}
+
+	// Глубина 2: искажения b1 уже не расширяются
+	for _, q := range provider.queries {
+		if strings.Contains(strings.ToLower(q), "ialoveni") {
+			t.Errorf("searched level 3 (%q) with depth 2", q)
+		}
+	}
+
+	if peak := ai.peak.Load(); peak != 1 {
+		t.Errorf("%d AI requests ran at once, want 1 (shared analyzer slot)", peak)
+	}
+	if len(analyzer.sem) != 0 || analyzer.waiting.Load() != 0 {
+		t.Error("analyzer slot not released")
+	}
+}
+
+func TestChainAnalyzeWaitsForAnalyzerSlot(t *testing.T) {
+	analyzer := &AnalyzerService{sem: make(chan struct{}, 1)}
+	analyzer.sem <- struct{}{} // идёт анализ
+	chain := NewChainService(&chainTestAI{}, analyzer, nil, nil, ChainConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := chain.analyze(ctx, "Проанализируй статью"); err == nil {
+		t.Fatal("chain must wait for the analyzer slot")
+	}
+	if analyzer.waiting.Load() != 0 {
+		t.Error("cancelled wait must leave the queue")
+	}
+
+	<-analyzer.sem
+	if _, err := chain.analyze(context.Background(), "Проанализируй статью"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Экспорт цепочки в графовые форматы: Graphviz DOT, GraphML (Gephi, yEd),
+// JSON Graph Format и готовый SVG для вставки в отчёт.
+// Узлы получают идентификаторы n0, n1, … в порядке ChainResult.Nodes.
+
+// chainNodeIDs — URL узла → идентификатор в экспорте.
+func chainNodeIDs(res *ChainResult) map[string]string {
+	ids := make(map[string]string, len(res.Nodes))
+	for i, n := range res.Nodes {
+		ids[n.URL] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+// chainEdgeDistortions — типы искажений вдоль ребра. Искажения узла найдены
+// относительно его родителя в поиске, поэтому относятся к ребру между ними.
+func chainEdgeDistortions(res *ChainResult, e ChainEdge) []string {
+	var types []string
+	seen := map[string]bool{}
+	for _, n := range res.Nodes {
+		if !(n.URL == e.To && n.ParentURL == e.From) && !(n.URL == e.From && n.ParentURL == e.To) {
+			continue
+		}
+		for _, d := range n.Distortions {
+			if d.Type != "" && !seen[d.Type] {
+				seen[d.Type] = true
+				types = append(types, d.Type)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func chainPublished(n ChainNode) string {
+	if n.PublishedAt != nil {
+		return n.PublishedAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return ""
+}
+
+// ── Graphviz DOT ─────────────────────────────────────────────────────────────
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// ChainDOT — цепочка в формате Graphviz DOT.
+func ChainDOT(res *ChainResult) string {
+	ids := chainNodeIDs(res)
+	var b strings.Builder
+	b.WriteString("digraph chain {\n")
+	fmt.Fprintf(&b, "  label=%s;\n  rankdir=TB;\n  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n", dotQuote(res.Topic))
+	for i, n := range res.Nodes {
+		label := fmt.Sprintf("%s\nдост. %d/10 · иск. %d/10", n.Domain, n.CredibilityScore, n.DistortionScore)
+		if p := chainPublished(n); p != "" {
+			label += "\n" + p[:10]
+		}
+		fmt.Fprintf(&b, "  n%d [label=%s, URL=%s, fillcolor=%s, domain=%s, credibility=%d, distortion_score=%d, published=%s, original=%t];\n",
+			i, dotQuote(label), dotQuote(n.URL), dotQuote(chainNodeColor(n)), dotQuote(n.Domain),
+			n.CredibilityScore, n.DistortionScore, dotQuote(chainPublished(n)), n.IsOriginal)
+	}
+	for _, e := range res.Edges {
+		types := strings.Join(chainEdgeDistortions(res, e), ",")
+		label := fmt.Sprintf("%.0f%%", e.Overlap)
+		if types != "" {
+			label += "\n" + types
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, overlap=%.1f, basis=%s, distortions=%s];\n",
+			ids[e.From], ids[e.To], dotQuote(label), e.Overlap, dotQuote(e.Basis), dotQuote(types))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ── GraphML ──────────────────────────────────────────────────────────────────
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ChainGraphML — цепочка в формате GraphML.
+func ChainGraphML(res *ChainResult) ([]byte, error) {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{"label", "node", "label", "string"},
+			{"url", "node", "url", "string"},
+			{"domain", "node", "domain", "string"},
+			{"credibility", "node", "credibility", "int"},
+			{"distortion_score", "node", "distortion_score", "int"},
+			{"published", "node", "published", "string"},
+			{"original", "node", "original", "boolean"},
+			{"input", "node", "input", "boolean"},
+			{"overlap", "edge", "overlap", "double"},
+			{"basis", "edge", "basis", "string"},
+			{"distortions", "edge", "distortions", "string"},
+		},
+		Graph: graphMLGraph{ID: "chain", EdgeDefault: "directed"},
+	}
+	ids := chainNodeIDs(res)
+	for i, n := range res.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: fmt.Sprintf("n%d", i),
+			Data: []graphMLData{
+				{"label", n.Title},
+				{"url", n.URL},
+				{"domain", n.Domain},
+				{"credibility", fmt.Sprint(n.CredibilityScore)},
+				{"distortion_score", fmt.Sprint(n.DistortionScore)},
+				{"published", chainPublished(n)},
+				{"original", fmt.Sprint(n.IsOriginal)},
+				{"input", fmt.Sprint(n.IsInput)},
+			},
+		})
+	}
+	for _, e := range res.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: ids[e.From],
+			Target: ids[e.To],
+			Data: []graphMLData{
+				{"overlap", fmt.Sprintf("%.1f", e.Overlap)},
+				{"basis", e.Basis},
+				{"distortions", strings.Join(chainEdgeDistortions(res, e), ",")},
+			},
+		})
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// ── JSON Graph Format (v2) ───────────────────────────────────────────────────
+
+type JGFDocument struct {
+	Graph JGFGraph `json:"graph"`
+}
+
+type JGFGraph struct {
+	ID       string                 `json:"id,omitempty"`
+	Label    string                 `json:"label"`
+	Directed bool                   `json:"directed"`
+	Type     string                 `json:"type"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Nodes    map[string]JGFNode     `json:"nodes"`
+	Edges    []JGFEdge              `json:"edges"`
+}
+
+type JGFNode struct {
+	Label    string                 `json:"label"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type JGFEdge struct {
+	Source   string                 `json:"source"`
+	Target   string                 `json:"target"`
+	Relation string                 `json:"relation"`
+	Directed bool                   `json:"directed"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// ChainJGF — цепочка в JSON Graph Format.
+func ChainJGF(res *ChainResult) JGFDocument {
+	ids := chainNodeIDs(res)
+	g := JGFGraph{
+		ID:       res.ID,
+		Label:    res.Topic,
+		Directed: true,
+		Type:     "source-chain",
+		Metadata: map[string]interface{}{
+			"input_url":     res.InputURL,
+			"original_url":  res.OriginalURL,
+			"origin_reason": res.OriginReason,
+		},
+		Nodes: make(map[string]JGFNode, len(res.Nodes)),
+		Edges: []JGFEdge{},
+	}
+	for i, n := range res.Nodes {
+		g.Nodes[fmt.Sprintf("n%d", i)] = JGFNode{
+			Label: n.Title,
+			Metadata: map[string]interface{}{
+				"url":              n.URL,
+				"domain":           n.Domain,
+				"credibility":      n.CredibilityScore,
+				"distortion_score": n.DistortionScore,
+				"published":        chainPublished(n),
+				"original":         n.IsOriginal,
+				"input":            n.IsInput,
+			},
+		}
+	}
+	for _, e := range res.Edges {
+		g.Edges = append(g.Edges, JGFEdge{
+			Source:   ids[e.From],
+			Target:   ids[e.To],
+			Relation: "copied_to",
+			Directed: true,
+			Metadata: map[string]interface{}{
+				"overlap":     e.Overlap,
+				"basis":       e.Basis,
+				"distortions": chainEdgeDistortions(res, e),
+			},
+		})
+	}
+	return JGFDocument{Graph: g}
+}
+
+// ── SVG ──────────────────────────────────────────────────────────────────────
+
+const (
+	svgNodeW  = 220
+	svgNodeH  = 58
+	svgGapX   = 30
+	svgGapY   = 70
+	svgMargin = 20
+)
+
+func chainNodeColor(n ChainNode) string {
+	switch {
+	case n.IsOriginal:
+		return "#dbeafe"
+	case n.DistortionScore <= 2:
+		return "#dcfce7"
+	case n.DistortionScore <= 5:
+		return "#fef9c3"
+	default:
+		return "#fee2e2"
+	}
+}
+
+// chainLayers раскладывает узлы по уровням: расстояние от первоисточника
+// по рёбрам, недостижимые узлы — на последний уровень.
+func chainLayers(res *ChainResult) [][]int {
+	index := map[string]int{}
+	origin := 0
+	for i, n := range res.Nodes {
+		index[n.URL] = i
+		if n.IsOriginal {
+			origin = i
+		}
+	}
+	adj := map[int][]int{}
+	for _, e := range res.Edges {
+		from, okF := index[e.From]
+		to, okT := index[e.To]
+		if okF && okT {
+			adj[from] = append(adj[from], to)
+		}
+	}
+	level := map[int]int{origin: 0}
+	queue := []int{origin}
+	maxLevel := 0
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, v := range adj[u] {
+			if _, ok := level[v]; !ok {
+				level[v] = level[u] + 1
+				maxLevel = max(maxLevel, level[v])
+				queue = append(queue, v)
+			}
+		}
+	}
+	var layers [][]int
+	for i := range res.Nodes {
+		l, ok := level[i]
+		if !ok {
+			l = maxLevel + 1
+		}
+		for len(layers) <= l {
+			layers = append(layers, nil)
+		}
+		layers[l] = append(layers[l], i)
+	}
+	return layers
+}
+
+// ChainSVG рисует граф цепочки: уровни сверху вниз от первоисточника,
+// на рёбрах — процент совпадения текста и типы искажений.
+func ChainSVG(res *ChainResult) string {
+	layers := chainLayers(res)
+	widest := 1
+	for _, l := range layers {
+		widest = max(widest, len(l))
+	}
+	width := svgMargin*2 + widest*svgNodeW + (widest-1)*svgGapX
+	height := svgMargin*2 + 24 + len(layers)*svgNodeH + max(len(layers)-1, 0)*svgGapY
+
+	type point struct{ x, y int }
+	pos := map[string]point{}
+	for li, l := range layers {
+		rowW := len(l)*svgNodeW + (len(l)-1)*svgGapX
+		x0 := (width - rowW) / 2
+		for k, i := range l {
+			pos[res.Nodes[i].URL] = point{x0 + k*(svgNodeW+svgGapX), svgMargin + 24 + li*(svgNodeH+svgGapY)}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n", width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#64748b"/></marker></defs>` + "\n")
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" font-weight="bold" fill="#0f172a">%s</text>`+"\n", svgMargin, svgMargin+4, html.EscapeString(chainTruncate(res.Topic, 90)))
+
+	for _, e := range res.Edges {
+		from, okF := pos[e.From]
+		to, okT := pos[e.To]
+		if !okF || !okT {
+			continue
+		}
+		x1, y1 := from.x+svgNodeW/2, from.y+svgNodeH
+		x2, y2 := to.x+svgNodeW/2, to.y
+		if to.y <= from.y {
+			// Ребро вверх или вбок — от верхней кромки к нижней
+			y1, y2 = from.y, to.y+svgNodeH
+		}
+		dash := ""
+		if e.Basis == "search" {
+			dash = ` stroke-dasharray="4 3"`
+		}
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#64748b" stroke-width="1.5"%s marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2, dash)
+		label := fmt.Sprintf("%.0f%%", e.Overlap)
+		if types := chainEdgeDistortions(res, e); len(types) > 0 {
+			label += " · " + strings.Join(types, ", ")
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="#334155" text-anchor="middle">%s</text>`+"\n", (x1+x2)/2, (y1+y2)/2, html.EscapeString(label))
+	}
+
+	for _, n := range res.Nodes {
+		p := pos[n.URL]
+		stroke := "#94a3b8"
+		if n.IsOriginal {
+			stroke = "#2563eb"
+		}
+		fmt.Fprintf(&b, `<a href="%s"><g>`, html.EscapeString(n.URL))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="8" fill="%s" stroke="%s" stroke-width="1.5"/>`, p.x, p.y, svgNodeW, svgNodeH, chainNodeColor(n), stroke)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" font-weight="bold" fill="#0f172a">%s</text>`, p.x+10, p.y+18, html.EscapeString(chainTruncate(n.Domain, 30)))
+		sub := fmt.Sprintf("дост. %d/10 · иск. %d/10", n.CredibilityScore, n.DistortionScore)
+		if n.IsOriginal {
+			sub = "ОРИГИНАЛ · " + sub
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="#334155">%s</text>`, p.x+10, p.y+34, html.EscapeString(sub))
+		if pub := chainPublished(n); pub != "" {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="#64748b">%s</text>`, p.x+10, p.y+49, pub[:10])
+		}
+		b.WriteString("</g></a>\n")
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainEdgeDistortions(t *testing.T) {
+	res := sampleChain()
+	tests := []struct {
+		edge ChainEdge
+		want []string
+	}{
+		{res.Edges[0], []string{"exaggeration", "omission"}},
+		// Обратное направление — то же ребро поиска
+		{ChainEdge{From: res.Edges[0].To, To: res.Edges[0].From}, []string{"exaggeration", "omission"}},
+		{res.Edges[1], nil},
+	}
+	for _, tt := range tests {
+		if got := chainEdgeDistortions(res, tt.edge); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s -> %s: got %v, want %v", tt.edge.From, tt.edge.To, got, tt.want)
+		}
+	}
+}
+
+func TestChainDOT(t *testing.T) {
+	dot := ChainDOT(sampleChain())
+	for _, want := range []string{
+		"digraph chain {\n",
+		`label="Тарифы на газ \"выросли\" <вдвое>";`,
+		`n0 [label="origin.md\nдост. 8/10 · иск. 0/10\n2024-03-01", URL="https://origin.md/news/1", fillcolor="#dbeafe"`,
+		`published="2024-03-01T08:30:00Z", original=true];`,
+		`n1 [label="copy.md\nдост. 4/10 · иск. 6/10"`,
+		`n0 -> n1 [label="72%\nexaggeration,omission", overlap=72.5, basis="timestamps", distortions="exaggeration,omission"];`,
+		`n1 -> n2 [label="0%", overlap=0.0, basis="search", distortions=""];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT lacks %s\n%s", want, dot)
+		}
+	}
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Error("DOT graph not closed")
+	}
+}
+
+func TestChainGraphML(t *testing.T) {
+	out, err := ChainGraphML(sampleChain())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), xml.Header) {
+		t.Error("missing XML header")
+	}
+	var doc graphML
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("invalid GraphML: %v", err)
+	}
+	if doc.XMLNS != "http://graphml.graphdrawing.org/xmlns" || doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("graph header = %q %q", doc.XMLNS, doc.Graph.EdgeDefault)
+	}
+	keys := map[string]bool{}
+	for _, k := range doc.Keys {
+		keys[k.ID] = true
+	}
+	data := func(d []graphMLData) map[string]string {
+		m := map[string]string{}
+		for _, v := range d {
+			if !keys[v.Key] {
+				t.Errorf("data key %q is not declared", v.Key)
+			}
+			m[v.Key] = v.Value
+		}
+		return m
+	}
+
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("nodes = %d, edges = %d; want 3 and 2", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	origin := data(doc.Graph.Nodes[0].Data)
+	if doc.Graph.Nodes[0].ID != "n0" || origin["url"] != "https://origin.md/news/1" || origin["original"] != "true" ||
+		origin["published"] != "2024-03-01T08:30:00Z" || origin["credibility"] != "8" {
+		t.Errorf("origin node = %s %v", doc.Graph.Nodes[0].ID, origin)
+	}
+	if input := data(doc.Graph.Nodes[1].Data); input["input"] != "true" || input["label"] != "Газ подорожал вдвое" {
+		t.Errorf("input node = %v", input)
+	}
+	e := doc.Graph.Edges[0]
+	if d := data(e.Data); e.Source != "n0" || e.Target != "n1" || d["overlap"] != "72.5" ||
+		d["basis"] != "timestamps" || d["distortions"] != "exaggeration,omission" {
+		t.Errorf("edge = %s -> %s %v", e.Source, e.Target, d)
+	}
+}
+
+func TestChainJGF(t *testing.T) {
+	data, err := json.Marshal(ChainJGF(sampleChain()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Проверяем то, что увидит клиент, — разобранный JSON
+	var doc struct {
+		Graph struct {
+			ID       string `json:"id"`
+			Label    string `json:"label"`
+			Directed bool   `json:"directed"`
+			Metadata map[string]string
+			Nodes    map[string]struct {
+				Label    string                 `json:"label"`
+				Metadata map[string]interface{} `json:"metadata"`
+			} `json:"nodes"`
+			Edges []struct {
+				Source, Target, Relation string
+				Directed                 bool
+				Metadata                 map[string]interface{}
+			} `json:"edges"`
+		} `json:"graph"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	g := doc.Graph
+	if g.ID != "a1b2c3d4e5f6" || !g.Directed || g.Metadata["original_url"] != "https://origin.md/news/1" {
+		t.Errorf("graph = %+v", g)
+	}
+	if len(g.Nodes) != 3 || g.Nodes["n1"].Metadata["input"] != true || g.Nodes["n0"].Metadata["credibility"] != 8.0 {
+		t.Errorf("nodes = %+v", g.Nodes)
+	}
+	if len(g.Edges) != 2 {
+		t.Fatalf("edges = %+v", g.Edges)
+	}
+	e := g.Edges[0]
+	if e.Source != "n0" || e.Target != "n1" || e.Relation != "copied_to" || !e.Directed || e.Metadata["overlap"] != 72.5 ||
+		!reflect.DeepEqual(e.Metadata["distortions"], []interface{}{"exaggeration", "omission"}) {
+		t.Errorf("edge = %+v", e)
+	}
+
+	// Цепочка без рёбер — пустой массив, а не null
+	empty := sampleChain()
+	empty.Edges = nil
+	if data, _ := json.Marshal(ChainJGF(empty)); !strings.Contains(string(data), `"edges":[]`) {
+		t.Errorf("empty edges = %s", data)
+	}
+}
+
+func TestChainLayers(t *testing.T) {
+	res := sampleChain()
+	res.Nodes = append(res.Nodes, ChainNode{URL: "https://lonely.md/4"})
+	if got, want := chainLayers(res), [][]int{{0}, {1}, {2}, {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("layers = %v, want %v", got, want)
+	}
+	// Ребро в узел вне цепочки не ломает раскладку
+	res.Edges = append(res.Edges, ChainEdge{From: "https://origin.md/news/1", To: "https://missing.md"})
+	res.Edges = append(res.Edges, ChainEdge{From: "https://origin.md/news/1", To: "https://lonely.md/4"})
+	if got, want := chainLayers(res), [][]int{{0}, {1, 3}, {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("layers = %v, want %v", got, want)
+	}
+}
+
+func TestChainSVG(t *testing.T) {
+	svg := ChainSVG(sampleChain())
+
+	// Документ должен разбираться как XML: заголовок и подписи экранированы
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	rects, links := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, svg)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch el.Name.Local {
+			case "rect":
+				rects++
+			case "a":
+				links++
+			}
+		}
+	}
+	if rects != 4 || links != 3 {
+		t.Errorf("rects = %d, links = %d; want background + 3 nodes", rects, links)
+	}
+	for _, want := range []string{
+		"Тарифы на газ &#34;выросли&#34; &lt;вдвое&gt;",
+		"ОРИГИНАЛ · дост. 8/10 · иск. 0/10",
+		"72% · exaggeration, omission",
+		`stroke-dasharray="4 3"`,
+		`<a href="https://origin.md/news/1">`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG lacks %s", want)
+		}
+	}
+	if strings.Count(svg, "stroke-dasharray") != 1 {
+		t.Error("only search edges are dashed")
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Граф цепочки: кто у кого заимствовал текст. Направление ребра берётся из
+// дат публикации, а если их нет или они слишком близки — из асимметрии
+// заимствований (текст, почти целиком входящий в другой, скопирован из него).
+// Оригиналом считается узел без входящих рёбер, от которого достижимо больше
+// всего публикаций — это не обязательно ссылка, которую прислал пользователь.
+
+const (
+	chainMinOverlap = 0.15      // доля общих шинглов, с которой тексты считаются связанными
+	chainDateSlack  = time.Hour // меньшая разница в датах порядок не определяет
+	chainContainGap = 0.15      // минимальная асимметрия вхождения для направления по тексту
+)
+
+// ChainEdge — направленное ребро «откуда → куда» перешёл текст.
+type ChainEdge struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Overlap float64 `json:"overlap"` // % общих фрагментов от меньшего из двух текстов
+	Basis   string  `json:"basis"`   // timestamps | text | search — чем определено направление
+}
+
+// buildChainGraph строит рёбра между узлами и выбирает вероятный оригинал.
+// shingles — шинглы текста каждого узла по URL.
+func buildChainGraph(nodes []ChainNode, shingles map[string]map[uint64]bool) (edges []ChainEdge, origin int, reason string) {
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			a, b := &nodes[i], &nodes[j]
+			aInB, bInA := shingleContainment(shingles[a.URL], shingles[b.URL])
+			overlap := max(aInB, bInA)
+			related := a.ParentURL == b.URL || b.ParentURL == a.URL
+			if overlap < chainMinOverlap && !related {
+				continue
+			}
+			from, to, basis := chainDirection(a, b, aInB, bInA)
+			edges = append(edges, ChainEdge{
+				From:    from.URL,
+				To:      to.URL,
+				Overlap: math.Round(overlap*1000) / 10,
+				Basis:   basis,
+			})
+		}
+	}
+
+	origin, reach := chainOrigin(nodes, edges)
+	return edges, origin, chainOriginReason(nodes, edges, origin, reach)
+}
+
+// chainDirection решает, кто из двух узлов источник.
+func chainDirection(a, b *ChainNode, aInB, bInA float64) (from, to *ChainNode, basis string) {
+	switch {
+	case a.PublishedAt != nil && b.PublishedAt != nil && chainAbs(a.PublishedAt.Sub(*b.PublishedAt)) >= chainDateSlack:
+		if a.PublishedAt.Before(*b.PublishedAt) {
+			return a, b, "timestamps"
+		}
+		return b, a, "timestamps"
+	case max(aInB, bInA) >= chainMinOverlap && math.Abs(aInB-bInA) >= chainContainGap:
+		// b почти целиком состоит из кусков a — значит, b переписан с a
+		if bInA > aInB {
+			return a, b, "text"
+		}
+		return b, a, "text"
+	case b.ParentURL == a.URL:
+		return a, b, "search"
+	case a.ParentURL == b.URL:
+		return b, a, "search"
+	case a.Depth <= b.Depth:
+		return a, b, "search"
+	default:
+		return b, a, "search"
+	}
+}
+
+// chainOrigin — узел без входящих рёбер с наибольшим числом достижимых узлов.
+// При равенстве — более ранняя дата публикации, затем исходная ссылка.
+func chainOrigin(nodes []ChainNode, edges []ChainEdge) (origin, reach int) {
+	indeg := map[string]int{}
+	adj := map[string][]string{}
+	for _, e := range edges {
+		indeg[e.To]++
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+
+	origin, reach = -1, -1
+	for i, n := range nodes {
+		if indeg[n.URL] > 0 {
+			continue
+		}
+		r := chainReach(n.URL, adj)
+		if origin < 0 || r > reach || (r == reach && chainBefore(&n, &nodes[origin])) {
+			origin, reach = i, r
+		}
+	}
+	if origin < 0 || reach == 0 {
+		// Цикл или связей нет — точкой отсчёта остаётся исходная ссылка
+		for i, n := range nodes {
+			if n.IsInput {
+				return i, max(reach, 0)
+			}
+		}
+		return 0, 0
+	}
+	return origin, reach
+}
+
+func chainReach(from string, adj map[string][]string) int {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, v := range adj[u] {
+			if !seen[v] {
+				seen[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	return len(seen) - 1
+}
+
+// chainBefore — a предпочтительнее b как оригинал при равном охвате.
+func chainBefore(a, b *ChainNode) bool {
+	switch {
+	case a.PublishedAt != nil && b.PublishedAt != nil:
+		return a.PublishedAt.Before(*b.PublishedAt)
+	case a.PublishedAt != nil:
+		return true
+	case b.PublishedAt != nil:
+		return false
+	}
+	return a.IsInput && !b.IsInput
+}
+
+func chainOriginReason(nodes []ChainNode, edges []ChainEdge, origin, reach int) string {
+	if reach == 0 {
+		return "заимствований текста не найдено — точкой отсчёта считается исходная ссылка"
+	}
+	n := nodes[origin]
+	var parts []string
+	if n.PublishedAt != nil {
+		earliest := true
+		for _, other := range nodes {
+			if other.PublishedAt != nil && other.PublishedAt.Before(*n.PublishedAt) {
+				earliest = false
+				break
+			}
+		}
+		if earliest {
+			parts = append(parts, "самая ранняя дата публикации ("+n.PublishedAt.Format("2006-01-02 15:04")+")")
+		}
+	}
+	var maxOverlap float64
+	for _, e := range edges {
+		if e.From == n.URL && e.Basis != "search" {
+			maxOverlap = max(maxOverlap, e.Overlap)
+		}
+	}
+	if maxOverlap > 0 {
+		parts = append(parts, fmt.Sprintf("его текст повторяют другие публикации (до %.0f%% совпадения)", maxOverlap))
+	}
+	parts = append(parts, fmt.Sprintf("производных публикаций: %d", reach))
+	if !n.IsInput {
+		parts = append(parts, "присланная ссылка — не первоисточник")
+	}
+	return strings.Join(parts, "; ")
+}
+
+func chainAbs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shingleRange — набор шинглов lo..hi-1: пересечения задаются диапазонами.
+func shingleRange(lo, hi uint64) map[uint64]bool {
+	out := make(map[uint64]bool, hi-lo)
+	for h := lo; h < hi; h++ {
+		out[h] = true
+	}
+	return out
+}
+
+func chainTime(hour, minute int) *time.Time {
+	t := time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func TestBuildChainGraphDirection(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      ChainNode
+		sa, sb    map[uint64]bool
+		want      []ChainEdge
+		origin    string
+		reasonHas []string
+	}{
+		{
+			// a целиком входит в b, но опубликован на сутки раньше
+			name:      "timestamps beat text",
+			a:         ChainNode{URL: "a", IsInput: true, PublishedAt: chainTime(0, 0)},
+			b:         ChainNode{URL: "b", Depth: 1, ParentURL: "a", PublishedAt: chainTime(23, 0)},
+			sa:        shingleRange(0, 50),
+			sb:        shingleRange(0, 100),
+			want:      []ChainEdge{{From: "a", To: "b", Overlap: 100, Basis: "timestamps"}},
+			origin:    "a",
+			reasonHas: []string{"самая ранняя дата публикации (2024-03-01 00:00)", "производных публикаций: 1"},
+		},
+		{
+			name:      "close dates fall back to text containment",
+			a:         ChainNode{URL: "a", IsInput: true, PublishedAt: chainTime(10, 0)},
+			b:         ChainNode{URL: "b", Depth: 1, ParentURL: "a", PublishedAt: chainTime(10, 10)},
+			sa:        shingleRange(0, 50),
+			sb:        shingleRange(0, 100),
+			want:      []ChainEdge{{From: "b", To: "a", Overlap: 100, Basis: "text"}},
+			origin:    "b",
+			reasonHas: []string{"до 100% совпадения", "присланная ссылка — не первоисточник"},
+		},
+		{
+			name:   "symmetric text falls back to search parent",
+			a:      ChainNode{URL: "a", Depth: 1, ParentURL: "b"},
+			b:      ChainNode{URL: "b", IsInput: true},
+			sa:     shingleRange(0, 100),
+			sb:     shingleRange(20, 120),
+			want:   []ChainEdge{{From: "b", To: "a", Overlap: 80, Basis: "search"}},
+			origin: "b",
+			// По поиску направление найдено, но совпадение текста не заявляется
+			reasonHas: []string{"производных публикаций: 1"},
+		},
+		{
+			name:      "unrelated pages",
+			a:         ChainNode{URL: "a", IsInput: true},
+			b:         ChainNode{URL: "b", Depth: 1},
+			sa:        shingleRange(0, 100),
+			sb:        shingleRange(95, 195),
+			want:      nil,
+			origin:    "a",
+			reasonHas: []string{"заимствований текста не найдено"},
+		},
+	}
+	for _, tt := range tests {
+		nodes := []ChainNode{tt.a, tt.b}
+		edges, origin, reason := buildChainGraph(nodes, map[string]map[uint64]bool{"a": tt.sa, "b": tt.sb})
+		if !reflect.DeepEqual(edges, tt.want) {
+			t.Errorf("%s: edges = %+v, want %+v", tt.name, edges, tt.want)
+		}
+		if nodes[origin].URL != tt.origin {
+			t.Errorf("%s: origin = %s, want %s", tt.name, nodes[origin].URL, tt.origin)
+		}
+		for _, s := range tt.reasonHas {
+			if !strings.Contains(reason, s) {
+				t.Errorf("%s: reason %q lacks %q", tt.name, reason, s)
+			}
+		}
+		if strings.Contains(tt.name, "search") && strings.Contains(reason, "совпадения") {
+			t.Errorf("%s: reason %q claims text overlap for a search edge", tt.name, reason)
+		}
+	}
+}
+
+func TestChainOrigin(t *testing.T) {
+	edge := func(from, to string) ChainEdge { return ChainEdge{From: from, To: to, Basis: "text"} }
+	tests := []struct {
+		name      string
+		nodes     []ChainNode
+		edges     []ChainEdge
+		origin    string
+		reach     int
+		reasonHas string
+	}{
+		{
+			name:   "largest reach wins over earlier date",
+			nodes:  []ChainNode{{URL: "a", IsInput: true, PublishedAt: chainTime(1, 0)}, {URL: "b", PublishedAt: chainTime(5, 0)}, {URL: "c"}, {URL: "d"}},
+			edges:  []ChainEdge{edge("a", "c"), edge("b", "c"), edge("b", "d")},
+			origin: "b",
+			reach:  2,
+		},
+		{
+			name:   "transitive reach",
+			nodes:  []ChainNode{{URL: "a", IsInput: true}, {URL: "b"}, {URL: "c"}},
+			edges:  []ChainEdge{edge("c", "b"), edge("b", "a")},
+			origin: "c",
+			reach:  2,
+		},
+		{
+			name:      "equal reach: earlier date",
+			nodes:     []ChainNode{{URL: "a", IsInput: true, PublishedAt: chainTime(9, 0)}, {URL: "b", PublishedAt: chainTime(3, 0)}, {URL: "c"}, {URL: "d"}},
+			edges:     []ChainEdge{edge("a", "c"), edge("b", "d")},
+			origin:    "b",
+			reach:     1,
+			reasonHas: "самая ранняя дата публикации (2024-03-01 03:00)",
+		},
+		{
+			name:   "equal reach: dated node over undated",
+			nodes:  []ChainNode{{URL: "a", IsInput: true}, {URL: "b", PublishedAt: chainTime(9, 0)}, {URL: "c"}, {URL: "d"}},
+			edges:  []ChainEdge{edge("a", "c"), edge("b", "d")},
+			origin: "b",
+			reach:  1,
+		},
+		{
+			name:      "equal reach: input link without dates",
+			nodes:     []ChainNode{{URL: "a"}, {URL: "b", IsInput: true}, {URL: "c"}, {URL: "d"}},
+			edges:     []ChainEdge{edge("a", "c"), edge("b", "d")},
+			origin:    "b",
+			reach:     1,
+			reasonHas: "производных публикаций: 1",
+		},
+		{
+			name:   "equal reach: first node otherwise",
+			nodes:  []ChainNode{{URL: "a"}, {URL: "b"}, {URL: "c", IsInput: true}, {URL: "d", IsInput: true}},
+			edges:  []ChainEdge{edge("a", "c"), edge("b", "d")},
+			origin: "a",
+			reach:  1,
+		},
+		{
+			name:      "cycle falls back to input link",
+			nodes:     []ChainNode{{URL: "a"}, {URL: "b", IsInput: true}, {URL: "c"}},
+			edges:     []ChainEdge{edge("a", "b"), edge("b", "c"), edge("c", "a")},
+			origin:    "b",
+			reach:     0,
+			reasonHas: "заимствований текста не найдено",
+		},
+		{
+			name:   "cycle with a root outside it",
+			nodes:  []ChainNode{{URL: "a", IsInput: true}, {URL: "b"}, {URL: "c"}, {URL: "root"}},
+			edges:  []ChainEdge{edge("a", "b"), edge("b", "c"), edge("c", "a"), edge("root", "a")},
+			origin: "root",
+			reach:  3,
+		},
+	}
+	for _, tt := range tests {
+		origin, reach := chainOrigin(tt.nodes, tt.edges)
+		if tt.nodes[origin].URL != tt.origin || reach != tt.reach {
+			t.Errorf("%s: origin = %s (reach %d), want %s (reach %d)", tt.name, tt.nodes[origin].URL, reach, tt.origin, tt.reach)
+		}
+		reason := chainOriginReason(tt.nodes, tt.edges, origin, reach)
+		if tt.reasonHas != "" && !strings.Contains(reason, tt.reasonHas) {
+			t.Errorf("%s: reason %q lacks %q", tt.name, reason, tt.reasonHas)
+		}
+		if (tt.nodes[origin].IsInput || reach == 0) == strings.Contains(reason, "не первоисточник") {
+			t.Errorf("%s: reason %q misreports whether the input link is the origin", tt.name, reason)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"text-analyzer/cache"
+	"text-analyzer/database"
+	"time"
+)
+
+// Хранение цепочек: таблица chains (узлы, искажения и рёбра — в JSONB result)
+// и кэш в Redis по URL и параметрам, чтобы повторный запрос не строил
+// цепочку заново.
+
+// ErrChainNotFound — цепочки с таким id нет.
+var ErrChainNotFound = errors.New("цепочка не найдена")
+
+func newChainID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// chainCacheKey — ключ кэша для URL с учётом ширины и глубины.
+func chainCacheKey(inputURL string, opts ChainOptions) string {
+	h := sha256.Sum256([]byte(inputURL))
+	return fmt.Sprintf("chain:%s:%d:%d", hex.EncodeToString(h[:]), opts.Breadth, opts.Depth)
+}
+
+// cachedChain возвращает ранее построенную цепочку из Redis, если она есть.
+func cachedChain(inputURL string, opts ChainOptions) *ChainResult {
+	raw, err := cache.Get(chainCacheKey(inputURL, opts))
+	if err != nil {
+		return nil
+	}
+	var res ChainResult
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		return nil
+	}
+	return &res
+}
+
+// SaveChain присваивает цепочке id, сохраняет её в БД и кладёт в кэш.
+// Без БД цепочка всё равно кэшируется, но без id.
+func SaveChain(res *ChainResult, opts ChainOptions, ttl time.Duration) error {
+	res.ID = newChainID()
+	res.CreatedAt = time.Now().UTC()
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if database.DB == nil {
+		err = fmt.Errorf("БД недоступна")
+	} else {
+		_, err = database.DB.Exec(`
+			INSERT INTO chains (id, input_url, origin_url, topic, breadth, depth, node_count, result)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, res.ID, res.InputURL, res.OriginalURL, res.Topic, opts.Breadth, opts.Depth, len(res.Nodes), data)
+	}
+	if err != nil {
+		res.ID = ""
+		data, _ = json.Marshal(res)
+	}
+	if ttl > 0 {
+		cache.Set(chainCacheKey(res.InputURL, opts), string(data), ttl)
+	}
+	return err
+}
+
+// LoadChain читает сохранённую цепочку по id.
+func LoadChain(id string) (*ChainResult, error) {
+	if database.DB == nil {
+		return nil, fmt.Errorf("БД недоступна")
+	}
+	var raw []byte
+	err := database.DB.QueryRow(`SELECT result FROM chains WHERE id = $1`, id).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChainNotFound
+	}
+	if err != nil {
+		log.Printf("[CHAIN] ⚠ Ошибка чтения цепочки %s: %v", id, err)
+		return nil, err
+	}
+	var res ChainResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
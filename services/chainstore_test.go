@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleChain — цепочка из трёх узлов: оригинал, пересказ с искажением и
+// узел, найденный поиском без заимствований.
+func sampleChain() *ChainResult {
+	published := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	return &ChainResult{
+		ID:           "a1b2c3d4e5f6",
+		CreatedAt:    time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+		Topic:        `Тарифы на газ "выросли" <вдвое>`,
+		InputURL:     "https://copy.md/news/2",
+		OriginalURL:  "https://origin.md/news/1",
+		OriginReason: "самая ранняя дата публикации (2024-03-01 08:30)",
+		Nodes: []ChainNode{
+			{URL: "https://origin.md/news/1", Title: "ANRE a aprobat tarife noi", Domain: "origin.md", PublishedAt: &published,
+				IsOriginal: true, CredibilityScore: 8, KeyClaims: []string{"тариф вырос на 20%"}},
+			{URL: "https://copy.md/news/2", Title: "Газ подорожал вдвое", Domain: "copy.md", IsInput: true, CredibilityScore: 4,
+				DistortionScore: 6, Depth: 1, ParentURL: "https://origin.md/news/1",
+				Distortions: []Distortion{
+					{Type: "exaggeration", Original: "на 20%", Changed: "вдвое"},
+					{Type: "omission", Original: "для бытовых потребителей", Changed: ""},
+					{Type: "exaggeration", Original: "с 1 апреля", Changed: "уже сейчас"},
+				}},
+			{URL: "https://other.md/3", Title: "Другая тема", Domain: "other.md", CredibilityScore: 6, Depth: 1,
+				ParentURL: "https://copy.md/news/2"},
+		},
+		Edges: []ChainEdge{
+			{From: "https://origin.md/news/1", To: "https://copy.md/news/2", Overlap: 72.5, Basis: "timestamps"},
+			{From: "https://copy.md/news/2", To: "https://other.md/3", Overlap: 0, Basis: "search"},
+		},
+	}
+}
+
+func TestChainResultRoundTrip(t *testing.T) {
+	// В chains.result и в Redis цепочка хранится как JSON — узлы, искажения
+	// и рёбра должны читаться обратно без потерь
+	want := sampleChain()
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ChainResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("round trip changed the chain:\ngot  %+v\nwant %+v", got, *want)
+	}
+}
+
+func TestSaveChainWithoutDB(t *testing.T) {
+	res := sampleChain()
+	res.ID, res.CreatedAt = "", time.Time{}
+	if err := SaveChain(res, ChainOptions{Breadth: 3, Depth: 2}, time.Hour); err == nil {
+		t.Fatal("SaveChain without a database must report an error")
+	}
+	if res.ID != "" {
+		t.Errorf("id %q handed out for a chain that was not stored", res.ID)
+	}
+	if res.CreatedAt.IsZero() {
+		t.Error("created_at not set")
+	}
+	if _, err := LoadChain("a1b2c3d4e5f6"); err == nil {
+		t.Error("LoadChain without a database must report an error")
+	}
+}
+
+func TestChainCacheKey(t *testing.T) {
+	const page = "https://copy.md/news/2"
+	base := chainCacheKey(page, ChainOptions{Breadth: 3, Depth: 2})
+	if base != chainCacheKey(page, ChainOptions{Breadth: 3, Depth: 2}) {
+		t.Error("key must be stable")
+	}
+	for what, key := range map[string]string{
+		"url":     chainCacheKey(page+"?amp=1", ChainOptions{Breadth: 3, Depth: 2}),
+		"breadth": chainCacheKey(page, ChainOptions{Breadth: 5, Depth: 2}),
+		"depth":   chainCacheKey(page, ChainOptions{Breadth: 3, Depth: 1}),
+	} {
+		if key == base {
+			t.Errorf("key must depend on %s", what)
+		}
+	}
+}
+
+func TestNewChainID(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := newChainID()
+		if len(id) != 12 || seen[id] {
+			t.Fatalf("id %q: want 12 hex chars, unique", id)
+		}
+		seen[id] = true
+	}
+}
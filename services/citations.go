@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"text-analyzer/models"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxCitations       = 25 // больше ссылок не проверяем
+	citationWorkers    = 5
+	maxUnlinkedClaims  = 10
+	lowReputationScore = 4.0 // ниже — «ненадёжный» домен (как в /api/domain)
+)
+
+// Теги, внутри которых ссылка считается цитатой, а не навигацией.
+var citationContextTags = map[string]bool{
+	"p": true, "li": true, "blockquote": true, "figcaption": true, "td": true, "dd": true,
+}
+
+// pageLink — исходящая ссылка из основного текста статьи.
+type pageLink struct {
+	URL     string
+	Anchor  string
+	Context string // текст абзаца, в котором стоит ссылка
+}
+
+// extractLinks собирает ссылки из основного контента страницы.
+func (f *ContentFetcher) extractLinks(htmlStr, baseURL string) []pageLink {
+	doc, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		return nil
+	}
+	root := f.findMainContent(doc)
+	if root == nil {
+		root = doc
+	}
+
+	seen := map[string]bool{}
+	var links []pageLink
+	var walk func(n *html.Node, context *html.Node)
+	walk = func(n *html.Node, context *html.Node) {
+		if n.Type == html.ElementNode {
+			tag := strings.ToLower(n.Data)
+			if skipTags[tag] || isJunkNode(n) {
+				return
+			}
+			if citationContextTags[tag] {
+				context = n
+			}
+			if tag == "a" && context != nil {
+				if link, ok := citationLink(n, context, baseURL); ok && !seen[link.URL] {
+					seen[link.URL] = true
+					links = append(links, link)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, context)
+		}
+	}
+	walk(root, nil)
+	return links
+}
+
+func citationLink(a, context *html.Node, baseURL string) (pageLink, bool) {
+	var href string
+	for _, attr := range a.Attr {
+		if attr.Key == "href" {
+			href = strings.TrimSpace(attr.Val)
+		}
+	}
+	if href == "" || strings.HasPrefix(href, "#") {
+		return pageLink{}, false
+	}
+	resolved := resolveURL(baseURL, href)
+	if !strings.HasPrefix(resolved, "http://") && !strings.HasPrefix(resolved, "https://") {
+		return pageLink{}, false
+	}
+	if i := strings.IndexByte(resolved, '#'); i != -1 {
+		resolved = resolved[:i]
+	}
+	return pageLink{
+		URL:     resolved,
+		Anchor:  truncate(nodeText(a), 120),
+		Context: nodeText(context),
+	}, true
+}
+
+// nodeText — весь текст поддерева одной строкой.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(diffSpaceRe.ReplaceAllString(sb.String(), " "))
+}
+
+// ── Проверка ссылок ──────────────────────────────────────────────────────────
+
+// citationDeadline — сколько анализ ждёт проверки ссылок. Не успевшие
+// к этому сроку ссылки остаются «unchecked».
+var citationDeadline = 8 * time.Second
+
+// probeLink проверяет доступность ссылки: HEAD, а если сервер его не
+// поддерживает — GET без чтения тела. Редиректы проходят через политику загрузки.
+func (f *ContentFetcher) probeLink(ctx context.Context, rawURL string) (status int, finalURL string, err error) {
+	u, err := f.policy.ValidateURL(rawURL)
+	if err != nil {
+		return 0, "", err
+	}
+	if f.crawl.RespectRobots {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if !f.robotsFor(ctx, u).allowed(path) {
+			return 0, "", fmt.Errorf("запрещено robots.txt")
+		}
+	}
+
+	release, err := f.limiter.acquire(ctx, strings.ToLower(u.Host), 0)
+	if err != nil {
+		return 0, "", err
+	}
+	defer release()
+
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return 0, "", err
+		}
+		req.Header.Set("User-Agent", f.crawl.UserAgent)
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		status, finalURL = resp.StatusCode, resp.Request.URL.String()
+		// Многие сайты отвечают на HEAD 403/405 — повторяем GET
+		if method == http.MethodHead && (status == http.StatusMethodNotAllowed ||
+			status == http.StatusForbidden || status == http.StatusNotImplemented) {
+			continue
+		}
+		break
+	}
+	return status, finalURL, nil
+}
+
+func citationStatus(code int, err error) string {
+	switch {
+	case err != nil:
+		return "unreachable"
+	case code < 400:
+		return "ok"
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || code == http.StatusTooManyRequests:
+		return "blocked"
+	default:
+		return "dead"
+	}
+}
+
+// probeCitation проверяет одну ссылку и заполняет её статус.
+func (f *ContentFetcher) probeCitation(ctx context.Context, c models.Citation) models.Citation {
+	code, finalURL, err := f.probeLink(ctx, c.URL)
+	if err != nil && ctx.Err() != nil {
+		return c // не успели — остаётся «unchecked»
+	}
+	c.StatusCode, c.Status = code, citationStatus(code, err)
+	if err != nil {
+		c.Error = err.Error()
+	}
+	if finalURL != "" && finalURL != c.URL {
+		c.FinalURL = finalURL
+		c.Domain = NormalizeDomain(finalURL)
+	}
+	return c
+}
+
+// CheckCitations проверяет исходящие ссылки статьи и ищет ссылки на
+// исследования и источники, за которыми нет ссылки. Для текста, полученного
+// через фолбек (архив, AMP, метаданные), отчёт не строится. Ссылки на тот же
+// сайт не проверяются, а на остальные отводится не больше citationDeadline:
+// анализ не ждёт медленные сайты.
+func (f *ContentFetcher) CheckCitations(pageURL string, fetched *FetchResult) *models.CitationReport {
+	if fetched == nil || fetched.page == nil || fetched.Fallback != "" {
+		return nil
+	}
+	links := f.extractLinks(string(fetched.page.Body), fetched.URL)
+	if len(links) > maxCitations {
+		links = links[:maxCitations]
+	}
+
+	pageDomain := NormalizeDomain(fetched.URL)
+	report := &models.CitationReport{Citations: make([]models.Citation, len(links))}
+	var probe []int
+	for i, link := range links {
+		c := models.Citation{URL: link.URL, Anchor: link.Anchor, Domain: NormalizeDomain(link.URL), Status: "unchecked"}
+		c.SelfReferential = c.Domain == pageDomain
+		report.Citations[i] = c
+		if !c.SelfReferential {
+			probe = append(probe, i)
+		}
+	}
+	log.Printf("[CITATIONS] 🔗 Проверяю %d ссылок из %s (ещё %d — на тот же сайт)", len(probe), pageURL, len(links)-len(probe))
+
+	// Воркеры пишут в буферизованный канал, поэтому после срока их не ждём:
+	// оставшиеся запросы отменяются контекстом и завершаются сами.
+	ctx, cancel := context.WithTimeout(context.Background(), citationDeadline)
+	defer cancel()
+	type probed struct {
+		i int
+		c models.Citation
+	}
+	jobs := make(chan int, len(probe))
+	for _, i := range probe {
+		jobs <- i
+	}
+	close(jobs)
+	done := make(chan probed, len(probe))
+	initial := report.Citations // воркеры только читают
+	for w := 0; w < min(citationWorkers, len(probe)); w++ {
+		go func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				done <- probed{i, f.probeCitation(ctx, initial[i])}
+			}
+		}()
+	}
+	received := make([]models.Citation, len(links))
+	copy(received, initial)
+wait:
+	for range probe {
+		select {
+		case p := <-done:
+			received[p.i] = p.c
+		case <-ctx.Done():
+			log.Printf("[CITATIONS] ⏱ Не все ссылки проверены за %v", citationDeadline)
+			break wait
+		}
+	}
+	report.Citations = received
+
+	// Списки и ручные решения важнее средней оценки домена
+	domains := make([]string, len(report.Citations))
+	for i, c := range report.Citations {
+		domains[i] = c.Domain
+	}
+	scores := LookupDomainScores(domains)
+	curations := LookupCurations(domains)
+	for i, c := range report.Citations {
+		c.SelfReferential = c.SelfReferential || c.Domain == pageDomain
+		if ds, ok := scores[c.Domain]; ok {
+			avg := ds.Score
+			c.DomainScore = &avg
+			c.DomainAnalyses = ds.Total
+			c.LowReputation = avg < lowReputationScore
+		}
+		if cur := curations[c.Domain]; cur != nil && cur.Verdict != "" {
+			c.LowReputation = cur.Verdict == TierUnreliable
+		}
+		report.Citations[i] = c
+	}
+
+	for _, c := range report.Citations {
+		report.Total++
+		switch c.Status {
+		case "ok":
+			report.Working++
+		case "dead", "unreachable":
+			report.Dead++
+		}
+		if c.SelfReferential {
+			report.SelfReferential++
+		}
+		if c.LowReputation {
+			report.LowReputation++
+		}
+	}
+	report.UnlinkedClaims = findUnlinkedClaims(fetched.Text, links)
+	report.Summary = citationSummary(report)
+
+	log.Printf("[CITATIONS] ✓ Ссылок: %d, рабочих: %d, битых: %d, на себя: %d, ненадёжных: %d, утверждений без ссылки: %d",
+		report.Total, report.Working, report.Dead, report.SelfReferential, report.LowReputation, len(report.UnlinkedClaims))
+	return report
+}
+
+// ── Утверждения без ссылки ───────────────────────────────────────────────────
+
+// Обороты, которые обычно требуют ссылки на источник.
+var attributionRe = regexp.MustCompile(`(?i)(по данным|согласно|как показало|как показали|по словам|по информации|по мнению|как сообщают|как сообщает|сообщают источники|источники сообщают|учёные|ученые|исследовани[еяию]|опрос|эксперты|статистик[аи]|according to|a (?:new |recent )?study|studies show|research(?:ers)? (?:shows?|found|suggests?)|scientists|experts say|sources say|a survey|statistics show)`)
+
+var sentenceEndRe = regexp.MustCompile(`[.!?…]+\s+`)
+
+// findUnlinkedClaims ищет предложения с отсылкой к источнику в абзацах без ссылок.
+func findUnlinkedClaims(text string, links []pageLink) []string {
+	var claims []string
+	for _, para := range strings.Split(text, "\n") {
+		para = strings.TrimSpace(para)
+		if para == "" || !attributionRe.MatchString(para) || paragraphHasLink(para, links) {
+			continue
+		}
+		for _, sentence := range sentenceEndRe.Split(para, -1) {
+			if !attributionRe.MatchString(sentence) {
+				continue
+			}
+			if utf8.RuneCountInString(sentence) > 200 {
+				sentence = string([]rune(sentence)[:200]) + "…"
+			}
+			claims = append(claims, strings.TrimSpace(sentence))
+			if len(claims) >= maxUnlinkedClaims {
+				return claims
+			}
+		}
+	}
+	return claims
+}
+
+func paragraphHasLink(para string, links []pageLink) bool {
+	para = diffSpaceRe.ReplaceAllString(para, " ")
+	for _, l := range links {
+		if l.Context == "" {
+			continue
+		}
+		if strings.Contains(l.Context, para) || strings.Contains(para, l.Context) {
+			return true
+		}
+	}
+	return false
+}
+
+// citationSummary — краткая сводка для контекста модели.
+func citationSummary(r *models.CitationReport) string {
+	var sb strings.Builder
+	if r.Total == 0 {
+		sb.WriteString("В основном тексте статьи нет ни одной ссылки на источники.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Ссылок в тексте: %d (рабочих %d, битых %d, на этот же сайт %d, на домены с низкой репутацией %d)\n",
+			r.Total, r.Working, r.Dead, r.SelfReferential, r.LowReputation))
+		for i, c := range r.Citations {
+			if i >= 10 {
+				sb.WriteString(fmt.Sprintf("... и ещё %d\n", r.Total-i))
+				break
+			}
+			var notes []string
+			switch c.Status {
+			case "dead", "unreachable":
+				notes = append(notes, "битая")
+			case "blocked":
+				notes = append(notes, "доступ закрыт")
+			case "unchecked":
+				if !c.SelfReferential {
+					notes = append(notes, "не проверена")
+				}
+			}
+			if c.SelfReferential {
+				notes = append(notes, "тот же сайт")
+			}
+			if c.DomainScore != nil {
+				notes = append(notes, fmt.Sprintf("репутация домена %.1f/10", *c.DomainScore))
+			}
+			line := "- " + c.Domain
+			if c.Anchor != "" {
+				line += " («" + c.Anchor + "»)"
+			}
+			if len(notes) > 0 {
+				line += ": " + strings.Join(notes, ", ")
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	if len(r.UnlinkedClaims) > 0 {
+		sb.WriteString("Отсылки к исследованиям/источникам без ссылки:\n")
+		for _, c := range r.UnlinkedClaims {
+			sb.WriteString("- " + c + "\n")
+		}
+	}
+	return sb.String()
+}
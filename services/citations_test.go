@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckCitationsSkipsSameSiteAndHonoursDeadline(t *testing.T) {
+	defer func(d time.Duration) { citationDeadline = d }(citationDeadline)
+	citationDeadline = 300 * time.Millisecond
+
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	// Внешние сайты — через localhost, чтобы домен отличался от страницы (127.0.0.1)
+	ext := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	slowURL := strings.Replace(slow.URL, "127.0.0.1", "localhost", 1)
+	var internalHits atomic.Int32
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/article" {
+			internalHits.Add(1)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><body><article>
+<p>%s Vezi <a href="/about">despre noi</a> și <a href="/news/2">știrea anterioară</a>.</p>
+<p>Potrivit <a href="%s/report">raportului</a>, dar <a href="%s/gone">sursa</a> a dispărut, iar <a href="%s/study">studiul</a> se încarcă greu.</p>
+</article></body></html>`, strings.Repeat("Guvernul a anunțat plafonarea prețului la energia electrică. ", 5), ext, ext, slowURL)
+	}))
+	defer page.Close()
+
+	crawl := DefaultCrawlConfig()
+	crawl.HostInterval = 0
+	crawl.ArchiveAPIURL = ""
+	policy := DefaultFetchPolicy()
+	policy.AllowPrivate = true
+	f := NewContentFetcher(policy, crawl)
+
+	fetched, err := f.Fetch(page.URL + "/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	internalHits.Store(0)
+
+	start := time.Now()
+	report := f.CheckCitations(page.URL+"/article", fetched)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CheckCitations took %v, deadline is %v", elapsed, citationDeadline)
+	}
+	if report == nil {
+		t.Fatal("no report")
+	}
+	if internalHits.Load() != 0 {
+		t.Errorf("same-site links probed %d times", internalHits.Load())
+	}
+
+	want := map[string]struct {
+		status string
+		self   bool
+	}{
+		page.URL + "/about":  {"unchecked", true},
+		page.URL + "/news/2": {"unchecked", true},
+		ext + "/report":      {"ok", false},
+		ext + "/gone":        {"dead", false},
+		slowURL + "/study":   {"unchecked", false},
+	}
+	if len(report.Citations) != len(want) {
+		t.Fatalf("got %d citations, want %d: %+v", len(report.Citations), len(want), report.Citations)
+	}
+	for _, c := range report.Citations {
+		w, ok := want[c.URL]
+		if !ok {
+			t.Errorf("unexpected citation %s", c.URL)
+			continue
+		}
+		if c.Status != w.status || c.SelfReferential != w.self {
+			t.Errorf("%s: status=%s self=%v, want %s self=%v", c.URL, c.Status, c.SelfReferential, w.status, w.self)
+		}
+	}
+	if report.Total != 5 || report.Working != 1 || report.Dead != 1 || report.SelfReferential != 2 {
+		t.Errorf("totals: %d total, %d working, %d dead, %d self", report.Total, report.Working, report.Dead, report.SelfReferential)
+	}
+}
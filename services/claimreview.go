@@ -0,0 +1,485 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"text-analyzer/cache"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	claimMatchThreshold  = 0.3 // минимальная похожесть утверждения и предложения текста
+	claimCandidateLimit  = 100 // кандидатов из полнотекстового поиска
+	maxMatchSentences    = 200
+	claimReviewSeenTTL   = 30 * 24 * time.Hour
+	claimReviewSourceKey = "claimreview:page:"
+)
+
+// ── ClaimReview из JSON-LD ───────────────────────────────────────────────────
+
+var ldJSONRe = regexp.MustCompile(`(?i)<script[^>]+type=["']application/ld\+json["'][^>]*>([\s\S]*?)</script>`)
+
+// ExtractClaimReviews находит разметку schema.org/ClaimReview в JSON-LD страницы.
+func ExtractClaimReviews(htmlStr, pageURL string) []models.ClaimReview {
+	var reviews []models.ClaimReview
+	for _, m := range ldJSONRe.FindAllStringSubmatch(htmlStr, -1) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(m[1])), &data); err != nil {
+			continue
+		}
+		collectClaimReviews(data, pageURL, &reviews)
+	}
+	return reviews
+}
+
+func collectClaimReviews(v interface{}, pageURL string, out *[]models.ClaimReview) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			collectClaimReviews(item, pageURL, out)
+		}
+	case map[string]interface{}:
+		if ldHasType(t, "ClaimReview") {
+			if r, ok := parseClaimReview(t, pageURL); ok {
+				*out = append(*out, r)
+			}
+		}
+		if graph, ok := t["@graph"]; ok {
+			collectClaimReviews(graph, pageURL, out)
+		}
+	}
+}
+
+func parseClaimReview(m map[string]interface{}, pageURL string) (models.ClaimReview, bool) {
+	r := models.ClaimReview{
+		ClaimText: ldString(m["claimReviewed"]),
+		URL:       ldString(m["url"]),
+		Title:     ldString(m["headline"]),
+		Language:  ldString(m["inLanguage"]),
+		Source:    "jsonld",
+	}
+	if r.Title == "" {
+		r.Title = ldString(m["name"])
+	}
+	if r.URL == "" {
+		r.URL = pageURL
+	}
+	r.ReviewDate = parseLDDate(ldString(m["datePublished"]))
+
+	if rating, ok := m["reviewRating"].(map[string]interface{}); ok {
+		r.Rating = ldString(rating["alternateName"])
+		if r.Rating == "" {
+			r.Rating = ldString(rating["name"])
+		}
+		if r.Rating == "" {
+			if value := ldString(rating["ratingValue"]); value != "" {
+				r.Rating = value
+				if best := ldString(rating["bestRating"]); best != "" {
+					r.Rating += "/" + best
+				}
+			}
+		}
+	}
+	if author, ok := ldFirst(m["author"]).(map[string]interface{}); ok {
+		r.Publisher = ldString(author["name"])
+		if site := ldString(author["url"]); site != "" {
+			r.PublisherSite = URLHost(site)
+		}
+	} else {
+		r.Publisher = ldString(m["author"])
+	}
+	if r.PublisherSite == "" {
+		r.PublisherSite = URLHost(r.URL)
+	}
+	if item, ok := ldFirst(m["itemReviewed"]).(map[string]interface{}); ok {
+		r.Claimant = ldString(item["author"])
+		r.ClaimDate = parseLDDate(ldString(item["datePublished"]))
+		if r.ClaimText == "" {
+			r.ClaimText = ldString(item["name"])
+		}
+	}
+	r.ClaimText = strings.TrimSpace(r.ClaimText)
+	return r, r.ClaimText != "" && r.URL != ""
+}
+
+func ldHasType(m map[string]interface{}, typ string) bool {
+	switch t := m["@type"].(type) {
+	case string:
+		return t == typ
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ldFirst(v interface{}) interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		if len(arr) == 0 {
+			return nil
+		}
+		return arr[0]
+	}
+	return v
+}
+
+// ldString — строковое значение: строка, число, объект с name/@value или первый элемент массива.
+func ldString(v interface{}) string {
+	switch t := ldFirst(v).(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strings.TrimSuffix(fmt.Sprintf("%g", t), ".0")
+	case map[string]interface{}:
+		if s := ldString(t["name"]); s != "" {
+			return s
+		}
+		return ldString(t["@value"])
+	}
+	return ""
+}
+
+func parseLDDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{
+		time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02 15:04:05",
+		"2006-01-02T15:04Z07:00", "2006-01-02", time.RFC1123Z, time.RFC1123,
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// ── Хранилище ────────────────────────────────────────────────────────────────
+
+func claimHash(claim string) string {
+	sum := sha256.Sum256([]byte(normalizeQuery(claim)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SaveClaimReviews сохраняет проверки в claim_reviews (повторная проверка
+// того же утверждения по тому же URL обновляет запись). Возвращает число сохранённых.
+func SaveClaimReviews(reviews []models.ClaimReview) int {
+	if database.DB == nil {
+		return 0
+	}
+	saved := 0
+	for _, r := range reviews {
+		if strings.TrimSpace(r.ClaimText) == "" || r.URL == "" {
+			continue
+		}
+		_, err := database.DB.Exec(`
+			INSERT INTO claim_reviews (claim_text, claim_hash, claimant, claim_date, rating, publisher,
+				publisher_site, review_url, title, review_date, language, source)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (review_url, claim_hash) DO UPDATE SET
+				rating      = EXCLUDED.rating,
+				title       = COALESCE(NULLIF(EXCLUDED.title, ''), claim_reviews.title),
+				review_date = COALESCE(EXCLUDED.review_date, claim_reviews.review_date),
+				updated_at  = NOW()
+		`, strings.TrimSpace(r.ClaimText), claimHash(r.ClaimText), r.Claimant, r.ClaimDate, r.Rating, r.Publisher,
+			r.PublisherSite, r.URL, r.Title, r.ReviewDate, r.Language, r.Source)
+		if err != nil {
+			log.Printf("[CLAIMREVIEW] ⚠ Ошибка сохранения %s: %v", r.URL, err)
+			continue
+		}
+		saved++
+	}
+	if saved > 0 {
+		log.Printf("[CLAIMREVIEW] 💾 Сохранено проверок: %d", saved)
+	}
+	return saved
+}
+
+// findClaimReviewCandidates — полнотекстовый поиск по базе проверок по
+// ключевым словам текста (любое из слов).
+func findClaimReviewCandidates(text string) []models.ClaimReview {
+	if database.DB == nil {
+		return nil
+	}
+	var terms []string
+	for _, w := range topKeywords(text, 25) {
+		for _, part := range strings.Split(w, "-") {
+			if utf8.RuneCountInString(part) > 2 {
+				terms = append(terms, part)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	rows, err := database.DB.Query(`
+		SELECT id, claim_text, COALESCE(claimant, ''), claim_date, COALESCE(rating, ''), COALESCE(publisher, ''),
+			COALESCE(publisher_site, ''), review_url, COALESCE(title, ''), review_date, COALESCE(language, ''), COALESCE(source, '')
+		FROM claim_reviews
+		WHERE search_tsv @@ to_tsquery('simple', $1)
+		ORDER BY ts_rank(search_tsv, to_tsquery('simple', $1)) DESC
+		LIMIT $2
+	`, strings.Join(terms, " | "), claimCandidateLimit)
+	if err != nil {
+		log.Printf("[CLAIMREVIEW] ⚠ Ошибка поиска по базе: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var reviews []models.ClaimReview
+	for rows.Next() {
+		r, err := scanClaimReview(rows)
+		if err != nil {
+			continue
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews
+}
+
+func scanClaimReview(rows *sql.Rows) (models.ClaimReview, error) {
+	var r models.ClaimReview
+	var claimDate, reviewDate sql.NullTime
+	err := rows.Scan(&r.ID, &r.ClaimText, &r.Claimant, &claimDate, &r.Rating, &r.Publisher,
+		&r.PublisherSite, &r.URL, &r.Title, &reviewDate, &r.Language, &r.Source)
+	if claimDate.Valid {
+		r.ClaimDate = &claimDate.Time
+	}
+	if reviewDate.Valid {
+		r.ReviewDate = &reviewDate.Time
+	}
+	return r, err
+}
+
+// ── Сопоставление ────────────────────────────────────────────────────────────
+
+// trigrams — множество символьных триграмм слов строки (как в pg_trgm).
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range wordRe.FindAllString(strings.ToLower(s), -1) {
+		runes := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// trigramSimilarity — доля общих триграмм (коэффициент Жаккара).
+func trigramSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// matchSentences разбивает текст на предложения для сравнения с утверждениями.
+func matchSentences(text string) []string {
+	var sentences []string
+	for _, para := range strings.Split(text, "\n") {
+		for _, s := range sentenceEndRe.Split(para, -1) {
+			if s = strings.TrimSpace(s); utf8.RuneCountInString(s) >= 20 {
+				sentences = append(sentences, s)
+				if len(sentences) >= maxMatchSentences {
+					return sentences
+				}
+			}
+		}
+	}
+	return sentences
+}
+
+// MatchClaimReviews ищет проверки фактчекеров, похожие на утверждения текста:
+// кандидаты из базы (полнотекстовый поиск) и extra сравниваются с каждым
+// предложением текста по триграммам.
+func MatchClaimReviews(text string, extra []models.ClaimReview, limit int) []models.ClaimReviewMatch {
+	candidates := append(findClaimReviewCandidates(text), extra...)
+	if len(candidates) == 0 {
+		return nil
+	}
+	sentences := matchSentences(text)
+	if len(sentences) == 0 {
+		sentences = []string{text}
+	}
+	sentenceGrams := make([]map[string]bool, len(sentences))
+	for i, s := range sentences {
+		sentenceGrams[i] = trigrams(s)
+	}
+
+	best := map[string]models.ClaimReviewMatch{}
+	for _, c := range candidates {
+		cg := trigrams(c.ClaimText)
+		for i, sg := range sentenceGrams {
+			sim := trigramSimilarity(cg, sg)
+			if sim < claimMatchThreshold {
+				continue
+			}
+			key := c.URL + "|" + claimHash(c.ClaimText)
+			if prev, ok := best[key]; !ok || sim > prev.Similarity {
+				best[key] = models.ClaimReviewMatch{ClaimReview: c, Similarity: sim, MatchedText: truncate(sentences[i], 300)}
+			}
+		}
+	}
+
+	matches := make([]models.ClaimReviewMatch, 0, len(best))
+	for _, m := range best {
+		m.Similarity = float64(int(m.Similarity*100)) / 100
+		m.Normalized = NormalizeRating(m.Rating)
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// FormatClaimReviews форматирует найденные проверки для контекста модели.
+func FormatClaimReviews(matches []models.ClaimReviewMatch) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n--- 🕵️ БАЗА ПРОВЕРКИ ФАКТОВ (ClaimReview) ---\n")
+	sb.WriteString("ВАЖНО: Ниже приведены официальные проверки фактов, найденные независимыми журналистами. Если текущий текст совпадает с этими фейками, используйте это в анализе!\n")
+	for _, m := range matches {
+		sb.WriteString(fmt.Sprintf("\n🔴 Утверждение: \"%s\"\n", m.ClaimText))
+		verdict := m.Rating
+		if m.Normalized != nil && m.Normalized.Label != RatingUnknown {
+			verdict += " (" + m.Normalized.Name + ")"
+		}
+		sb.WriteString(fmt.Sprintf("📝 Вердикт журналистов: %s\n", verdict))
+		sb.WriteString(fmt.Sprintf("📰 Источник: %s (%s)\n", m.Publisher, m.URL))
+		sb.WriteString(fmt.Sprintf("🔗 Похоже на фрагмент текста (%.0f%%): \"%s\"\n", m.Similarity*100, m.MatchedText))
+	}
+	return sb.String()
+}
+
+// ── Сбор со страниц фактчекеров ──────────────────────────────────────────────
+
+// ScrapeClaimReviews загружает страницу и возвращает её ClaimReview-разметку
+// и ссылки на другие страницы того же сайта.
+func (f *ContentFetcher) ScrapeClaimReviews(pageURL string) ([]models.ClaimReview, []string, error) {
+	page, err := f.get(pageURL, f.client, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if page.StatusCode != 200 {
+		return nil, nil, fmt.Errorf("статус код: %d", page.StatusCode)
+	}
+	return ExtractClaimReviews(string(page.Body), page.URL), sameSiteLinks(string(page.Body), page.URL), nil
+}
+
+// sameSiteLinks — ссылки страницы на тот же домен, без якорей и повторов.
+func sameSiteLinks(htmlStr, baseURL string) []string {
+	doc, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		return nil
+	}
+	domain := NormalizeDomain(baseURL)
+	seen := map[string]bool{}
+	var links []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				link := resolveURL(baseURL, strings.TrimSpace(attr.Val))
+				if i := strings.IndexByte(link, '#'); i != -1 {
+					link = link[:i]
+				}
+				if strings.HasPrefix(link, "http") && NormalizeDomain(link) == domain && !seen[link] {
+					seen[link] = true
+					links = append(links, link)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links
+}
+
+// ClaimReviewCrawler периодически обходит страницы-списки фактчекеров и
+// собирает ClaimReview с новых статей.
+type ClaimReviewCrawler struct {
+	fetcher  *ContentFetcher
+	seeds    []string
+	interval time.Duration
+	perRun   int
+}
+
+func NewClaimReviewCrawler(fetcher *ContentFetcher, seeds []string, interval time.Duration, perRun int) *ClaimReviewCrawler {
+	return &ClaimReviewCrawler{fetcher: fetcher, seeds: seeds, interval: interval, perRun: perRun}
+}
+
+// Start запускает фоновый сбор (ничего не делает без интервала, страниц или БД).
+func (c *ClaimReviewCrawler) Start() {
+	if c.interval <= 0 || len(c.seeds) == 0 || database.DB == nil {
+		return
+	}
+	log.Printf("[CLAIMREVIEW] 🕸 Сбор проверок с %d страниц каждые %v", len(c.seeds), c.interval)
+	go func() {
+		c.runOnce()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			c.runOnce()
+		}
+	}()
+}
+
+func (c *ClaimReviewCrawler) runOnce() {
+	visited, saved := 0, 0
+	for _, seed := range c.seeds {
+		reviews, links, err := c.fetcher.ScrapeClaimReviews(seed)
+		if err != nil {
+			log.Printf("[CLAIMREVIEW] ⚠ %s: %v", seed, err)
+			continue
+		}
+		saved += SaveClaimReviews(reviews)
+		for _, link := range links {
+			if visited >= c.perRun {
+				break
+			}
+			key := claimReviewSourceKey + claimHash(link)
+			if _, err := cache.Get(key); err == nil {
+				continue
+			}
+			visited++
+			reviews, _, err := c.fetcher.ScrapeClaimReviews(link)
+			if err != nil {
+				log.Printf("[CLAIMREVIEW] ⚠ %s: %v", link, err)
+				continue
+			}
+			cache.Set(key, "1", claimReviewSeenTTL)
+			saved += SaveClaimReviews(reviews)
+		}
+	}
+	log.Printf("[CLAIMREVIEW] ✓ Обход завершён: страниц %d, сохранено проверок %d", visited, saved)
+}
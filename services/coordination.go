@@ -0,0 +1,433 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Согласованные публикации: один и тот же текст почти без изменений выходит
+// на нескольких доменах в течение нескольких часов — типичный почерк сетей
+// одноразовых сайтов. Сохранённые тексты анализов (а если текста нет —
+// снимки страниц) сравниваются по шинглам; группы из разных доменов,
+// опубликованные в пределах окна, сохраняются как сети, домены помечаются
+// в domain_stats, а новые анализы с тем же текстом получают предупреждение.
+
+// CoordinationConfig — параметры поиска сетей.
+type CoordinationConfig struct {
+	MinOverlap float64       // доля общих шинглов от меньшего текста (0..1)
+	Window     time.Duration // наибольший разрыв между публикациями одной сети
+	MinDomains int           // сколько разных доменов считается сетью
+	Lookback   time.Duration // за какой период сравниваются анализы
+	Interval   time.Duration // как часто искать (0 — выключено)
+	Batch      int           // статей за один проход
+}
+
+const (
+	coordSketchSize   = 128 // наименьших хешей шинглов в отпечатке статьи
+	coordMinShingles  = 20  // более короткие тексты не сравниваются
+	coordSampleRunes  = 300
+	coordShownDomains = 10
+)
+
+type CoordinationDetector struct {
+	cfg CoordinationConfig
+	mu  sync.Mutex // один проход за раз
+}
+
+func NewCoordinationDetector(cfg CoordinationConfig) *CoordinationDetector {
+	if cfg.MinOverlap <= 0 {
+		cfg.MinOverlap = 0.6
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	if cfg.MinDomains < 2 {
+		cfg.MinDomains = 3
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = 72 * time.Hour
+	}
+	if cfg.Batch <= 0 {
+		cfg.Batch = 1000
+	}
+	return &CoordinationDetector{cfg: cfg}
+}
+
+// ── Отпечатки ────────────────────────────────────────────────────────────────
+
+// shingleSketch — coordSketchSize наименьших хешей шинглов. Это равномерная
+// выборка шинглов текста: доля её элементов, найденных в другом тексте,
+// оценивает, какая часть этого текста в него входит.
+func shingleSketch(shingles map[uint64]bool) []int64 {
+	hashes := make([]uint64, 0, len(shingles))
+	for h := range shingles {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	if len(hashes) > coordSketchSize {
+		hashes = hashes[:coordSketchSize]
+	}
+	return shingleArray(hashes)
+}
+
+// shingleArray — хеши в виде BIGINT[] для Postgres.
+func shingleArray(hashes []uint64) []int64 {
+	out := make([]int64, len(hashes))
+	for i, h := range hashes {
+		out[i] = int64(h)
+	}
+	return out
+}
+
+// sketchContainment — доля отпечатка, найденная среди шинглов текста.
+func sketchContainment(sketch []int64, shingles map[uint64]bool) float64 {
+	if len(sketch) == 0 {
+		return 0
+	}
+	found := 0
+	for _, h := range sketch {
+		if shingles[uint64(h)] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(sketch))
+}
+
+// ── Поиск сетей ──────────────────────────────────────────────────────────────
+
+type coordArticle struct {
+	AnalysisID int64
+	URL        string
+	Domain     string
+	Text       string
+	At         time.Time // дата публикации, иначе время загрузки страницы
+	Shingles   map[uint64]bool
+}
+
+// loadCoordArticles — последние анализы URL за Lookback, по одному на адрес.
+func (d *CoordinationDetector) loadCoordArticles() ([]coordArticle, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, url, text, snapshot_id, at FROM (
+			SELECT DISTINCT ON (a.url) a.id, a.url, COALESCE(a.text, '') AS text,
+				COALESCE(a.snapshot_id, '') AS snapshot_id,
+				COALESCE(a.published_at, s.fetched_at, a.created_at) AS at,
+				a.created_at
+			FROM analysis_results a
+			LEFT JOIN snapshots s ON s.id = a.snapshot_id
+			WHERE a.url <> '' AND a.created_at > NOW() - make_interval(secs => $1)
+			ORDER BY a.url, a.created_at DESC
+		) t
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, d.cfg.Lookback.Seconds(), d.cfg.Batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var arts []coordArticle
+	for rows.Next() {
+		var a coordArticle
+		var snapshotID string
+		if rows.Scan(&a.AnalysisID, &a.URL, &a.Text, &snapshotID, &a.At) != nil {
+			continue
+		}
+		if a.Text == "" && snapshotID != "" {
+			if snap, err := GetSnapshot(snapshotID); err == nil {
+				a.Text = snap.Text
+			}
+		}
+		a.Domain = NormalizeDomain(a.URL)
+		a.Shingles = textShingles(a.Text)
+		if len(a.Shingles) < coordMinShingles || a.Domain == "" {
+			continue
+		}
+		arts = append(arts, a)
+	}
+	return arts, rows.Err()
+}
+
+// findCoordinated группирует статьи разных доменов с почти одинаковым текстом,
+// опубликованные в пределах окна. Возвращает индексы статей каждой группы.
+func findCoordinated(arts []coordArticle, cfg CoordinationConfig) [][]int {
+	postings := map[uint64][]int{}
+	for i, a := range arts {
+		for h := range a.Shingles {
+			postings[h] = append(postings[h], i)
+		}
+	}
+
+	parent := make([]int, len(arts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i, a := range arts {
+		common := map[int]int{}
+		for h := range a.Shingles {
+			for _, j := range postings[h] {
+				if j > i {
+					common[j]++
+				}
+			}
+		}
+		for j, c := range common {
+			b := arts[j]
+			if a.Domain == b.Domain || chainAbs(a.At.Sub(b.At)) > cfg.Window {
+				continue
+			}
+			if float64(c)/float64(min(len(a.Shingles), len(b.Shingles))) >= cfg.MinOverlap {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+
+	groups := map[int][]int{}
+	for i := range arts {
+		groups[find(i)] = append(groups[find(i)], i)
+	}
+	var out [][]int
+	for _, g := range groups {
+		domains := map[string]bool{}
+		for _, i := range g {
+			domains[arts[i].Domain] = true
+		}
+		if len(domains) >= cfg.MinDomains {
+			sort.Slice(g, func(x, y int) bool { return arts[g[x]].At.Before(arts[g[y]].At) })
+			out = append(out, g)
+		}
+	}
+	sort.Slice(out, func(x, y int) bool { return arts[out[x][0]].At.Before(arts[out[y][0]].At) })
+	return out
+}
+
+// saveCluster дописывает группу в существующую сеть (если кто-то из статей
+// уже в ней) или заводит новую. members отсортированы по дате.
+func saveCluster(members []coordArticle) (id int64, isNew bool, err error) {
+	ids := make([]int64, len(members))
+	for i, m := range members {
+		ids[i] = m.AnalysisID
+	}
+	err = database.DB.QueryRow(`
+		SELECT cluster_id FROM coordinated_members WHERE analysis_id = ANY($1)
+		ORDER BY cluster_id LIMIT 1
+	`, pq.Array(ids)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		first := members[0]
+		err = database.DB.QueryRow(`
+			INSERT INTO coordinated_clusters (sample, first_published, last_published)
+			VALUES ($1, $2, $2)
+			RETURNING id
+		`, chainTruncate(strings.TrimSpace(first.Text), coordSampleRunes), first.At).Scan(&id)
+		if err != nil {
+			return 0, false, err
+		}
+		isNew = true
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	for _, m := range members {
+		if _, err := database.DB.Exec(`
+			INSERT INTO coordinated_members (cluster_id, analysis_id, url, domain, published_at, sketch)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT DO NOTHING
+		`, id, m.AnalysisID, m.URL, m.Domain, m.At, pq.Array(shingleSketch(m.Shingles))); err != nil {
+			log.Printf("[COORD] ⚠ Ошибка добавления статьи #%d в сеть %d: %v", m.AnalysisID, id, err)
+		}
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE coordinated_clusters c SET
+			article_count   = m.articles,
+			domain_count    = m.domains,
+			first_published = m.first,
+			last_published  = m.last,
+			updated_at      = NOW()
+		FROM (
+			SELECT COUNT(*) AS articles, COUNT(DISTINCT domain) AS domains,
+				MIN(published_at) AS first, MAX(published_at) AS last
+			FROM coordinated_members WHERE cluster_id = $1
+		) m
+		WHERE c.id = $1
+	`, id)
+	return id, isNew, err
+}
+
+// flagCoordinatedDomains пересчитывает, в скольких сетях участвовал каждый домен.
+func flagCoordinatedDomains(domains []string) {
+	_, err := database.DB.Exec(`
+		UPDATE domain_stats d SET
+			coordinated_clusters = m.clusters,
+			coordinated_at       = NOW()
+		FROM (
+			SELECT domain, COUNT(DISTINCT cluster_id) AS clusters
+			FROM coordinated_members WHERE domain = ANY($1)
+			GROUP BY domain
+		) m
+		WHERE d.domain = m.domain
+	`, pq.Array(domains))
+	if err != nil {
+		log.Printf("[COORD] ⚠ Ошибка пометки доменов: %v", err)
+	}
+}
+
+// Start запускает поиск сетей — сразу и затем каждые Interval
+// (ничего не делает, если интервал не задан).
+func (d *CoordinationDetector) Start() {
+	if d == nil || d.cfg.Interval <= 0 || database.DB == nil {
+		return
+	}
+	log.Printf("[COORD] 🕸 Поиск согласованных публикаций каждые %v (≥%d доменов, окно %v)", d.cfg.Interval, d.cfg.MinDomains, d.cfg.Window)
+	go func() {
+		d.runOnce()
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.runOnce()
+		}
+	}()
+}
+
+func (d *CoordinationDetector) runOnce() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	arts, err := d.loadCoordArticles()
+	if err != nil {
+		log.Printf("[COORD] ⚠ Ошибка чтения анализов: %v", err)
+		return
+	}
+	groups := findCoordinated(arts, d.cfg)
+	created := 0
+	domains := map[string]bool{}
+	for _, g := range groups {
+		members := make([]coordArticle, len(g))
+		for i, idx := range g {
+			members[i] = arts[idx]
+			domains[arts[idx].Domain] = true
+		}
+		id, isNew, err := saveCluster(members)
+		if err != nil {
+			log.Printf("[COORD] ⚠ Ошибка сохранения сети: %v", err)
+			continue
+		}
+		if isNew {
+			created++
+			log.Printf("[COORD] 🚨 Новая сеть #%d: %d статей на %d доменах", id, len(members), coordDomainCount(members))
+		}
+	}
+	if len(domains) > 0 {
+		list := make([]string, 0, len(domains))
+		for dom := range domains {
+			list = append(list, dom)
+		}
+		flagCoordinatedDomains(list)
+	}
+	log.Printf("[COORD] ✓ Сравнено статей: %d, сетей: %d (новых: %d)", len(arts), len(groups), created)
+}
+
+func coordDomainCount(members []coordArticle) int {
+	seen := map[string]bool{}
+	for _, m := range members {
+		seen[m.Domain] = true
+	}
+	return len(seen)
+}
+
+// ── Проверка нового текста ───────────────────────────────────────────────────
+
+// Match ищет известную сеть, публиковавшую этот текст. nil — совпадений нет.
+func (d *CoordinationDetector) Match(text string) *models.CoordinatedMatch {
+	if d == nil || database.DB == nil {
+		return nil
+	}
+	shingles := textShingles(text)
+	if len(shingles) < coordMinShingles {
+		return nil
+	}
+	hashes := make([]uint64, 0, len(shingles))
+	for h := range shingles {
+		hashes = append(hashes, h)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT cluster_id, sketch FROM coordinated_members WHERE sketch && $1
+	`, pq.Array(shingleArray(hashes)))
+	if err != nil {
+		log.Printf("[COORD] ⚠ Ошибка поиска сети: %v", err)
+		return nil
+	}
+	var bestID int64
+	var best float64
+	for rows.Next() {
+		var id int64
+		var sketch []int64
+		if rows.Scan(&id, pq.Array(&sketch)) != nil {
+			continue
+		}
+		if c := sketchContainment(sketch, shingles); c > best {
+			bestID, best = id, c
+		}
+	}
+	rows.Close()
+	if best < d.cfg.MinOverlap {
+		return nil
+	}
+
+	m := &models.CoordinatedMatch{ClusterID: bestID, Overlap: math.Round(best*1000) / 10}
+	err = database.DB.QueryRow(`
+		SELECT article_count, domain_count, first_published, last_published
+		FROM coordinated_clusters WHERE id = $1
+	`, bestID).Scan(&m.ArticleCount, &m.DomainCount, &m.FirstSeen, &m.LastSeen)
+	if err != nil {
+		log.Printf("[COORD] ⚠ Ошибка чтения сети %d: %v", bestID, err)
+		return nil
+	}
+	domRows, err := database.DB.Query(`
+		SELECT domain FROM coordinated_members WHERE cluster_id = $1
+		GROUP BY domain ORDER BY MIN(published_at) LIMIT $2
+	`, bestID, coordShownDomains)
+	if err == nil {
+		for domRows.Next() {
+			var dom string
+			if domRows.Scan(&dom) == nil {
+				m.Domains = append(m.Domains, dom)
+			}
+		}
+		domRows.Close()
+	}
+	m.Warning = fmt.Sprintf("Координированная сеть: почти тот же текст опубликован на %d доменах за %s",
+		m.DomainCount, coordSpan(m.LastSeen.Sub(m.FirstSeen)))
+	return m
+}
+
+// coordSpan — промежуток между первой и последней публикацией по-человечески.
+func coordSpan(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин.", int(d.Minutes())+1)
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%.0f ч.", math.Ceil(d.Hours()))
+	default:
+		return fmt.Sprintf("%.0f дн.", math.Ceil(d.Hours()/24))
+	}
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"text-analyzer/cache"
+	"time"
+)
+
+// CrawlConfig — настройки «вежливой» загрузки: общий для всех сервисов
+// User-Agent, лимиты на хост, robots.txt и кэш ответов.
+type CrawlConfig struct {
+	UserAgent       string
+	RespectRobots   bool
+	HostConcurrency int           // одновременных запросов к одному хосту
+	HostInterval    time.Duration // минимальная пауза между запросами к одному хосту
+	CacheTTL        time.Duration // сколько ответ считается свежим без перепроверки
+	ArchiveAPIURL   string        // Wayback availability API; пусто — фолбек на архив отключён
+}
+
+// DefaultCrawlConfig — значения по умолчанию.
+func DefaultCrawlConfig() CrawlConfig {
+	return CrawlConfig{
+		UserAgent:       "TextAnalyzerBot/1.0 (fact-checking service)",
+		HostConcurrency: 2,
+		HostInterval:    time.Second,
+		CacheTTL:        10 * time.Minute,
+		ArchiveAPIURL:   DefaultArchiveAPIURL,
+	}
+}
+
+// Ответы больше этого размера не кладём в Redis.
+const maxCachedBodyBytes = 2 << 20
+
+// fetchedPage — сырой ответ сервера после всех проверок политики.
+type fetchedPage struct {
+	URL        string      `json:"url"` // итоговый URL после редиректов
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+	FromCache  bool        `json:"-"`
+}
+
+// ── Лимиты на хост ───────────────────────────────────────────────────────────
+
+type hostSlot struct {
+	sem   chan struct{}
+	mu    sync.Mutex
+	next  time.Time
+	users int // ждут или держат слот; под hostLimiter.mu
+}
+
+type hostLimiter struct {
+	mu          sync.Mutex
+	slots       map[string]*hostSlot
+	concurrency int
+	interval    time.Duration
+	swept       time.Time
+}
+
+// hostSlotSweepInterval — как часто удалять слоты хостов, к которым никто
+// не обращается и пауза после последнего запроса уже прошла.
+const hostSlotSweepInterval = time.Minute
+
+func newHostLimiter(concurrency int, interval time.Duration) *hostLimiter {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &hostLimiter{slots: map[string]*hostSlot{}, concurrency: concurrency, interval: interval}
+}
+
+// acquire ждёт свободный слот для хоста и соблюдает паузу между запросами.
+// extraDelay — Crawl-delay из robots.txt, если он больше общего интервала.
+// Если ctx завершится раньше, возвращает его ошибку.
+func (l *hostLimiter) acquire(ctx context.Context, host string, extraDelay time.Duration) (func(), error) {
+	l.mu.Lock()
+	l.sweep()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.concurrency)}
+		l.slots[host] = slot
+	}
+	slot.users++
+	l.mu.Unlock()
+
+	done := func() {
+		l.mu.Lock()
+		slot.users--
+		l.mu.Unlock()
+	}
+
+	select {
+	case slot.sem <- struct{}{}:
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+	release := func() {
+		<-slot.sem
+		done()
+	}
+
+	interval := l.interval
+	if extraDelay > interval {
+		interval = extraDelay
+	}
+	slot.mu.Lock()
+	wait := time.Until(slot.next)
+	start := time.Now()
+	if wait > 0 {
+		start = slot.next
+	}
+	slot.next = start.Add(interval)
+	slot.mu.Unlock()
+
+	if wait > 0 {
+		log.Printf("[CRAWLER] ⏱ %s: пауза %v перед запросом", host, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// sweep удаляет простаивающие слоты. Вызывается под l.mu.
+func (l *hostLimiter) sweep() {
+	now := time.Now()
+	if now.Sub(l.swept) < hostSlotSweepInterval {
+		return
+	}
+	l.swept = now
+	for host, slot := range l.slots {
+		if slot.users > 0 {
+			continue
+		}
+		slot.mu.Lock()
+		idle := now.After(slot.next)
+		slot.mu.Unlock()
+		if idle {
+			delete(l.slots, host)
+		}
+	}
+}
+
+// ── robots.txt ───────────────────────────────────────────────────────────────
+
+type robotsRule struct {
+	allow   bool
+	pattern *regexp.Regexp
+	length  int
+}
+
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	expires    time.Time
+}
+
+// allowed применяет правило с самым длинным совпадением (как Google).
+func (r *robotsRules) allowed(path string) bool {
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if rule.length > best && rule.pattern.MatchString(path) {
+			best = rule.length
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPattern переводит шаблон robots.txt (* и $) в regexp.
+func robotsPattern(p string) *regexp.Regexp {
+	anchored := strings.HasSuffix(p, "$")
+	p = strings.TrimSuffix(p, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// parseRobots выбирает группу для нашего агента (или *) и собирает её правила.
+func parseRobots(data []byte, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	if i := strings.IndexAny(agent, "/ "); i != -1 {
+		agent = agent[:i]
+	}
+
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+	var groups []*group
+	var cur *group
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if cur == nil || !lastWasAgent {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if cur != nil && value != "" {
+				cur.rules = append(cur.rules, robotsRule{
+					allow:   key == "allow",
+					pattern: robotsPattern(value),
+					length:  len(value),
+				})
+			}
+		case "crawl-delay":
+			if cur != nil {
+				var sec float64
+				if _, err := fmt.Sscanf(value, "%g", &sec); err == nil && sec > 0 {
+					cur.delay = time.Duration(sec * float64(time.Second))
+				}
+			}
+		}
+		lastWasAgent = false
+	}
+
+	var wildcard, specific *group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" && wildcard == nil {
+				wildcard = g
+			} else if a != "*" && agent != "" && strings.Contains(agent, a) && specific == nil {
+				specific = g
+			}
+		}
+	}
+	chosen := specific
+	if chosen == nil {
+		chosen = wildcard
+	}
+	rules := &robotsRules{}
+	if chosen != nil {
+		rules.rules = chosen.rules
+		rules.crawlDelay = chosen.delay
+	}
+	return rules
+}
+
+// robotsFor возвращает (и кэширует на час) правила robots.txt для хоста.
+// Недоступный robots.txt трактуется как «всё разрешено». Загрузка идёт
+// через лимиты на хост, как и запросы страниц.
+func (f *ContentFetcher) robotsFor(ctx context.Context, u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	f.robotsMu.Lock()
+	rules, ok := f.robots[key]
+	f.robotsMu.Unlock()
+	if ok && time.Now().Before(rules.expires) {
+		return rules
+	}
+
+	release, err := f.limiter.acquire(ctx, strings.ToLower(u.Host), 0)
+	if err != nil {
+		// Не дождались очереди — не кэшируем, перепроверим в следующий раз
+		return &robotsRules{}
+	}
+	defer release()
+
+	rules = &robotsRules{}
+	req, err := http.NewRequestWithContext(ctx, "GET", key+"/robots.txt", nil)
+	if err == nil {
+		req.Header.Set("User-Agent", f.crawl.UserAgent)
+		if resp, err := f.client.Do(req); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				if body, err := f.policy.ReadBody(resp.Body); err == nil {
+					rules = parseRobots(body, f.crawl.UserAgent)
+				}
+			}
+			resp.Body.Close()
+		} else {
+			log.Printf("[CRAWLER] ⚠ robots.txt для %s недоступен: %v", u.Host, err)
+		}
+	}
+	rules.expires = time.Now().Add(time.Hour)
+
+	f.robotsMu.Lock()
+	f.robots[key] = rules
+	f.robotsMu.Unlock()
+	return rules
+}
+
+// ── Кэш ответов ──────────────────────────────────────────────────────────────
+
+// Заголовки, с которыми запрашиваются страницы. Они входят в ключ кэша:
+// сайты отдают разный HTML разным User-Agent (мобильная версия,
+// OpenGraph-разметка для facebookexternalhit).
+const (
+	fetchAccept         = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	fetchAcceptLanguage = "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7"
+)
+
+// fetchRevalidateWindow — сколько после истечения CacheTTL хранится ответ с
+// ETag / Last-Modified, чтобы перепроверить его условным запросом.
+var fetchRevalidateWindow = time.Hour
+
+func fetchCacheKey(rawURL, userAgent string) string {
+	sum := sha256.Sum256([]byte(rawURL + "\n" + userAgent + "\n" + fetchAccept + "\n" + fetchAcceptLanguage))
+	return "fetch:" + hex.EncodeToString(sum[:])
+}
+
+// cachedPageTTL — срок хранения ответа в Redis: CacheTTL, а для ответов с
+// валидаторами ещё и окно перепроверки. 0 — не кэшировать.
+func cachedPageTTL(page *fetchedPage, ttl time.Duration) time.Duration {
+	if ttl <= 0 || page.StatusCode != http.StatusOK || len(page.Body) > maxCachedBodyBytes {
+		return 0
+	}
+	if page.Header.Get("ETag") != "" || page.Header.Get("Last-Modified") != "" {
+		return ttl + fetchRevalidateWindow
+	}
+	return ttl
+}
+
+func loadCachedPage(key string) *fetchedPage {
+	raw, err := cache.Get(key)
+	if err != nil {
+		return nil
+	}
+	var page fetchedPage
+	if err := json.Unmarshal([]byte(raw), &page); err != nil {
+		return nil
+	}
+	page.FromCache = true
+	return &page
+}
+
+// storeCachedPage сохраняет успешный ответ на срок cachedPageTTL.
+func (f *ContentFetcher) storeCachedPage(key string, page *fetchedPage) {
+	ttl := cachedPageTTL(page, f.crawl.CacheTTL)
+	if ttl <= 0 {
+		return
+	}
+	if data, err := json.Marshal(page); err == nil {
+		cache.Set(key, string(data), ttl)
+	}
+}
+
+// ── Общая точка загрузки ─────────────────────────────────────────────────────
+
+// get загружает страницу через общий слой: проверка политики, robots.txt,
+// лимиты на хост и HTTP-кэш в Redis. userAgent пустой — используется общий.
+func (f *ContentFetcher) get(rawURL string, client *http.Client, userAgent string) (*fetchedPage, error) {
+	u, err := f.policy.ValidateURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent == "" {
+		userAgent = f.crawl.UserAgent
+	}
+
+	cacheKey := fetchCacheKey(rawURL, userAgent)
+	cached := loadCachedPage(cacheKey)
+	if cached != nil && time.Since(cached.FetchedAt) < f.crawl.CacheTTL {
+		log.Printf("[CRAWLER] 🚀 %s — из кэша (%v назад)", u.Host, time.Since(cached.FetchedAt).Round(time.Second))
+		return cached, nil
+	}
+
+	var crawlDelay time.Duration
+	if f.crawl.RespectRobots {
+		rules := f.robotsFor(context.Background(), u)
+		path := u.EscapedPath()
+		if u.RawQuery != "" {
+			path += "?" + u.RawQuery
+		}
+		if path == "" {
+			path = "/"
+		}
+		if !rules.allowed(path) {
+			return nil, fetchDeniedError(fmt.Sprintf("❌ Загрузка %s запрещена robots.txt сайта", u.Host))
+		}
+		crawlDelay = rules.crawlDelay
+	}
+
+	release, err := f.limiter.acquire(context.Background(), strings.ToLower(u.Host), crawlDelay)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", fetchAccept)
+	req.Header.Set("Accept-Language", fetchAcceptLanguage)
+	if cached != nil {
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		log.Printf("[CRAWLER] ✓ %s — 304 Not Modified, использую кэш", u.Host)
+		cached.FetchedAt = time.Now()
+		f.storeCachedPage(cacheKey, cached)
+		return cached, nil
+	}
+
+	page := &fetchedPage{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		FetchedAt:  time.Now(),
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := checkContentType(resp.Header.Get("Content-Type")); err != nil {
+			return nil, err
+		}
+		page.Body, err = f.policy.ReadBody(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения: %w", err)
+		}
+		f.storeCachedPage(cacheKey, page)
+	}
+	return page, nil
+}
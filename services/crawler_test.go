@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCacheKeyVariesByUserAgent(t *testing.T) {
+	const page = "https://example.md/news/1"
+	base := fetchCacheKey(page, DefaultCrawlConfig().UserAgent)
+	if base != fetchCacheKey(page, DefaultCrawlConfig().UserAgent) {
+		t.Error("key must be stable")
+	}
+	for _, other := range []string{
+		fetchCacheKey(page, "facebookexternalhit/1.1"),
+		fetchCacheKey(page, "Mozilla/5.0 (Linux; Android 12)"),
+		fetchCacheKey(page+"?amp=1", DefaultCrawlConfig().UserAgent),
+	} {
+		if other == base {
+			t.Error("different request variants share a cache entry")
+		}
+	}
+}
+
+func TestCachedPageTTL(t *testing.T) {
+	withValidator := func(name, value string) http.Header {
+		h := http.Header{}
+		h.Set(name, value)
+		return h
+	}
+	ttl := 10 * time.Minute
+	tests := []struct {
+		name string
+		page fetchedPage
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{"no validators", fetchedPage{StatusCode: 200, Header: http.Header{}}, ttl, ttl},
+		{"etag", fetchedPage{StatusCode: 200, Header: withValidator("ETag", `"v1"`)}, ttl, ttl + fetchRevalidateWindow},
+		{"last-modified", fetchedPage{StatusCode: 200, Header: withValidator("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")}, ttl, ttl + fetchRevalidateWindow},
+		{"cache disabled", fetchedPage{StatusCode: 200, Header: withValidator("ETag", `"v1"`)}, 0, 0},
+		{"not found", fetchedPage{StatusCode: 404, Header: http.Header{}}, ttl, 0},
+		{"too large", fetchedPage{StatusCode: 200, Header: http.Header{}, Body: make([]byte, maxCachedBodyBytes+1)}, ttl, 0},
+	}
+	for _, tt := range tests {
+		if got := cachedPageTTL(&tt.page, tt.ttl); got != tt.want {
+			t.Errorf("%s: ttl %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiterHonoursContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		limiter  *hostLimiter
+		holdSlot bool // первый запрос ещё выполняется
+	}{
+		{"all slots busy", newHostLimiter(1, 0), true},
+		{"interval not passed", newHostLimiter(2, time.Hour), false},
+	}
+	for _, tt := range tests {
+		release, err := tt.limiter.acquire(context.Background(), "news.md", 0)
+		if err != nil {
+			t.Fatalf("%s: first acquire: %v", tt.name, err)
+		}
+		if !tt.holdSlot {
+			release()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		if _, err := tt.limiter.acquire(ctx, "news.md", 0); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: err = %v, want deadline exceeded", tt.name, err)
+		}
+		cancel()
+		if waited := time.Since(start); waited > time.Second {
+			t.Errorf("%s: acquire returned after %v, want it to stop with ctx", tt.name, waited)
+		}
+		if tt.holdSlot {
+			release()
+		}
+
+		// Отменённое ожидание не занимает слот
+		tt.limiter.mu.Lock()
+		users := tt.limiter.slots["news.md"].users
+		tt.limiter.mu.Unlock()
+		if users != 0 {
+			t.Errorf("%s: %d users left after release", tt.name, users)
+		}
+	}
+}
+
+func TestHostLimiterEvictsIdleSlots(t *testing.T) {
+	l := newHostLimiter(1, 0)
+	idle, err := l.acquire(context.Background(), "idle.md", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle()
+	busy, err := l.acquire(context.Background(), "busy.md", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy()
+
+	l.mu.Lock()
+	l.swept = time.Time{}
+	l.mu.Unlock()
+	release, err := l.acquire(context.Background(), "other.md", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.slots["idle.md"]; ok {
+		t.Error("idle slot was not evicted")
+	}
+	if _, ok := l.slots["busy.md"]; !ok {
+		t.Error("slot in use was evicted")
+	}
+}
+
+func TestRobotsFetchUsesHostLimiter(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			hits.Add(1)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/news/1")
+
+	crawl := DefaultCrawlConfig()
+	crawl.HostConcurrency = 1
+	crawl.HostInterval = 0
+	crawl.ArchiveAPIURL = ""
+	policy := DefaultFetchPolicy()
+	policy.AllowPrivate = true
+	f := NewContentFetcher(policy, crawl)
+
+	// Пока слот хоста занят, robots.txt не запрашивается
+	release, err := f.limiter.acquire(context.Background(), strings.ToLower(u.Host), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	f.robotsFor(ctx, u)
+	cancel()
+	if hits.Load() != 0 {
+		t.Errorf("robots.txt fetched while the host slot was busy")
+	}
+	release()
+
+	if rules := f.robotsFor(context.Background(), u); rules.allowed("/private/x") || hits.Load() != 1 {
+		t.Errorf("robots.txt hits = %d, want rules fetched once the slot is free", hits.Load())
+	}
+}
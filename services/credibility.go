@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"text-analyzer/models"
+)
+
+// Уровни доверия к источнику результата поиска.
+const (
+	TierFactCheck  = "факт-чекер"
+	TierTrusted    = "проверенный" // из списка SEARCH_TRUSTED_DOMAINS
+	TierReliable   = "надёжный"    // по domain_stats
+	TierUnknown    = "неизвестный"
+	TierDoubtful   = "сомнительный"
+	TierUnreliable = "ненадёжный"
+)
+
+// Порядок уровней при ранжировании: меньше — выше в выдаче.
+var tierRank = map[string]int{
+	TierFactCheck:  0,
+	TierTrusted:    1,
+	TierReliable:   2,
+	TierUnknown:    3,
+	TierDoubtful:   4,
+	TierUnreliable: 5,
+}
+
+// DefaultFactCheckDomains — известные фактчекинговые проекты (дополняются
+// SEARCH_FACTCHECK_DOMAINS).
+var DefaultFactCheckDomains = []string{
+	"stopfals.md", "factcheck.org", "snopes.com", "politifact.com", "fullfact.org",
+	"factcheck.afp.com", "leadstories.com", "checkyourfact.com", "euvsdisinfo.eu",
+	"provereno.media", "factcheck.kz", "stopfake.org", "veridica.ro", "factual.ro",
+}
+
+// DomainVerdict — словесная оценка домена по сглаженной оценке его статей.
+func DomainVerdict(avg float64) string {
+	switch {
+	case avg >= 7:
+		return "надёжный"
+	case avg >= lowReputationScore:
+		return "сомнительный"
+	default:
+		return "ненадёжный"
+	}
+}
+
+// IsDeserving сообщает, можно ли ссылаться на источник как на подтверждение.
+func IsDeserving(tier string) bool {
+	return tier == TierFactCheck || tier == TierTrusted || tier == TierReliable
+}
+
+// sourceTier определяет уровень доверия к источнику: списки из конфигурации
+// сверяются с хостом, репутация — с доменом (издателем на платформе).
+// blocked — хост в запрещённом списке, его результаты отбрасываются.
+// Ручное решение и курируемые списки важнее остальных признаков.
+func (s *SearchService) sourceTier(host, domain string, scores map[string]domainScore, curations map[string]*models.DomainCuration) (tier string, score *float64, blocked bool) {
+	if hostMatches(host, s.cfg.BlockedDomains) {
+		return TierUnreliable, nil, true
+	}
+	if tier := curatedTier(curations[domain]); tier != "" {
+		return tier, nil, false
+	}
+	if hostMatches(host, s.cfg.FactCheckDomains) {
+		return TierFactCheck, nil, false
+	}
+	if hostMatches(host, s.cfg.TrustedDomains) {
+		return TierTrusted, nil, false
+	}
+	if ds, ok := scores[domain]; ok {
+		score := ds.Score
+		return DomainVerdict(score), &score, false
+	}
+	return TierUnknown, nil, false
+}
+
+// RankResults помечает результаты уровнем доверия к источнику и сортирует
+// их: факт-чекеры и проверенные СМИ выше, «ненадёжные» — в конце.
+// Домены из запрещённого списка отбрасываются.
+func (s *SearchService) RankResults(results []SearchResult) []SearchResult {
+	if s == nil || len(results) == 0 {
+		return results
+	}
+	var hosts, domains []string
+	for _, r := range results {
+		hosts = append(hosts, URLHost(r.Link))
+		domains = append(domains, NormalizeDomain(r.Link))
+	}
+	scores := LookupDomainScores(domains)
+	curations := LookupCurations(domains)
+
+	ranked := make([]SearchResult, 0, len(results))
+	dropped := 0
+	for i, r := range results {
+		tier, score, blocked := s.sourceTier(hosts[i], domains[i], scores, curations)
+		if blocked {
+			dropped++
+			continue
+		}
+		r.Tier, r.DomainScore = tier, score
+		ranked = append(ranked, r)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return tierRank[ranked[i].Tier] < tierRank[ranked[j].Tier]
+	})
+	if dropped > 0 {
+		log.Printf("[SEARCH] 🚫 Отброшено %d результатов с запрещённых доменов", dropped)
+	}
+	return ranked
+}
+
+// tierLabel — подпись источника для контекста модели.
+func tierLabel(r SearchResult) string {
+	label := r.Tier
+	if r.DomainScore != nil {
+		label += fmt.Sprintf(", репутация домена %.1f/10", *r.DomainScore)
+	}
+	return label
+}
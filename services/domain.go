@@ -2,27 +2,59 @@ package services
 
 import (
 	"log"
+	"net"
 	"net/url"
 	"strings"
 	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/net/publicsuffix"
 )
 
-// NormalizeDomain extracts host from a URL and strips www. prefix and port.
+// URLHost extracts host from a URL and strips www. prefix and port.
+func URLHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return urlHost(u)
+}
+
+func urlHost(u *url.URL) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), "www.")
+}
+
+// NormalizeDomain returns the entity a URL's reputation is tracked under:
+// the registrable domain by the public suffix list (news.example.co.uk →
+// example.co.uk), or the publisher on a platform (t.me/channel).
 func NormalizeDomain(rawURL string) string {
 	u, err := url.Parse(rawURL)
 	if err != nil || u.Host == "" {
 		return rawURL
 	}
-	host := strings.ToLower(u.Host)
-	host = strings.TrimPrefix(host, "www.")
-	if idx := strings.LastIndex(host, ":"); idx != -1 {
-		host = host[:idx]
+	host := urlHost(u)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host // localhost, сам публичный суффикс и т. п.
+	}
+	if entity, ok := platformEntity(host, registrable, u); ok {
+		return entity
 	}
-	return host
+	return registrable
 }
 
+// decaySQL — доля веса, оставшаяся у прежних анализов домена к NOW() ($4 — полураспад в секундах).
+const decaySQL = `power(0.5, EXTRACT(EPOCH FROM NOW() - COALESCE(domain_stats.decayed_at, NOW())) / $4::FLOAT)`
+
 // UpsertDomainStats updates domain reputation after each URL analysis.
-func UpsertDomainStats(rawURL string, score int) {
+// Ratings outside the 1–5 scale (unproven, unknown) don't count towards avg_rating.
+// Decayed sums are brought to NOW() before the new score is added.
+func UpsertDomainStats(rawURL string, score int, rating *models.NormalizedRating) {
 	if database.DB == nil {
 		return
 	}
@@ -30,18 +62,265 @@ func UpsertDomainStats(rawURL string, score int) {
 	if domain == "" {
 		return
 	}
-	_, err := database.DB.Exec(`
-		INSERT INTO domain_stats (domain, total_analyses, sum_scores, avg_score, last_analyzed_at)
-		VALUES ($1, 1, $2::INTEGER, $2::FLOAT, NOW())
+	ratingValue := 0
+	if rating != nil {
+		ratingValue = rating.Value
+	}
+	var w, s, q float64
+	err := database.DB.QueryRow(`
+		INSERT INTO domain_stats (domain, total_analyses, sum_scores, avg_score, last_analyzed_at,
+			rated_analyses, sum_rating, avg_rating,
+			decayed_weight, decayed_sum, decayed_sq, decayed_at)
+		VALUES ($1, 1, $2::INTEGER, $2::FLOAT, NOW(),
+			CASE WHEN $3 > 0 THEN 1 ELSE 0 END, $3::INTEGER, CASE WHEN $3 > 0 THEN $3::FLOAT END,
+			1, $2::FLOAT, $2::FLOAT * $2::FLOAT, NOW())
 		ON CONFLICT (domain) DO UPDATE SET
 			total_analyses   = domain_stats.total_analyses + 1,
 			sum_scores       = domain_stats.sum_scores + $2::INTEGER,
 			avg_score        = (domain_stats.sum_scores + $2)::float / (domain_stats.total_analyses + 1),
-			last_analyzed_at = NOW()
-	`, domain, score)
+			last_analyzed_at = NOW(),
+			rated_analyses   = domain_stats.rated_analyses + CASE WHEN $3 > 0 THEN 1 ELSE 0 END,
+			sum_rating       = domain_stats.sum_rating + $3::INTEGER,
+			avg_rating       = CASE WHEN $3 > 0
+				THEN (domain_stats.sum_rating + $3)::float / (domain_stats.rated_analyses + 1)
+				ELSE domain_stats.avg_rating END,
+			decayed_weight   = domain_stats.decayed_weight * `+decaySQL+` + 1,
+			decayed_sum      = domain_stats.decayed_sum * `+decaySQL+` + $2::FLOAT,
+			decayed_sq       = domain_stats.decayed_sq * `+decaySQL+` + $2::FLOAT * $2::FLOAT,
+			decayed_at       = NOW()
+		RETURNING decayed_weight, decayed_sum, decayed_sq
+	`, domain, score, ratingValue, reputation.HalfLife.Seconds()).Scan(&w, &s, &q)
 	if err != nil {
 		log.Printf("[DOMAIN] ⚠ Ошибка обновления stats для %s: %v", domain, err)
-	} else {
-		log.Printf("[DOMAIN] ✓ Stats обновлены: %s score=%d", domain, score)
+		return
+	}
+	now := time.Now()
+	rep := reputationFromSums(w, s, q, now, now)
+	recordDomainHistory(domain, score, rep)
+	log.Printf("[DOMAIN] ✓ Stats обновлены: %s score=%d репутация=%.1f [%.1f–%.1f]", domain, score, rep.Score, rep.Low, rep.High)
+}
+
+// ReputationColumns — столбцы domain_stats, из которых ScanReputation
+// собирает сглаженную оценку.
+const ReputationColumns = `COALESCE(decayed_weight, 0), COALESCE(decayed_sum, 0), COALESCE(decayed_sq, 0), COALESCE(decayed_at, NOW())`
+
+// ReputationSums — затухающие суммы домена, прочитанные из ReputationColumns.
+type ReputationSums struct {
+	W, S, Q float64
+	At      time.Time
+}
+
+// Dest — указатели для rows.Scan в порядке ReputationColumns.
+func (r *ReputationSums) Dest() []interface{} {
+	return []interface{}{&r.W, &r.S, &r.Q, &r.At}
+}
+
+// Reputation — сглаженная оценка на текущий момент.
+func (r ReputationSums) Reputation() DomainReputation {
+	return reputationFromSums(r.W, r.S, r.Q, r.At, time.Now())
+}
+
+// LookupDomainScore возвращает сглаженную оценку домена из domain_stats.
+// Оценка платформы целиком (t.me, youtube.com) ничего не говорит о
+// конкретном издателе, поэтому для платформ ok = false.
+func LookupDomainScore(domain string) (score float64, total int, ok bool) {
+	if database.DB == nil || domain == "" || IsPlatform(domain) {
+		return 0, 0, false
+	}
+	var sums ReputationSums
+	err := database.DB.QueryRow(`
+		SELECT total_analyses, `+ReputationColumns+` FROM domain_stats WHERE domain = $1
+	`, domain).Scan(append([]interface{}{&total}, sums.Dest()...)...)
+	return sums.Reputation().Score, total, err == nil
+}
+
+type domainScore struct {
+	Score      float64 // сглаженная репутация
+	Total      int
+	Reputation DomainReputation
+}
+
+// LookupDomainScores — оценки сразу нескольких доменов одним запросом
+// (без платформ, как и LookupDomainScore).
+func LookupDomainScores(domains []string) map[string]domainScore {
+	scores := map[string]domainScore{}
+	if database.DB == nil || len(domains) == 0 {
+		return scores
+	}
+	rows, err := database.DB.Query(`
+		SELECT domain, total_analyses, `+ReputationColumns+` FROM domain_stats WHERE domain = ANY($1)
+	`, pq.Array(domains))
+	if err != nil {
+		log.Printf("[DOMAIN] ⚠ Ошибка чтения stats: %v", err)
+		return scores
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d string
+		var ds domainScore
+		var sums ReputationSums
+		if rows.Scan(append([]interface{}{&d, &ds.Total}, sums.Dest()...)...) == nil && !IsPlatform(d) {
+			ds.Reputation = sums.Reputation()
+			ds.Score = ds.Reputation.Score
+			scores[d] = ds
+		}
+	}
+	return scores
+}
+
+// ── Платформы и их издатели ──────────────────────────────────────────────────
+
+// DomainChild — издатель на платформе.
+type DomainChild struct {
+	Domain        string  `json:"domain"`
+	TotalAnalyses int     `json:"total_analyses"`
+	Score         float64 `json:"score"`
+	Verdict       string  `json:"verdict"`
+}
+
+// PlatformAggregate — сводка по платформе: её собственная строка (анализы
+// страниц без издателя) и все издатели. Затухающие суммы приведены к
+// текущему моменту; children — первые limit издателей по числу анализов.
+func PlatformAggregate(platform string, limit int) (agg ReputationSums, total, sumScores int, children []DomainChild, publishers int, err error) {
+	rows, err := database.DB.Query(`
+		SELECT domain, total_analyses, sum_scores, `+ReputationColumns+`
+		FROM domain_stats WHERE domain = $1 OR domain LIKE $1 || '/%'
+		ORDER BY total_analyses DESC
+	`, platform)
+	if err != nil {
+		return agg, 0, 0, nil, 0, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	agg.At = now
+	for rows.Next() {
+		var d string
+		var n, sum int
+		var sums ReputationSums
+		if rows.Scan(append([]interface{}{&d, &n, &sum}, sums.Dest()...)...) != nil {
+			continue
+		}
+		f := decayFactor(now.Sub(sums.At))
+		agg.W += sums.W * f
+		agg.S += sums.S * f
+		agg.Q += sums.Q * f
+		total += n
+		sumScores += sum
+		if d == platform {
+			continue
+		}
+		publishers++
+		if len(children) < limit {
+			score := sums.Reputation().Score
+			children = append(children, DomainChild{Domain: d, TotalAnalyses: n, Score: score, Verdict: DomainVerdict(score)})
+		}
+	}
+	return agg, total, sumScores, children, publishers, rows.Err()
+}
+
+// ── Миграция ключей ──────────────────────────────────────────────────────────
+
+// MigrateDomainEntities переносит статистику, историю, списки и ручные
+// решения, записанные до сведения поддоменов к регистрируемому домену
+// (news.example.co.uk → example.co.uk, m.facebook.com → facebook.com).
+// Старые строки платформ без издателя остаются строками самих платформ.
+func MigrateDomainEntities() {
+	if database.DB == nil {
+		return
+	}
+	moved := 0
+	for _, table := range []string{"domain_stats", "domain_stats_history", "domain_lists", "domain_overrides"} {
+		rows, err := database.DB.Query(`SELECT DISTINCT domain FROM ` + table)
+		if err != nil {
+			log.Printf("[DOMAIN] ⚠ Ошибка чтения %s для миграции: %v", table, err)
+			continue
+		}
+		var stale []string
+		for rows.Next() {
+			var d string
+			if rows.Scan(&d) == nil && NormalizeDomain("https://"+d) != d {
+				stale = append(stale, d)
+			}
+		}
+		rows.Close()
+		for _, d := range stale {
+			if err := migrateDomainKey(table, d, NormalizeDomain("https://"+d)); err != nil {
+				log.Printf("[DOMAIN] ⚠ Ошибка переноса %s → %s (%s): %v", d, NormalizeDomain("https://"+d), table, err)
+				continue
+			}
+			moved++
+		}
+	}
+	if moved > 0 {
+		log.Printf("[DOMAIN] 🔀 Перенесено записей на регистрируемые домены: %d", moved)
+	}
+}
+
+// domainKeyMerges — как слить строку старого ключа $1 в строку нового $2.
+var domainKeyMerges = map[string]string{
+	"domain_stats": `
+		INSERT INTO domain_stats (domain, total_analyses, sum_scores, avg_score, last_analyzed_at,
+			rated_analyses, sum_rating, avg_rating, coordinated_clusters, coordinated_at,
+			decayed_weight, decayed_sum, decayed_sq, decayed_at)
+		SELECT $2, total_analyses, sum_scores, avg_score, last_analyzed_at,
+			rated_analyses, sum_rating, avg_rating, coordinated_clusters, coordinated_at,
+			COALESCE(decayed_weight, 0) * ` + migrateDecaySQL + `,
+			COALESCE(decayed_sum, 0) * ` + migrateDecaySQL + `,
+			COALESCE(decayed_sq, 0) * ` + migrateDecaySQL + `, NOW()
+		FROM domain_stats WHERE domain = $1
+		ON CONFLICT (domain) DO UPDATE SET
+			total_analyses       = domain_stats.total_analyses + EXCLUDED.total_analyses,
+			sum_scores           = domain_stats.sum_scores + EXCLUDED.sum_scores,
+			avg_score            = (domain_stats.sum_scores + EXCLUDED.sum_scores)::float
+				/ NULLIF(domain_stats.total_analyses + EXCLUDED.total_analyses, 0),
+			last_analyzed_at     = GREATEST(domain_stats.last_analyzed_at, EXCLUDED.last_analyzed_at),
+			rated_analyses       = domain_stats.rated_analyses + EXCLUDED.rated_analyses,
+			sum_rating           = domain_stats.sum_rating + EXCLUDED.sum_rating,
+			avg_rating           = (domain_stats.sum_rating + EXCLUDED.sum_rating)::float
+				/ NULLIF(domain_stats.rated_analyses + EXCLUDED.rated_analyses, 0),
+			coordinated_clusters = COALESCE(domain_stats.coordinated_clusters, 0) + COALESCE(EXCLUDED.coordinated_clusters, 0),
+			coordinated_at       = GREATEST(domain_stats.coordinated_at, EXCLUDED.coordinated_at),
+			decayed_weight       = COALESCE(domain_stats.decayed_weight, 0) * ` + migrateDecaySQL + ` + EXCLUDED.decayed_weight,
+			decayed_sum          = COALESCE(domain_stats.decayed_sum, 0) * ` + migrateDecaySQL + ` + EXCLUDED.decayed_sum,
+			decayed_sq           = COALESCE(domain_stats.decayed_sq, 0) * ` + migrateDecaySQL + ` + EXCLUDED.decayed_sq,
+			decayed_at           = NOW()`,
+	"domain_stats_history": `
+		INSERT INTO domain_stats_history (domain, day, analyses, sum_scores, avg_score, reputation, reputation_low, reputation_high)
+		SELECT $2, day, analyses, sum_scores, avg_score, reputation, reputation_low, reputation_high
+		FROM domain_stats_history WHERE domain = $1
+		ON CONFLICT (domain, day) DO UPDATE SET
+			analyses   = domain_stats_history.analyses + EXCLUDED.analyses,
+			sum_scores = domain_stats_history.sum_scores + EXCLUDED.sum_scores,
+			avg_score  = (domain_stats_history.sum_scores + EXCLUDED.sum_scores)::float
+				/ NULLIF(domain_stats_history.analyses + EXCLUDED.analyses, 0)`,
+	"domain_lists": `
+		INSERT INTO domain_lists (domain, category, source, notes, imported_at)
+		SELECT $2, category, source, notes, imported_at FROM domain_lists WHERE domain = $1
+		ON CONFLICT (domain, source) DO NOTHING`,
+	"domain_overrides": `
+		INSERT INTO domain_overrides (domain, verdict, category, reason, created_at, updated_at)
+		SELECT $2, verdict, category, reason, created_at, updated_at FROM domain_overrides WHERE domain = $1
+		ON CONFLICT (domain) DO NOTHING`,
+}
+
+// migrateDecaySQL — как decaySQL, но полураспад в секундах передаётся в $3.
+const migrateDecaySQL = `power(0.5, EXTRACT(EPOCH FROM NOW() - COALESCE(domain_stats.decayed_at, NOW())) / $3::FLOAT)`
+
+func migrateDomainKey(table, from, to string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	args := []interface{}{from, to}
+	if table == "domain_stats" {
+		args = append(args, reputation.HalfLife.Seconds())
+	}
+	if _, err := tx.Exec(domainKeyMerges[table], args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM `+table+` WHERE domain = $1`, from); err != nil {
+		return err
 	}
+	return tx.Commit()
 }
@@ -0,0 +1,85 @@
+package services
+
+import "testing"
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		// обычные сайты — регистрируемый домен
+		{"https://www.Point.md:443/ru/novosti/1", "point.md"},
+		{"https://news.example.co.uk/a", "example.co.uk"},
+		{"https://user.github.io/post", "user.github.io"},
+		{"http://127.0.0.1:8080/x", "127.0.0.1"},
+		{"http://localhost:3000/x", "localhost"},
+		{"not a url", "not a url"},
+
+		// Telegram
+		{"https://t.me/durov/123", "t.me/durov"},
+		{"https://t.me/s/Durov", "t.me/durov"},
+		{"https://telegram.me/durov", "t.me/durov"},
+		{"https://t.me/+AbCdEf", "t.me"},
+		{"https://t.me/joinchat/AbCdEf", "t.me"},
+		{"https://t.me/c/1234/56", "t.me"},
+
+		// Facebook
+		{"https://m.facebook.com/SomePage/posts/1", "facebook.com/somepage"},
+		{"https://www.facebook.com/profile.php?id=100012345", "facebook.com/100012345"},
+		{"https://fb.com/groups/Moldova/permalink/1", "facebook.com/groups/moldova"},
+		{"https://facebook.com/people/Ion-Popescu/100012345", "facebook.com/100012345"},
+		{"https://facebook.com/watch?v=1", "facebook.com"},
+		{"https://facebook.com/share/p/abc", "facebook.com"},
+
+		// YouTube
+		{"https://www.youtube.com/@Handle/videos", "youtube.com/@handle"},
+		{"https://youtube.com/channel/UCaBcD", "youtube.com/channel/UCaBcD"},
+		{"https://m.youtube.com/user/Name", "youtube.com/user/name"},
+		{"https://youtube.com/watch?v=xyz", "youtube.com"},
+		{"https://youtu.be/xyz", "youtube.com"},
+
+		// X / Twitter, Medium, Instagram, TikTok, VK
+		{"https://twitter.com/Someone/status/1", "x.com/someone"},
+		{"https://x.com/search?q=moldova", "x.com"},
+		{"https://medium.com/@Writer/story-1", "medium.com/@writer"},
+		{"https://writer.medium.com/story-1", "medium.com/@writer"},
+		{"https://medium.com/p/abc", "medium.com"},
+		{"https://www.instagram.com/account/", "instagram.com/account"},
+		{"https://instagram.com/stories/account/1", "instagram.com/account"},
+		{"https://instagram.com/p/abc", "instagram.com"},
+		{"https://www.tiktok.com/@user/video/1", "tiktok.com/@user"},
+		{"https://tiktok.com/discover", "tiktok.com"},
+		{"https://vk.com/club1", "vk.com/club1"},
+		{"https://vk.com/feed", "vk.com"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeDomain(tt.url); got != tt.want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPlatformHierarchy(t *testing.T) {
+	tests := []struct {
+		domain   string
+		platform bool
+		parent   string
+	}{
+		{"t.me", true, ""},
+		{"t.me/durov", false, "t.me"},
+		{"facebook.com/groups/moldova", false, "facebook.com"},
+		{"x.com", true, ""},
+		{"twitter.com", false, ""}, // алиас, статистика ведётся на x.com
+		{"youtube.com", true, ""},
+		{"youtu.be", false, ""},
+		{"point.md", false, ""},
+	}
+	for _, tt := range tests {
+		if got := IsPlatform(tt.domain); got != tt.platform {
+			t.Errorf("IsPlatform(%q) = %v, want %v", tt.domain, got, tt.platform)
+		}
+		if got := ParentDomain(tt.domain); got != tt.parent {
+			t.Errorf("ParentDomain(%q) = %q, want %q", tt.domain, got, tt.parent)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text-analyzer/database"
+	"text-analyzer/models"
+
+	"github.com/lib/pq"
+)
+
+// Курируемые списки доменов (пропаганда, сатира, государственные СМИ,
+// проверенные издания) и ручные решения администратора. И то и другое
+// важнее наших средних оценок: сначала ручное решение, затем списки,
+// и только потом domain_stats.
+
+// DomainCategory — категория курируемого списка.
+type DomainCategory struct {
+	Label   string // подпись для пользователя
+	Verdict string // вердикт, который категория задаёт домену
+	rank    int    // при попадании в несколько списков берётся меньший
+}
+
+var DomainCategories = map[string]DomainCategory{
+	"propaganda":  {Label: "пропаганда", Verdict: TierUnreliable, rank: 0},
+	"satire":      {Label: "сатира", Verdict: TierDoubtful, rank: 1},
+	"state_media": {Label: "государственное СМИ", Verdict: TierDoubtful, rank: 2},
+	"verified":    {Label: "проверенное издание", Verdict: TierReliable, rank: 3},
+}
+
+// ErrOverrideNotFound — для домена нет ручного решения.
+var ErrOverrideNotFound = errors.New("ручное решение для домена не найдено")
+
+// normalizeListDomain приводит домен из списка к виду domain_stats:
+// принимает и «example.com», и полный URL.
+func normalizeListDomain(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	return NormalizeDomain(raw)
+}
+
+// ── Импорт ───────────────────────────────────────────────────────────────────
+
+// ParseDomainListCSV читает CSV с заголовком domain,category[,source][,notes].
+// Пустой source заменяется на defaultSource.
+func ParseDomainListCSV(r io.Reader, defaultSource string) ([]models.DomainListEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("пустой CSV: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["domain"]; !ok {
+		return nil, fmt.Errorf("в заголовке CSV нет столбца domain")
+	}
+	if _, ok := cols["category"]; !ok {
+		return nil, fmt.Errorf("в заголовке CSV нет столбца category")
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var entries []models.DomainListEntry
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка CSV: %w", err)
+		}
+		e := models.DomainListEntry{
+			Domain:   field(rec, "domain"),
+			Category: field(rec, "category"),
+			Source:   field(rec, "source"),
+			Notes:    field(rec, "notes"),
+		}
+		if e.Source == "" {
+			e.Source = defaultSource
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ParseDomainListJSON принимает массив записей или объект
+// {"source": "...", "entries": [...]}; source объекта — для записей без своего.
+func ParseDomainListJSON(data []byte, defaultSource string) ([]models.DomainListEntry, error) {
+	var entries []models.DomainListEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var doc struct {
+			Source  string                   `json:"source"`
+			Entries []models.DomainListEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("неверный JSON: %w", err)
+		}
+		entries = doc.Entries
+		if doc.Source != "" {
+			defaultSource = doc.Source
+		}
+	}
+	for i := range entries {
+		if entries[i].Source == "" {
+			entries[i].Source = defaultSource
+		}
+	}
+	return entries, nil
+}
+
+// ImportDomainList сохраняет записи списков. replace — сначала удалить
+// прежние записи тех источников, что есть в импорте (список обновился
+// целиком; при ошибке БД импорт отменяется целиком). Возвращает число
+// сохранённых записей и ошибки по строкам.
+func ImportDomainList(entries []models.DomainListEntry, replace bool) (int, []string) {
+	if database.DB == nil {
+		return 0, []string{"БД недоступна"}
+	}
+	var errs []string
+	valid := make([]models.DomainListEntry, 0, len(entries))
+	sources := map[string]bool{}
+	for i, e := range entries {
+		e.Domain = normalizeListDomain(e.Domain)
+		e.Category = strings.ToLower(strings.TrimSpace(e.Category))
+		e.Source = strings.TrimSpace(e.Source)
+		switch {
+		case e.Domain == "":
+			errs = append(errs, fmt.Sprintf("запись %d: пустой домен", i+1))
+		case e.Source == "":
+			errs = append(errs, fmt.Sprintf("запись %d (%s): не указан source", i+1, e.Domain))
+		default:
+			if _, ok := DomainCategories[e.Category]; !ok {
+				errs = append(errs, fmt.Sprintf("запись %d (%s): неизвестная категория %q", i+1, e.Domain, e.Category))
+				continue
+			}
+			valid = append(valid, e)
+			sources[e.Source] = true
+		}
+	}
+
+	// При замене удаление и вставка идут одной транзакцией: при первой
+	// ошибке прежние списки остаются как были.
+	var tx *sql.Tx
+	exec := database.DB.Exec
+	if replace && len(sources) > 0 {
+		var err error
+		if tx, err = database.DB.Begin(); err != nil {
+			return 0, append(errs, "не удалось начать транзакцию: "+err.Error())
+		}
+		defer tx.Rollback()
+		exec = tx.Exec
+
+		list := make([]string, 0, len(sources))
+		for s := range sources {
+			list = append(list, s)
+		}
+		if _, err := exec(`DELETE FROM domain_lists WHERE source = ANY($1)`, pq.Array(list)); err != nil {
+			return 0, append(errs, "не удалось очистить прежние списки: "+err.Error())
+		}
+	}
+
+	saved := 0
+	for _, e := range valid {
+		_, err := exec(`
+			INSERT INTO domain_lists (domain, category, source, notes)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+			ON CONFLICT (domain, source) DO UPDATE SET
+				category    = EXCLUDED.category,
+				notes       = EXCLUDED.notes,
+				imported_at = NOW()
+		`, e.Domain, e.Category, e.Source, e.Notes)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.Domain, err))
+			if tx != nil {
+				return 0, append(errs, "импорт отменён, прежние списки сохранены")
+			}
+			continue
+		}
+		saved++
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return 0, append(errs, "не удалось сохранить списки: "+err.Error())
+		}
+	}
+	log.Printf("[DOMAIN] 📋 Импортировано записей списков: %d (ошибок: %d)", saved, len(errs))
+	return saved, errs
+}
+
+// ── Ручные решения ───────────────────────────────────────────────────────────
+
+// SetDomainOverride закрепляет за доменом вердикт и/или категорию.
+func SetDomainOverride(o models.DomainOverride) (*models.DomainOverride, error) {
+	if database.DB == nil {
+		return nil, fmt.Errorf("БД недоступна")
+	}
+	o.Domain = normalizeListDomain(o.Domain)
+	o.Category = strings.ToLower(strings.TrimSpace(o.Category))
+	o.Reason = strings.TrimSpace(o.Reason)
+	switch {
+	case o.Domain == "":
+		return nil, fmt.Errorf("не указан домен")
+	case o.Reason == "":
+		return nil, fmt.Errorf("не указана причина")
+	case o.Verdict == "" && o.Category == "":
+		return nil, fmt.Errorf("нужен verdict или category")
+	case o.Verdict != "" && o.Verdict != TierReliable && o.Verdict != TierDoubtful && o.Verdict != TierUnreliable:
+		return nil, fmt.Errorf("verdict — %s, %s или %s", TierReliable, TierDoubtful, TierUnreliable)
+	}
+	if _, ok := DomainCategories[o.Category]; o.Category != "" && !ok {
+		return nil, fmt.Errorf("неизвестная категория %q", o.Category)
+	}
+	err := database.DB.QueryRow(`
+		INSERT INTO domain_overrides (domain, verdict, category, reason)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		ON CONFLICT (domain) DO UPDATE SET
+			verdict    = EXCLUDED.verdict,
+			category   = EXCLUDED.category,
+			reason     = EXCLUDED.reason,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, o.Domain, o.Verdict, o.Category, o.Reason).Scan(&o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DOMAIN] 📌 Ручное решение для %s: %s %s — %s", o.Domain, o.Verdict, o.Category, o.Reason)
+	return &o, nil
+}
+
+// DeleteDomainOverride снимает ручное решение.
+func DeleteDomainOverride(domain string) error {
+	if database.DB == nil {
+		return fmt.Errorf("БД недоступна")
+	}
+	res, err := database.DB.Exec(`DELETE FROM domain_overrides WHERE domain = $1`, normalizeListDomain(domain))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOverrideNotFound
+	}
+	return nil
+}
+
+// ListDomainOverrides — все ручные решения, новые первыми.
+func ListDomainOverrides() ([]models.DomainOverride, error) {
+	if database.DB == nil {
+		return nil, fmt.Errorf("БД недоступна")
+	}
+	rows, err := database.DB.Query(`
+		SELECT domain, COALESCE(verdict, ''), COALESCE(category, ''), reason, updated_at
+		FROM domain_overrides ORDER BY updated_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.DomainOverride{}
+	for rows.Next() {
+		var o models.DomainOverride
+		if rows.Scan(&o.Domain, &o.Verdict, &o.Category, &o.Reason, &o.UpdatedAt) == nil {
+			list = append(list, o)
+		}
+	}
+	return list, rows.Err()
+}
+
+// ── Поиск ────────────────────────────────────────────────────────────────────
+
+// LookupCurations — списки и ручные решения для доменов одним проходом.
+// В карте только домены, о которых что-то известно.
+func LookupCurations(domains []string) map[string]*models.DomainCuration {
+	out := map[string]*models.DomainCuration{}
+	if database.DB == nil || len(domains) == 0 {
+		return out
+	}
+	get := func(d string) *models.DomainCuration {
+		if out[d] == nil {
+			out[d] = &models.DomainCuration{Domain: d}
+		}
+		return out[d]
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT domain, category, source, COALESCE(notes, '')
+		FROM domain_lists WHERE domain = ANY($1)
+		ORDER BY domain, source
+	`, pq.Array(domains))
+	if err != nil {
+		log.Printf("[DOMAIN] ⚠ Ошибка чтения списков: %v", err)
+		return out
+	}
+	for rows.Next() {
+		var e models.DomainListEntry
+		if rows.Scan(&e.Domain, &e.Category, &e.Source, &e.Notes) == nil {
+			c := get(e.Domain)
+			c.Lists = append(c.Lists, e)
+		}
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query(`
+		SELECT domain, COALESCE(verdict, ''), COALESCE(category, ''), reason, updated_at
+		FROM domain_overrides WHERE domain = ANY($1)
+	`, pq.Array(domains))
+	if err != nil {
+		log.Printf("[DOMAIN] ⚠ Ошибка чтения ручных решений: %v", err)
+	} else {
+		for rows.Next() {
+			var o models.DomainOverride
+			if rows.Scan(&o.Domain, &o.Verdict, &o.Category, &o.Reason, &o.UpdatedAt) == nil {
+				get(o.Domain).Override = &o
+			}
+		}
+		rows.Close()
+	}
+
+	for _, c := range out {
+		resolveCuration(c)
+	}
+	return out
+}
+
+// LookupCuration — то же для одного домена; nil, если о нём ничего не известно.
+func LookupCuration(domain string) *models.DomainCuration {
+	if domain == "" {
+		return nil
+	}
+	return LookupCurations([]string{domain})[domain]
+}
+
+// CuratedDomains — все домены из списков и ручных решений.
+func CuratedDomains() ([]string, error) {
+	if database.DB == nil {
+		return nil, nil
+	}
+	rows, err := database.DB.Query(`SELECT domain FROM domain_lists UNION SELECT domain FROM domain_overrides`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var domains []string
+	for rows.Next() {
+		var d string
+		if rows.Scan(&d) == nil {
+			domains = append(domains, d)
+		}
+	}
+	return domains, rows.Err()
+}
+
+// resolveCuration выбирает категорию и вердикт: ручное решение важнее
+// списков, из нескольких списков — самая строгая категория.
+func resolveCuration(c *models.DomainCuration) {
+	best := -1
+	for _, e := range c.Lists {
+		if cat, ok := DomainCategories[e.Category]; ok && (best < 0 || cat.rank < best) {
+			best, c.Category = cat.rank, e.Category
+		}
+	}
+	if c.Category != "" {
+		c.Verdict, c.VerdictSource = DomainCategories[c.Category].Verdict, "curated"
+	}
+	if o := c.Override; o != nil {
+		if o.Category != "" {
+			c.Category = o.Category
+			c.Verdict = DomainCategories[o.Category].Verdict
+		}
+		if o.Verdict != "" {
+			c.Verdict = o.Verdict
+		}
+		c.VerdictSource = "override"
+	}
+	c.CategoryLabel = DomainCategories[c.Category].Label
+}
+
+// curatedTier — уровень доверия к источнику поиска по спискам и ручным решениям.
+func curatedTier(c *models.DomainCuration) string {
+	if c == nil || c.Verdict == "" {
+		return ""
+	}
+	if c.Category == "verified" && c.Verdict == TierReliable {
+		return TierTrusted
+	}
+	return c.Verdict
+}
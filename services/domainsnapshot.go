@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+)
+
+// Вердикты доменов для клиентов, которые помечают все ссылки страницы:
+// пакетный поиск (LookupDomainBadges) и снимок всех известных доменов
+// (DomainSnapshotter). Снимок пересобирается раз в интервал; если вердикты
+// изменились, он получает новую версию, а разница с прошлой версией
+// сохраняется, чтобы клиент мог догрузить только изменения.
+
+// DomainBadge — краткие сведения о домене для значка у ссылки.
+type DomainBadge struct {
+	Domain        string  `json:"domain"`
+	Verdict       string  `json:"verdict"`
+	VerdictSource string  `json:"verdict_source"` // override | curated | computed
+	Category      string  `json:"category,omitempty"`
+	Score         float64 `json:"score,omitempty"`
+	Low           float64 `json:"low,omitempty"`
+	High          float64 `json:"high,omitempty"`
+	Confidence    string  `json:"confidence,omitempty"`
+	Analyses      int     `json:"analyses,omitempty"`
+}
+
+// domainBadge собирает значок из оценки и курируемых сведений;
+// ok = false, если о домене ничего не известно.
+func domainBadge(domain string, ds *domainScore, cur *models.DomainCuration) (DomainBadge, bool) {
+	b := DomainBadge{Domain: domain}
+	if ds != nil {
+		b.Verdict, b.VerdictSource = DomainVerdict(ds.Score), "computed"
+		b.Score, b.Low, b.High = ds.Score, ds.Reputation.Low, ds.Reputation.High
+		b.Confidence, b.Analyses = ds.Reputation.Confidence, ds.Total
+	}
+	if cur != nil && cur.Verdict != "" {
+		b.Verdict, b.VerdictSource, b.Category = cur.Verdict, cur.VerdictSource, cur.Category
+	}
+	return b, b.Verdict != ""
+}
+
+// LookupDomainBadges — значки сразу для многих доменов (или URL). Ключ —
+// строка из запроса; неизвестные домены в карту не попадают.
+func LookupDomainBadges(inputs []string) map[string]DomainBadge {
+	normalized := make([]string, len(inputs))
+	for i, in := range inputs {
+		normalized[i] = normalizeListDomain(in)
+	}
+	scores := LookupDomainScores(normalized)
+	curations := LookupCurations(normalized)
+
+	out := make(map[string]DomainBadge, len(inputs))
+	for i, in := range inputs {
+		d := normalized[i]
+		if d == "" {
+			continue
+		}
+		var ds *domainScore
+		if s, ok := scores[d]; ok {
+			ds = &s
+		}
+		if b, ok := domainBadge(d, ds, curations[d]); ok {
+			out[in] = b
+		}
+	}
+	return out
+}
+
+// ── Снимок ───────────────────────────────────────────────────────────────────
+
+// DomainSnapshot — вердикты доменов на момент версии Version. При
+// инкрементальном ответе (Full = false) Domains содержит только изменившиеся
+// с версии Since домены, а Removed — пропавшие из снимка.
+type DomainSnapshot struct {
+	Version     int64             `json:"version"`
+	Since       int64             `json:"since,omitempty"`
+	Full        bool              `json:"full"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Domains     map[string]string `json:"domains"` // домен → вердикт
+	Removed     []string          `json:"removed,omitempty"`
+}
+
+type snapshotDiff struct {
+	from    int64 // версия, от которой отсчитаны изменения
+	changed map[string]string
+	removed []string
+}
+
+const snapshotMaxDiffs = 288 // сутки при интервале 5 минут
+
+// DomainSnapshotter периодически пересобирает снимок вердиктов доменов.
+type DomainSnapshotter struct {
+	interval time.Duration
+	build    sync.Mutex // одна пересборка за раз
+
+	mu      sync.RWMutex
+	version int64
+	at      time.Time
+	domains map[string]string
+	full    []byte // полный снимок, JSON
+	fullGz  []byte // он же в gzip
+	diffs   []snapshotDiff
+}
+
+func NewDomainSnapshotter(interval time.Duration) *DomainSnapshotter {
+	return &DomainSnapshotter{interval: interval, domains: map[string]string{}}
+}
+
+// Interval — как часто пересобирается снимок.
+func (s *DomainSnapshotter) Interval() time.Duration { return s.interval }
+
+// Enabled — снимок собирается: задан интервал и есть БД.
+func (s *DomainSnapshotter) Enabled() bool {
+	return s != nil && s.interval > 0 && database.DB != nil
+}
+
+func (s *DomainSnapshotter) Start() {
+	if !s.Enabled() {
+		return
+	}
+	log.Printf("[DOMAIN] 📦 Снимок вердиктов доменов пересобирается каждые %v", s.interval)
+	go func() {
+		s.Rebuild()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.Rebuild()
+		}
+	}()
+}
+
+// Rebuild пересобирает снимок; версия меняется, только если изменились вердикты.
+func (s *DomainSnapshotter) Rebuild() {
+	if !s.Enabled() {
+		return
+	}
+	s.build.Lock()
+	defer s.build.Unlock()
+
+	domains, err := loadSnapshotDomains()
+	if err != nil {
+		log.Printf("[DOMAIN] ⚠ Ошибка сборки снимка доменов: %v", err)
+		return
+	}
+
+	s.mu.RLock()
+	prev, version := s.domains, s.version
+	s.mu.RUnlock()
+
+	diff := snapshotDiff{from: version, changed: map[string]string{}}
+	for d, v := range domains {
+		if prev[d] != v {
+			diff.changed[d] = v
+		}
+	}
+	for d := range prev {
+		if _, ok := domains[d]; !ok {
+			diff.removed = append(diff.removed, d)
+		}
+	}
+	if version != 0 && len(diff.changed) == 0 && len(diff.removed) == 0 {
+		return
+	}
+
+	// Версия — время сборки в секундах: она растёт и после перезапуска
+	now := time.Now()
+	next := now.Unix()
+	if next <= version {
+		next = version + 1
+	}
+	sort.Strings(diff.removed)
+
+	snap := DomainSnapshot{Version: next, Full: true, GeneratedAt: now, Domains: domains}
+	raw, _ := json.Marshal(snap)
+	gz := GzipBytes(raw)
+
+	s.mu.Lock()
+	if version != 0 {
+		s.diffs = append(s.diffs, diff)
+		if len(s.diffs) > snapshotMaxDiffs {
+			s.diffs = s.diffs[len(s.diffs)-snapshotMaxDiffs:]
+		}
+	}
+	s.version, s.at, s.domains, s.full, s.fullGz = next, now, domains, raw, gz
+	s.mu.Unlock()
+
+	log.Printf("[DOMAIN] 📦 Снимок доменов v%d: %d доменов (изменено %d, удалено %d), %d байт в gzip",
+		next, len(domains), len(diff.changed), len(diff.removed), len(gz))
+}
+
+// Full — полный снимок в JSON и gzip; version = 0 — снимок ещё не собран.
+func (s *DomainSnapshotter) Full() (version int64, raw, gz []byte) {
+	if s == nil {
+		return 0, nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version, s.full, s.fullGz
+}
+
+// Since — изменения с версии since. ok = false, если разница для этой
+// версии уже не хранится (или версия неизвестна) — клиенту нужен полный снимок.
+func (s *DomainSnapshotter) Since(since int64) (snap *DomainSnapshot, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start := -1
+	for i, d := range s.diffs {
+		if d.from == since {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+	changed := map[string]string{}
+	removed := map[string]bool{}
+	for _, d := range s.diffs[start:] {
+		for dom, v := range d.changed {
+			changed[dom] = v
+			delete(removed, dom)
+		}
+		for _, dom := range d.removed {
+			delete(changed, dom)
+			removed[dom] = true
+		}
+	}
+	snap = &DomainSnapshot{Version: s.version, Since: since, GeneratedAt: s.at, Domains: changed}
+	for dom := range removed {
+		snap.Removed = append(snap.Removed, dom)
+	}
+	sort.Strings(snap.Removed)
+	return snap, true
+}
+
+// loadSnapshotDomains — вердикты всех доменов, о которых можно судить:
+// с курируемым вердиктом или с уверенностью оценки не ниже «средней».
+// Вычисленная оценка платформы целиком в снимок не попадает.
+func loadSnapshotDomains() (map[string]string, error) {
+	rows, err := database.DB.Query(`SELECT domain, ` + ReputationColumns + ` FROM domain_stats`)
+	if err != nil {
+		return nil, err
+	}
+	domains := map[string]string{}
+	for rows.Next() {
+		var d string
+		var sums ReputationSums
+		if rows.Scan(append([]interface{}{&d}, sums.Dest()...)...) != nil {
+			continue
+		}
+		if rep := sums.Reputation(); rep.Confidence != "низкая" && !IsPlatform(d) {
+			domains[d] = DomainVerdict(rep.Score)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	curated, err := CuratedDomains()
+	if err != nil {
+		return nil, err
+	}
+	for d, c := range LookupCurations(curated) {
+		if c.Verdict != "" {
+			domains[d] = c.Verdict
+		}
+	}
+	return domains, nil
+}
+
+// GzipBytes сжимает данные для ответа с Content-Encoding: gzip.
+func GzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDomainSnapshotterSince(t *testing.T) {
+	// v100 → v200: a.md стал недостоверным, появился b.md
+	// v200 → v300: b.md пропал, c.md появился
+	// v300 → v400: b.md вернулся, a.md пропал
+	s := &DomainSnapshotter{version: 400, diffs: []snapshotDiff{
+		{from: 100, changed: map[string]string{"a.md": "недостоверный", "b.md": "надёжный"}},
+		{from: 200, changed: map[string]string{"c.md": "сомнительный"}, removed: []string{"b.md"}},
+		{from: 300, changed: map[string]string{"b.md": "сомнительный"}, removed: []string{"a.md"}},
+	}}
+	tests := []struct {
+		since   int64
+		ok      bool
+		domains map[string]string
+		removed []string
+	}{
+		{100, true, map[string]string{"b.md": "сомнительный", "c.md": "сомнительный"}, []string{"a.md"}},
+		{200, true, map[string]string{"b.md": "сомнительный", "c.md": "сомнительный"}, []string{"a.md"}},
+		{300, true, map[string]string{"b.md": "сомнительный"}, []string{"a.md"}},
+		{50, false, nil, nil},  // разница вытеснена — нужен полный снимок
+		{250, false, nil, nil}, // такой версии не было
+	}
+	for _, tt := range tests {
+		snap, ok := s.Since(tt.since)
+		if ok != tt.ok {
+			t.Errorf("Since(%d): ok = %v, want %v", tt.since, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if snap.Version != 400 || snap.Since != tt.since || snap.Full {
+			t.Errorf("Since(%d): version %d since %d full %v", tt.since, snap.Version, snap.Since, snap.Full)
+		}
+		if !reflect.DeepEqual(snap.Domains, tt.domains) {
+			t.Errorf("Since(%d): domains %v, want %v", tt.since, snap.Domains, tt.domains)
+		}
+		if !reflect.DeepEqual(snap.Removed, tt.removed) {
+			t.Errorf("Since(%d): removed %v, want %v", tt.since, snap.Removed, tt.removed)
+		}
+	}
+}
+
+func TestDomainSnapshotterEnabled(t *testing.T) {
+	// Без интервала или без БД снимок не собирается — клиенту 404, а не 503
+	var nilSnapshotter *DomainSnapshotter
+	for name, s := range map[string]*DomainSnapshotter{
+		"nil":         nilSnapshotter,
+		"no interval": NewDomainSnapshotter(0),
+		"no database": NewDomainSnapshotter(10 * time.Minute),
+	} {
+		if s.Enabled() {
+			t.Errorf("%s: snapshotter enabled", name)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+)
+
+// ── Поиск предыдущей версии ──────────────────────────────────────────────────
+
+// previousVersion — последнее известное состояние страницы до текущей загрузки.
+type previousVersion struct {
+	SnapshotID string
+	Fallback   string
+	Text       string
+}
+
+// findPreviousVersion ищет прошлую версию текста страницы: сначала по снимкам,
+// затем по тексту предыдущего анализа. Вызывать до сохранения нового снимка.
+func findPreviousVersion(pageURL string) *previousVersion {
+	if database.DB == nil {
+		return nil
+	}
+
+	var snapshotID string
+	err := database.DB.QueryRow(`
+		SELECT id FROM snapshots WHERE url = $1
+		ORDER BY COALESCE(last_seen_at, fetched_at) DESC LIMIT 1
+	`, pageURL).Scan(&snapshotID)
+	if err == nil {
+		if snap, err := GetSnapshot(snapshotID); err == nil {
+			return &previousVersion{SnapshotID: snapshotID, Fallback: snap.Fallback, Text: snap.Text}
+		}
+	} else if err != sql.ErrNoRows {
+		log.Printf("[EDITS] ⚠ Ошибка поиска снимков: %v", err)
+	}
+
+	var text string
+	err = database.DB.QueryRow(`
+		SELECT text FROM analysis_results WHERE url = $1 AND text IS NOT NULL
+		ORDER BY id DESC LIMIT 1
+	`, pageURL).Scan(&text)
+	if err != nil {
+		return nil
+	}
+	return &previousVersion{Text: text}
+}
+
+// ── Абзацный diff ────────────────────────────────────────────────────────────
+
+var diffSpaceRe = regexp.MustCompile(`\s+`)
+
+// Больше абзацев не сравниваем — LCS квадратичен.
+const maxDiffParagraphs = 2000
+
+func splitParagraphs(text string) []string {
+	var paras []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(diffSpaceRe.ReplaceAllString(line, " "))
+		if line != "" {
+			paras = append(paras, line)
+		}
+	}
+	if len(paras) > maxDiffParagraphs {
+		paras = paras[:maxDiffParagraphs]
+	}
+	return paras
+}
+
+// diffParagraphs сравнивает два текста по абзацам (LCS). Соседние удаление
+// и добавление объединяются в «изменение».
+func diffParagraphs(oldText, newText string) []models.ParagraphChange {
+	a, b := splitParagraphs(oldText), splitParagraphs(newText)
+
+	// lcs[i][j] — длина LCS для a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var changes []models.ParagraphChange
+	var removed, added []string
+	pos := 0
+	flush := func() {
+		for len(removed) > 0 && len(added) > 0 {
+			changes = append(changes, models.ParagraphChange{Type: "changed", Position: pos, Old: removed[0], New: added[0]})
+			removed, added = removed[1:], added[1:]
+		}
+		for _, r := range removed {
+			changes = append(changes, models.ParagraphChange{Type: "removed", Position: pos, Old: r})
+		}
+		for _, ad := range added {
+			changes = append(changes, models.ParagraphChange{Type: "added", Position: pos, New: ad})
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			i++
+			j++
+			pos = j
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, b[j])
+			j++
+		default:
+			removed = append(removed, a[i])
+			i++
+		}
+	}
+	flush()
+	return changes
+}
+
+// ── Запись событий ───────────────────────────────────────────────────────────
+
+// DetectEdit сравнивает новую версию страницы с предыдущей и, если текст
+// изменился, записывает событие правки. prev — результат findPreviousVersion.
+// Версии, полученные через фолбек (архив, AMP, метаданные), не сравниваются:
+// их текст отличается от оригинала и без всякой правки.
+func DetectEdit(pageURL string, prev *previousVersion, snapshotID string, fetched *FetchResult) *models.EditEvent {
+	if prev == nil || fetched == nil || fetched.Fallback != "" || prev.Fallback != "" {
+		return nil
+	}
+	if prev.SnapshotID != "" && prev.SnapshotID == snapshotID {
+		return nil
+	}
+	changes := diffParagraphs(prev.Text, fetched.Text)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	event := &models.EditEvent{
+		URL:                pageURL,
+		PreviousSnapshotID: prev.SnapshotID,
+		SnapshotID:         snapshotID,
+		DetectedAt:         time.Now().UTC(),
+		Changes:            changes,
+	}
+	for _, c := range changes {
+		switch c.Type {
+		case "added":
+			event.Added++
+		case "removed":
+			event.Removed++
+		case "changed":
+			event.Changed++
+		}
+	}
+
+	log.Printf("[EDITS] ✏️ %s изменена: +%d −%d ~%d абзацев", pageURL, event.Added, event.Removed, event.Changed)
+
+	if database.DB != nil {
+		diffJSON, _ := json.Marshal(changes)
+		err := database.DB.QueryRow(`
+			INSERT INTO edit_events (url, previous_snapshot_id, snapshot_id, added, removed, changed, diff)
+			VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7)
+			RETURNING id
+		`, pageURL, event.PreviousSnapshotID, event.SnapshotID, event.Added, event.Removed, event.Changed, diffJSON).Scan(&event.ID)
+		if err != nil {
+			log.Printf("[EDITS] ⚠ Ошибка записи события правки: %v", err)
+		}
+	}
+	return event
+}
+
+// GetEditHistory возвращает историю правок страницы, новые сверху.
+func GetEditHistory(pageURL string, limit int) ([]models.EditEvent, error) {
+	events := []models.EditEvent{}
+	if database.DB == nil {
+		return events, nil
+	}
+	rows, err := database.DB.Query(`
+		SELECT id, url, COALESCE(previous_snapshot_id, ''), COALESCE(snapshot_id, ''),
+		       added, removed, changed, diff, detected_at
+		FROM edit_events WHERE url = $1
+		ORDER BY detected_at DESC LIMIT $2
+	`, pageURL, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.EditEvent
+		var diff []byte
+		if err := rows.Scan(&e.ID, &e.URL, &e.PreviousSnapshotID, &e.SnapshotID,
+			&e.Added, &e.Removed, &e.Changed, &diff, &e.DetectedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(diff, &e.Changes)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ── Периодическая перепроверка ───────────────────────────────────────────────
+
+// EditWatcher периодически перезагружает страницы с низкой оценкой,
+// чтобы поймать тихие правки без повторного анализа.
+type EditWatcher struct {
+	fetcher  *ContentFetcher
+	interval time.Duration
+	maxScore int
+	maxAge   time.Duration
+	batch    int
+}
+
+func NewEditWatcher(fetcher *ContentFetcher, interval time.Duration, maxScore int, maxAge time.Duration, batch int) *EditWatcher {
+	return &EditWatcher{fetcher: fetcher, interval: interval, maxScore: maxScore, maxAge: maxAge, batch: batch}
+}
+
+// Start запускает фоновую перепроверку (ничего не делает, если интервал не задан).
+func (w *EditWatcher) Start() {
+	if w.interval <= 0 || database.DB == nil {
+		return
+	}
+	log.Printf("[EDITS] 👁 Перепроверка страниц с оценкой ≤%d каждые %v", w.maxScore, w.interval)
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.runOnce()
+		}
+	}()
+}
+
+func (w *EditWatcher) runOnce() {
+	rows, err := database.DB.Query(`
+		SELECT url FROM analysis_results
+		WHERE url <> '' AND (result->>'credibility_score')::int <= $1
+		  AND created_at > NOW() - make_interval(secs => $2)
+		GROUP BY url
+		ORDER BY MAX(created_at) DESC
+		LIMIT $3
+	`, w.maxScore, w.maxAge.Seconds(), w.batch)
+	if err != nil {
+		log.Printf("[EDITS] ⚠ Ошибка выборки URL для перепроверки: %v", err)
+		return
+	}
+	var urls []string
+	for rows.Next() {
+		var u string
+		if rows.Scan(&u) == nil {
+			urls = append(urls, u)
+		}
+	}
+	rows.Close()
+
+	edited := 0
+	for _, u := range urls {
+		fetched, err := w.fetcher.Fetch(u)
+		if err != nil {
+			log.Printf("[EDITS] ⚠ %s: %v", u, err)
+			continue
+		}
+		prev := findPreviousVersion(u)
+		snapshotID := SaveSnapshot(u, fetched)
+		if DetectEdit(u, prev, snapshotID, fetched) != nil {
+			edited++
+		}
+	}
+	log.Printf("[EDITS] ✓ Перепроверено %d страниц, изменений: %d", len(urls), edited)
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"text-analyzer/models"
 )
 
 type GoogleFactCheckClient struct {
@@ -36,70 +37,70 @@ type GoogleFactCheckResponse struct {
 	} `json:"claims"`
 }
 
-// Search queries the Google Fact Check Tools API
-// We filter strictly with parameters to find misinformation relevant to Moldova
-func (c *GoogleFactCheckClient) Search(query string) (string, error) {
+// Search queries the Google Fact Check Tools API and returns every
+// ClaimReview found. lang narrows results to one language ("" — any).
+func (c *GoogleFactCheckClient) Search(query, lang string) ([]models.ClaimReview, error) {
 	if c.APIKey == "" {
-		return "", nil
+		return nil, nil
 	}
 
 	log.Printf("[FACT CHECK] 🔍 Проверяю факты через Google Fact Check: %s", query)
 
-	encodedQuery := url.QueryEscape(query)
-	// languageCode=ro and languageCode=ru are most common for Moldova, but we leave it open to catch translation
-	apiURL := fmt.Sprintf("https://factchecktools.googleapis.com/v1alpha1/claims:search?query=%s&key=%s", encodedQuery, c.APIKey)
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("pageSize", "10")
+	params.Set("key", c.APIKey)
+	if lang != "" {
+		params.Set("languageCode", lang)
+	}
+	apiURL := "https://factchecktools.googleapis.com/v1alpha1/claims:search?" + params.Encode()
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := searchHTTPClient.Do(req)
 	if err != nil {
 		log.Printf("[FACT CHECK] ❌ Ошибка сети: %v", err)
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("[FACT CHECK] ❌ API вернуло статус: %d", resp.StatusCode)
-		return "", fmt.Errorf("bad status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var factCheckResp GoogleFactCheckResponse
 	if err := json.Unmarshal(body, &factCheckResp); err != nil {
 		log.Printf("[FACT CHECK] ❌ Ошибка парсинга JSON: %v", err)
-		return "", err
-	}
-
-	if len(factCheckResp.Claims) == 0 {
-		return "", nil
+		return nil, err
 	}
 
-	// Формируем результат в виде текста, который скормим AI
-	var result string
-	result += "\n\n--- 🕵️ БАЗА ПРОВЕРКИ ФАКТОВ (Google Fact Check Tools) ---\n"
-	result += "ВАЖНО: Ниже приведены официальные проверки фактов, найденные независимыми журналистами. Если текущий текст совпадает с этими фейками, используйте это в анализе!\n"
-
-	count := 0
+	var reviews []models.ClaimReview
 	for _, claim := range factCheckResp.Claims {
-		if count >= 3 {
-			break // Берём только 3 самых релевантных фейка, чтобы не перегружать контекст
-		}
-		if len(claim.ClaimReview) > 0 {
-			review := claim.ClaimReview[0]
-			result += fmt.Sprintf("\n🔴 Утверждение: \"%s\"\n", claim.Text)
-			result += fmt.Sprintf("📝 Вердикт журналистов: %s\n", review.TextualRating)
-			result += fmt.Sprintf("📰 Источник: %s (%s)\n", review.Publisher.Name, review.Url)
-			count++
+		for _, review := range claim.ClaimReview {
+			reviews = append(reviews, models.ClaimReview{
+				ClaimText:     claim.Text,
+				Claimant:      claim.Claimant,
+				ClaimDate:     parseLDDate(claim.ClaimDate),
+				Rating:        review.TextualRating,
+				Publisher:     review.Publisher.Name,
+				PublisherSite: review.Publisher.Site,
+				URL:           review.Url,
+				Title:         review.Title,
+				ReviewDate:    parseLDDate(review.ReviewDate),
+				Language:      review.LanguageCode,
+				Source:        "google",
+			})
 		}
 	}
-
-	return result, nil
+	log.Printf("[FACT CHECK] ✓ Найдено проверок: %d", len(reviews))
+	return reviews, nil
 }
@@ -2,21 +2,51 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
 )
 
-type ContentFetcher struct{}
+type ContentFetcher struct {
+	policy   *FetchPolicy
+	crawl    CrawlConfig
+	limiter  *hostLimiter
+	client   *http.Client
+	fbClient *http.Client
 
-func NewContentFetcher() *ContentFetcher {
-	return &ContentFetcher{}
+	robotsMu sync.Mutex
+	robots   map[string]*robotsRules
+}
+
+// NewContentFetcher создаёт общий для всех сервисов загрузчик страниц.
+func NewContentFetcher(policy *FetchPolicy, crawl CrawlConfig) *ContentFetcher {
+	if policy == nil {
+		policy = DefaultFetchPolicy()
+	}
+	if crawl.UserAgent == "" {
+		crawl.UserAgent = DefaultCrawlConfig().UserAgent
+	}
+	return &ContentFetcher{
+		policy:  policy,
+		crawl:   crawl,
+		limiter: newHostLimiter(crawl.HostConcurrency, crawl.HostInterval),
+		robots:  map[string]*robotsRules{},
+		client:  policy.Client(nil),
+		// Follow redirects but stop if we land on login page
+		fbClient: policy.Client(func(req *http.Request) error {
+			if strings.Contains(req.URL.String(), "/login") || strings.Contains(req.URL.String(), "login.php") {
+				return fmt.Errorf("Facebook требует авторизации для этого поста")
+			}
+			return nil
+		}),
+	}
 }
 
 func isFacebookURL(u string) bool {
@@ -48,37 +78,146 @@ func toMbasic(u string) string {
 	return u
 }
 
+// FetchResult — текст страницы и сведения о том, откуда он получен.
+type FetchResult struct {
+	Text       string
+	URL        string // URL, с которого фактически взят текст
+	Fallback   string // "" | "ld+json" | "amp" | "wayback" | "meta"
+	ArchivedAt string // время снимка Wayback Machine (YYYYMMDDhhmmss)
+
+	PublishedAt *time.Time // из метаданных страницы, если указаны
+	ModifiedAt  *time.Time
+
+	page *fetchedPage
+}
+
+// FetchURL загружает страницу и возвращает только текст.
 func (f *ContentFetcher) FetchURL(url string) (string, error) {
+	res, err := f.Fetch(url)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// Fetch загружает страницу и извлекает текст. Если страница недоступна,
+// пустая или рендерится JavaScript'ом — пробует цепочку фолбеков:
+// ld+json → AMP-версия → Wayback Machine → meta-теги.
+func (f *ContentFetcher) Fetch(url string) (*FetchResult, error) {
+	res, err := f.fetch(url)
+	if err == nil && res.page != nil {
+		res.PublishedAt, res.ModifiedAt = extractPublishDates(string(res.page.Body))
+	}
+	return res, err
+}
+
+func (f *ContentFetcher) fetch(url string) (*FetchResult, error) {
 	log.Printf("[FETCHER] 🌐 Начинаю загрузку контента с URL: %s", url)
 
+	if _, err := f.policy.ValidateURL(url); err != nil {
+		log.Printf("[FETCHER] ⛔ URL отклонён политикой: %v", err)
+		return nil, err
+	}
+
 	// Facebook requires special handling via mbasic.facebook.com
 	if isFacebookURL(url) {
-		return f.fetchFacebook(url)
+		text, err := f.fetchFacebook(url)
+		if err != nil {
+			return nil, err
+		}
+		return &FetchResult{Text: text, URL: url}, nil
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-
-	req, err := http.NewRequest("GET", url, nil)
+	log.Printf("[FETCHER] 📡 Отправляю HTTP запрос...")
+	page, err := f.get(url, f.client, "")
 	if err != nil {
-		return "", fmt.Errorf("ошибка создания запроса: %w", err)
+		var unsupported unsupportedContentError
+		var denied fetchDeniedError
+		if errors.As(err, &unsupported) || errors.As(err, &denied) {
+			return nil, err
+		}
+		log.Printf("[FETCHER] ⚠ Страница недоступна: %v", err)
+		return f.fallbackForDeadPage(url, err)
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7")
+	log.Printf("[FETCHER] ✓ Получен ответ: статус %d", page.StatusCode)
+	log.Printf("[FETCHER] 📄 Content-Type: %s", page.Header.Get("Content-Type"))
 
-	log.Printf("[FETCHER] 📡 Отправляю HTTP запрос...")
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("ошибка загрузки: %w", err)
+	if page.StatusCode != http.StatusOK {
+		err := fmt.Errorf("статус код: %d", page.StatusCode)
+		if !isDeadPageStatus(page.StatusCode) {
+			return nil, err
+		}
+		return f.fallbackForDeadPage(url, err)
+	}
+	body := page.Body
+
+	log.Printf("[FETCHER] ✓ Загружено %d байт", len(body))
+
+	content := f.extractText(string(body))
+	log.Printf("[FETCHER] ✓ Извлечено %d символов текста", len(content))
+	if len(content) > 0 {
+		log.Printf("[FETCHER] 📝 Первые 100 символов: %s...", truncate(content, 100))
+	}
+
+	if len(content) < 200 {
+		log.Printf("[FETCHER] ⚠ Контент очень короткий (%d символов), пробую фолбеки для SPA...", len(content))
+
+		// Фолбек 1: ld+json (структурированные данные статьи)
+		if ldContent := f.extractLdJson(string(body)); len(ldContent) >= 200 {
+			log.Printf("[FETCHER] ✓ Извлечено %d символов из ld+json", len(ldContent))
+			return &FetchResult{Text: ldContent, URL: page.URL, Fallback: "ld+json", page: page}, nil
+		}
+
+		// Фолбек 2: AMP-версия (обычно отдаётся без JavaScript)
+		if res := f.tryAMP(page.URL, body); res != nil {
+			return res, nil
+		}
+
+		// Фолбек 3: архивная копия
+		if res := f.tryWayback(url); res != nil {
+			return res, nil
+		}
+
+		// Фолбек 4: Open Graph + meta теги
+		if metaContent := f.extractMetaTags(string(body)); len(metaContent) >= 50 {
+			log.Printf("[FETCHER] ✓ Извлечено %d символов из meta-тегов", len(metaContent))
+			return &FetchResult{Text: metaContent, URL: page.URL, Fallback: "meta", page: page}, nil
+		}
+
+		return nil, fmt.Errorf("недостаточно текстового контента на странице (%d символов). Сайт, вероятно, использует JavaScript для рендеринга", len(content))
+	}
+
+	return &FetchResult{Text: content, URL: page.URL, page: page}, nil
+}
+
+// isDeadPageStatus — статус удалённой или недоступной страницы, для которой
+// есть смысл искать копию. Отказ в доступе (401, 403, 429) — решение сайта,
+// и его копии в обход не ищем.
+func isDeadPageStatus(code int) bool {
+	return code == http.StatusNotFound || code == http.StatusGone ||
+		code == http.StatusUnavailableForLegalReasons || code >= 500
+}
+
+// fallbackForDeadPage пробует AMP-версию и Wayback Machine для страницы,
+// которая не открылась из-за сетевой ошибки или исчезла (часто — удалённый фейк).
+func (f *ContentFetcher) fallbackForDeadPage(url string, cause error) (*FetchResult, error) {
+	if res := f.tryAMP(url, nil); res != nil {
+		return res, nil
 	}
-	defer resp.Body.Close()
+	if res := f.tryWayback(url); res != nil {
+		return res, nil
+	}
+	return nil, cause
+}
 
-	log.Printf("[FETCHER] ✓ Получен ответ: статус %d", resp.StatusCode)
-	contentType := resp.Header.Get("Content-Type")
-	log.Printf("[FETCHER] 📄 Content-Type: %s", contentType)
+// unsupportedContentError — страница не HTML (PDF, картинка и т.п.), фолбеки бессмысленны.
+type unsupportedContentError string
 
-	// Блокируем бинарные форматы — только HTML/текст можно анализировать
+func (e unsupportedContentError) Error() string { return string(e) }
+
+// checkContentType блокирует бинарные форматы — только HTML/текст можно анализировать.
+func checkContentType(contentType string) error {
 	ct := strings.ToLower(contentType)
 	blockedTypes := []string{
 		"application/pdf",
@@ -106,46 +245,10 @@ func (f *ContentFetcher) FetchURL(url string) (string, error) {
 			} else if len(ext) > 1 {
 				typeName = ext[1]
 			}
-			return "", fmt.Errorf("❌ Невозможно проанализировать %s.\nПередайте ссылку на статью или веб-страницу (HTML), а не на файл", typeName)
-		}
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("статус код: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения: %w", err)
-	}
-
-	log.Printf("[FETCHER] ✓ Загружено %d байт", len(body))
-
-	content := f.extractText(string(body))
-	log.Printf("[FETCHER] ✓ Извлечено %d символов текста", len(content))
-	if len(content) > 0 {
-		log.Printf("[FETCHER] 📝 Первые 100 символов: %s...", truncate(content, 100))
-	}
-
-	if len(content) < 200 {
-		log.Printf("[FETCHER] ⚠ Контент очень короткий (%d символов), пробую фолбеки для SPA...", len(content))
-
-		// Фолбек 1: ld+json (структурированные данные статьи)
-		if ldContent := f.extractLdJson(string(body)); len(ldContent) >= 200 {
-			log.Printf("[FETCHER] ✓ Извлечено %d символов из ld+json", len(ldContent))
-			return ldContent, nil
+			return unsupportedContentError(fmt.Sprintf("❌ Невозможно проанализировать %s.\nПередайте ссылку на статью или веб-страницу (HTML), а не на файл", typeName))
 		}
-
-		// Фолбек 2: Open Graph + meta теги
-		if metaContent := f.extractMetaTags(string(body)); len(metaContent) >= 50 {
-			log.Printf("[FETCHER] ✓ Извлечено %d символов из meta-тегов", len(metaContent))
-			return metaContent, nil
-		}
-
-		return "", fmt.Errorf("недостаточно текстового контента на странице (%d символов). Сайт, вероятно, использует JavaScript для рендеринга", len(content))
 	}
-
-	return content, nil
+	return nil
 }
 
 func truncate(s string, maxLen int) string {
@@ -428,32 +531,8 @@ func (f *ContentFetcher) fetchFacebook(originalURL string) (string, error) {
 	mbasicURL := toMbasic(originalURL)
 	log.Printf("[FETCHER] 📘 Facebook → mbasic: %s", mbasicURL)
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		// Follow redirects but stop if we land on login page
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if strings.Contains(req.URL.String(), "/login") || strings.Contains(req.URL.String(), "login.php") {
-				return fmt.Errorf("Facebook требует авторизации для этого поста")
-			}
-			if len(via) >= 5 {
-				return fmt.Errorf("слишком много перенаправлений")
-			}
-			return nil
-		},
-	}
-
-	req, err := http.NewRequest("GET", mbasicURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("ошибка создания запроса: %w", err)
-	}
-
 	// Mobile browser UA — mbasic works best with mobile agents
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 12; Pixel 6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.210 Mobile Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-
-	resp, err := client.Do(req)
+	page, err := f.get(mbasicURL, f.fbClient, "Mozilla/5.0 (Linux; Android 12; Pixel 6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.210 Mobile Safari/537.36")
 	if err != nil {
 		// Give a friendly message if it's a login redirect
 		if strings.Contains(err.Error(), "авторизации") {
@@ -461,24 +540,19 @@ func (f *ContentFetcher) fetchFacebook(originalURL string) (string, error) {
 		}
 		return "", fmt.Errorf("ошибка загрузки Facebook: %w", err)
 	}
-	defer resp.Body.Close()
 
-	log.Printf("[FETCHER] ✓ mbasic ответил: %d", resp.StatusCode)
+	log.Printf("[FETCHER] ✓ mbasic ответил: %d", page.StatusCode)
 
 	// mbasic may return 302 to login — check final URL
-	if strings.Contains(resp.Request.URL.String(), "/login") {
+	if strings.Contains(page.URL, "/login") {
 		return "", fmt.Errorf("❌ Facebook требует авторизации для этого поста. Используйте публичные посты")
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[FETCHER] ⚠ mbasic вернул %d, пробую OG-теги из оригинального URL", resp.StatusCode)
+	if page.StatusCode != http.StatusOK {
+		log.Printf("[FETCHER] ⚠ mbasic вернул %d, пробую OG-теги из оригинального URL", page.StatusCode)
 		return f.fetchFacebookOG(originalURL)
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("ошибка чтения: %w", err)
-	}
+	body := page.Body
 
 	log.Printf("[FETCHER] ✓ Facebook: загружено %d байт", len(body))
 
@@ -503,26 +577,13 @@ func (f *ContentFetcher) fetchFacebook(originalURL string) (string, error) {
 // for public posts without requiring a login.
 func (f *ContentFetcher) fetchFacebookOG(originalURL string) (string, error) {
 	log.Printf("[FETCHER] 📘 Facebook OG fallback: %s", originalURL)
-	client := &http.Client{Timeout: 30 * time.Second}
-	req, err := http.NewRequest("GET", originalURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("OG fallback request: %w", err)
-	}
 	// facebookexternalhit UA triggers server-side OG tag rendering for public posts
-	req.Header.Set("User-Agent", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-
-	resp, err := client.Do(req)
+	page, err := f.get(originalURL, f.client, "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)")
 	if err != nil {
 		return "", fmt.Errorf("OG fallback error: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("OG fallback read: %w", err)
-	}
-	log.Printf("[FETCHER] ✓ Facebook OG: статус %d, загружено %d байт", resp.StatusCode, len(body))
+	body := page.Body
+	log.Printf("[FETCHER] ✓ Facebook OG: статус %d, загружено %d байт", page.StatusCode, len(body))
 
 	content := f.extractMetaTags(string(body))
 	if len(content) < 50 {
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// archiveArticle — текст снимка, достаточно длинный для извлечения.
+var archiveArticle = "<html><body><article><p>" +
+	strings.Repeat("Архивная копия удалённой статьи о ценах на энергию. ", 10) +
+	"</p></article></body></html>"
+
+// newArchiveStub — локальная замена Wayback Machine: availability API
+// и сами снимки. hits считает обращения к API.
+func newArchiveStub(t *testing.T) (srv *httptest.Server, hits *atomic.Int32) {
+	hits = &atomic.Int32{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/wayback/available":
+			hits.Add(1)
+			fmt.Fprintf(w, `{"archived_snapshots":{"closest":{"available":true,"status":"200","timestamp":"20240101000000","url":"%s/web/20240101000000/%s"}}}`,
+				srv.URL, r.URL.Query().Get("url"))
+		case strings.HasPrefix(r.URL.Path, "/web/20240101000000id_/"):
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, archiveArticle)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func newTestFetcher(archiveURL string, policy *FetchPolicy) *ContentFetcher {
+	crawl := DefaultCrawlConfig()
+	crawl.RespectRobots = true
+	crawl.HostInterval = 0
+	crawl.ArchiveAPIURL = archiveURL + "/wayback/available"
+	policy.AllowPrivate = true // httptest слушает loopback
+	return NewContentFetcher(policy, crawl)
+}
+
+func TestFetchFallsBackToArchiveForDeadPage(t *testing.T) {
+	archive, hits := newArchiveStub(t)
+	for _, status := range []int{http.StatusNotFound, http.StatusGone, http.StatusBadGateway} {
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		f := newTestFetcher(archive.URL, DefaultFetchPolicy())
+
+		res, err := f.Fetch(origin.URL + "/news/1")
+		origin.Close()
+		if err != nil {
+			t.Fatalf("status %d: %v", status, err)
+		}
+		if res.Fallback != "wayback" || res.ArchivedAt != "20240101000000" {
+			t.Errorf("status %d: fallback=%q archived_at=%q, want wayback snapshot", status, res.Fallback, res.ArchivedAt)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("archive API hits = %d, want 3", hits.Load())
+	}
+}
+
+func TestFetchFallsBackToArchiveOnNetworkError(t *testing.T) {
+	archive, hits := newArchiveStub(t)
+	origin := httptest.NewServer(http.NotFoundHandler())
+	dead := origin.URL + "/news/1"
+	origin.Close() // соединение будет отклонено
+
+	res, err := newTestFetcher(archive.URL, DefaultFetchPolicy()).Fetch(dead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fallback != "wayback" || hits.Load() != 1 {
+		t.Errorf("fallback=%q, archive hits=%d; want wayback after a network error", res.Fallback, hits.Load())
+	}
+}
+
+func TestFetchDoesNotBypassRefusals(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		policy  *FetchPolicy
+		denied  bool // ошибка — отказ политики или robots.txt
+	}{
+		{
+			name: "robots.txt disallow",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					fmt.Fprint(w, "User-agent: *\nDisallow: /news/\n")
+					return
+				}
+				fmt.Fprint(w, archiveArticle)
+			},
+			policy: DefaultFetchPolicy(),
+			denied: true,
+		},
+		{
+			name: "redirect to denied host",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://internal.example/admin", http.StatusFound)
+			},
+			policy: &FetchPolicy{MaxBodyBytes: 5 << 20, MaxRedirects: 5, DenyHosts: []string{"internal.example"}},
+			denied: true,
+		},
+		{
+			name: "body over limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, archiveArticle)
+			},
+			policy: &FetchPolicy{MaxBodyBytes: 64, MaxRedirects: 5},
+			denied: true,
+		},
+		{
+			name: "forbidden by site",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			policy: DefaultFetchPolicy(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, hits := newArchiveStub(t)
+			origin := httptest.NewServer(tt.handler)
+			defer origin.Close()
+
+			res, err := newTestFetcher(archive.URL, tt.policy).Fetch(origin.URL + "/news/1")
+			if err == nil {
+				t.Fatalf("got result via %q, want error", res.Fallback)
+			}
+			var denied fetchDeniedError
+			if got := errors.As(err, &denied); got != tt.denied {
+				t.Errorf("denied = %v, want %v (%v)", got, tt.denied, err)
+			}
+			if hits.Load() != 0 {
+				t.Errorf("archive API was queried %d times after a refusal", hits.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FetchPolicy ограничивает загрузку страниц по URL, присланным пользователем:
+// куда можно ходить (SSRF-защита), сколько редиректов и сколько байт читать.
+type FetchPolicy struct {
+	MaxBodyBytes int64
+	MaxRedirects int
+	Timeout      time.Duration
+	AllowHosts   []string // если не пусто — разрешены только эти хосты (и их поддомены)
+	DenyHosts    []string // всегда запрещены (и их поддомены)
+	AllowPrivate bool     // разрешить приватные/loopback адреса (только для локальной разработки)
+}
+
+// DefaultFetchPolicy — безопасные значения по умолчанию.
+func DefaultFetchPolicy() *FetchPolicy {
+	return &FetchPolicy{
+		MaxBodyBytes: 5 << 20,
+		MaxRedirects: 5,
+		Timeout:      30 * time.Second,
+	}
+}
+
+// Диапазоны, которые net.IP не помечает как приватные, но куда ходить нельзя.
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "этот" хост
+	"100.64.0.0/10", // CGNAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmark
+	"240.0.0.0/4",   // зарезервировано
+	"64:ff9b::/96",  // NAT64 — может указывать на внутренний IPv4
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// isBlockedIP сообщает, относится ли адрес к приватным, loopback, link-local и т.п.
+func isBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches проверяет host на совпадение с одним из шаблонов (точное или поддомен).
+func hostMatches(host string, patterns []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, p := range patterns {
+		p = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(p)), ".")
+		if p == "" {
+			continue
+		}
+		if host == p || strings.HasSuffix(host, "."+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchDeniedError — загрузку запретила политика или robots.txt сайта.
+// Страница при этом не «мёртвая», и её копии в архиве или AMP не ищутся.
+type fetchDeniedError string
+
+func (e fetchDeniedError) Error() string { return string(e) }
+
+// CheckURL проверяет схему и хост URL (без сетевых запросов).
+func (p *FetchPolicy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("❌ Поддерживаются только http и https ссылки")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("❌ В ссылке не указан хост")
+	}
+	if hostMatches(host, p.DenyHosts) {
+		return fetchDeniedError(fmt.Sprintf("❌ Загрузка с %s запрещена политикой", host))
+	}
+	if len(p.AllowHosts) > 0 && !hostMatches(host, p.AllowHosts) {
+		return fetchDeniedError(fmt.Sprintf("❌ Хост %s не входит в список разрешённых", host))
+	}
+	if ip := net.ParseIP(host); ip != nil && !p.AllowPrivate && isBlockedIP(ip) {
+		return fetchDeniedError("❌ Загрузка с внутренних адресов запрещена")
+	}
+	return nil
+}
+
+// ValidateURL разбирает URL, проверяет его по политике и резолвит DNS,
+// чтобы сразу отказать, если хост указывает во внутреннюю сеть.
+func (p *FetchPolicy) ValidateURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("❌ Некорректная ссылка: %w", err)
+	}
+	if err := p.CheckURL(u); err != nil {
+		return nil, err
+	}
+	if p.AllowPrivate || net.ParseIP(u.Hostname()) != nil {
+		return u, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("❌ Не удалось найти хост %s: %w", u.Hostname(), err)
+	}
+	for _, a := range addrs {
+		if isBlockedIP(a.IP) {
+			return nil, fetchDeniedError("❌ Загрузка с внутренних адресов запрещена")
+		}
+	}
+	return u, nil
+}
+
+// Client возвращает http.Client, который повторно проверяет адрес при каждом
+// соединении (в т.ч. после редиректов и при DNS rebinding) и ограничивает число редиректов.
+// checkRedirect — дополнительная проверка редиректа (может быть nil).
+func (p *FetchPolicy) Client(checkRedirect func(req *http.Request) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if p.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isBlockedIP(ip) {
+				return fetchDeniedError(fmt.Sprintf("соединение с %s запрещено политикой", host))
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // прокси обошёл бы проверку адреса
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          50,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   p.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.MaxRedirects {
+				return fmt.Errorf("слишком много перенаправлений (больше %d)", p.MaxRedirects)
+			}
+			if err := p.CheckURL(req.URL); err != nil {
+				return err
+			}
+			if checkRedirect != nil {
+				return checkRedirect(req)
+			}
+			return nil
+		},
+	}
+}
+
+// ReadBody читает тело ответа, но не больше MaxBodyBytes.
+func (p *FetchPolicy) ReadBody(r io.Reader) ([]byte, error) {
+	if p.MaxBodyBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, p.MaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > p.MaxBodyBytes {
+		return nil, fetchDeniedError(fmt.Sprintf("❌ Страница слишком большая (больше %d КБ)", p.MaxBodyBytes>>10))
+	}
+	return body, nil
+}
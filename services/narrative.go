@@ -0,0 +1,500 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Нарративы: анализы, продвигающие одну и ту же историю, группируются по
+// похожести темы и утверждений. Сравниваются только TF-IDF векторы ключевых
+// слов и имён собственных (эмбеддингов ни у одного AI-провайдера нет). Каждый
+// нарратив хранит центроид — средний вектор статей. Разметка идёт в фоне:
+// сохранённый анализ лишь будит фоновый проход (Notify), ответ его не ждёт.
+
+// ErrNarrativeNotFound — нарратива с таким id нет.
+var ErrNarrativeNotFound = errors.New("нарратив не найден")
+
+// NarrativeConfig — параметры кластеризации.
+type NarrativeConfig struct {
+	MinSimilarity float64       // порог отнесения к существующему нарративу (0..1)
+	Window        time.Duration // нарратив без новых статей дольше — не продолжается
+	Interval      time.Duration // повторный проход по неразмеченным анализам (0 — только по Notify)
+	Batch         int
+}
+
+const (
+	narrativeMaxTerms    = 40 // терминов в векторе статьи
+	narrativeCentroidMax = 80 // терминов в центроиде нарратива
+	narrativeKeywords    = 8
+	narrativeTextRunes   = 6000
+	narrativeMaxActive   = 2000
+)
+
+type NarrativeClusterer struct {
+	cfg  NarrativeConfig
+	mu   sync.Mutex    // загрузка кандидатов и обновление центроида — атомарно
+	kick chan struct{} // новый анализ ждёт разметки
+}
+
+func NewNarrativeClusterer(cfg NarrativeConfig) *NarrativeClusterer {
+	if cfg.MinSimilarity <= 0 {
+		cfg.MinSimilarity = 0.35
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 14 * 24 * time.Hour
+	}
+	if cfg.Batch <= 0 {
+		cfg.Batch = 200
+	}
+	return &NarrativeClusterer{cfg: cfg, kick: make(chan struct{}, 1)}
+}
+
+// ── Векторы ──────────────────────────────────────────────────────────────────
+
+// narrativeStem — грубая основа слова: без последних двух букв, но не короче
+// пяти. Для русского и румынского этого хватает, чтобы «тарифов» и «тарифы»,
+// «Молдовы» и «Молдове» совпали.
+func narrativeStem(w string) string {
+	r := []rune(w)
+	return string(r[:max(5, len(r)-2)])
+}
+
+// narrativeVector — веса терминов статьи: 1+ln(tf) по основам слов плюс
+// составные имена собственные («Виктор Парликов») с двойным весом. Резюме и проверяемые
+// факты из анализа добавляются к тексту — в них суть истории.
+// surface — основа → слово как в тексте, для читаемых ключевых слов.
+func narrativeVector(text string, res *models.AnalysisResponse) (vec map[string]float64, surface map[string]string) {
+	if res != nil {
+		text = res.Summary + "\n" + strings.Join(res.FactCheck.VerifiableFacts, "\n") + "\n" + text
+	}
+	if runes := []rune(text); len(runes) > narrativeTextRunes {
+		text = string(runes[:narrativeTextRunes])
+	}
+	counts := map[string]float64{}
+	surface = map[string]string{}
+	for _, w := range wordRe.FindAllString(strings.ToLower(text), -1) {
+		if len([]rune(w)) <= 4 || queryStopWords[w] {
+			continue
+		}
+		stem := narrativeStem(w)
+		if _, ok := surface[stem]; !ok {
+			surface[stem] = w
+		}
+		counts[stem]++
+	}
+	for _, e := range topEntities(text, 10) {
+		if strings.Contains(e, " ") {
+			counts[strings.ToLower(e)] += 2
+		}
+	}
+	vec = make(map[string]float64, len(counts))
+	for t, c := range counts {
+		vec[t] = 1 + math.Log(c)
+	}
+	return topTerms(vec, narrativeMaxTerms), surface
+}
+
+// topTerms оставляет n терминов с наибольшим весом.
+func topTerms(vec map[string]float64, n int) map[string]float64 {
+	if len(vec) <= n {
+		return vec
+	}
+	terms := sortedTerms(vec)
+	out := make(map[string]float64, n)
+	for _, t := range terms[:n] {
+		out[t] = vec[t]
+	}
+	return out
+}
+
+func sortedTerms(vec map[string]float64) []string {
+	terms := make([]string, 0, len(vec))
+	for t := range vec {
+		terms = append(terms, t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if vec[terms[i]] != vec[terms[j]] {
+			return vec[terms[i]] > vec[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+// termCosine — косинус TF-IDF векторов.
+func termCosine(a, b map[string]float64, idf map[string]float64) float64 {
+	var dot, na, nb float64
+	for t, w := range a {
+		w *= idf[t]
+		na += w * w
+		if v, ok := b[t]; ok {
+			dot += w * v * idf[t]
+		}
+	}
+	for t, v := range b {
+		v *= idf[t]
+		nb += v * v
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// narrativeIDF — обратная частота термина среди активных нарративов:
+// слова, встречающиеся во всех историях («заявил», «сообщает»), весят мало.
+func narrativeIDF(cands []narrativeCandidate, vec map[string]float64) map[string]float64 {
+	df := map[string]int{}
+	for _, c := range cands {
+		for t := range c.Terms {
+			df[t]++
+		}
+	}
+	idf := map[string]float64{}
+	n := float64(len(cands))
+	weight := func(t string) {
+		if _, ok := idf[t]; !ok {
+			idf[t] = math.Log((n+1)/float64(df[t]+1)) + 1
+		}
+	}
+	for t := range vec {
+		weight(t)
+	}
+	for _, c := range cands {
+		for t := range c.Terms {
+			weight(t)
+		}
+	}
+	return idf
+}
+
+// mergeCentroid — новый центроид после добавления статьи к count имеющимся.
+func mergeCentroid(centroid, vec map[string]float64, count int) map[string]float64 {
+	out := make(map[string]float64, len(centroid)+len(vec))
+	n := float64(count)
+	for t, w := range centroid {
+		out[t] = w * n / (n + 1)
+	}
+	for t, w := range vec {
+		out[t] += w / (n + 1)
+	}
+	return topTerms(out, narrativeCentroidMax)
+}
+
+// ── Отнесение к нарративу ────────────────────────────────────────────────────
+
+type narrativeCandidate struct {
+	ID    int64
+	Label string
+	Terms map[string]float64
+	Count int
+}
+
+// loadActiveNarratives — нарративы, в которые статьи поступали в пределах окна.
+func (c *NarrativeClusterer) loadActiveNarratives() []narrativeCandidate {
+	rows, err := database.DB.Query(`
+		SELECT id, COALESCE(label, ''), terms, article_count
+		FROM narratives
+		WHERE last_seen > NOW() - make_interval(secs => $1)
+		ORDER BY last_seen DESC
+		LIMIT $2
+	`, c.cfg.Window.Seconds(), narrativeMaxActive)
+	if err != nil {
+		log.Printf("[NARRATIVE] ⚠ Ошибка чтения нарративов: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var cands []narrativeCandidate
+	for rows.Next() {
+		var n narrativeCandidate
+		var terms []byte
+		if rows.Scan(&n.ID, &n.Label, &terms, &n.Count) != nil {
+			continue
+		}
+		json.Unmarshal(terms, &n.Terms)
+		cands = append(cands, n)
+	}
+	return cands
+}
+
+// Assign относит сохранённый анализ к самому похожему активному нарративу
+// или заводит новый. at — время анализа.
+func (c *NarrativeClusterer) Assign(analysisID int64, text, rawURL string, res *models.AnalysisResponse, at time.Time) *models.NarrativeRef {
+	if c == nil || database.DB == nil || analysisID <= 0 {
+		return nil
+	}
+	vec, surface := narrativeVector(text, res)
+	if len(vec) < 3 {
+		// 0 — текст слишком короткий, повторно не размечаем
+		database.DB.Exec(`UPDATE analysis_results SET narrative_id = 0 WHERE id = $1`, analysisID)
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cands := c.loadActiveNarratives()
+	idf := narrativeIDF(cands, vec)
+	best, bestSim := -1, 0.0
+	for i, n := range cands {
+		if sim := termCosine(vec, n.Terms, idf); sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+
+	title := chainTruncate(strings.TrimSpace(res.Summary), 160)
+	domain := ""
+	if rawURL != "" {
+		domain = NormalizeDomain(rawURL)
+	}
+	ref := &models.NarrativeRef{Similarity: math.Round(bestSim*100) / 100}
+
+	if best >= 0 && bestSim >= c.cfg.MinSimilarity {
+		n := cands[best]
+		terms := mergeCentroid(n.Terms, vec, n.Count)
+		termsJSON, _ := json.Marshal(terms)
+		err := database.DB.QueryRow(`
+			UPDATE narratives SET
+				terms         = $2,
+				keywords      = $3,
+				article_count = article_count + 1,
+				sum_score     = sum_score + $4,
+				avg_score     = (sum_score + $4)::float / (article_count + 1),
+				first_seen    = LEAST(first_seen, $5),
+				last_seen     = GREATEST(last_seen, $5)
+			WHERE id = $1
+			RETURNING article_count
+		`, n.ID, termsJSON, pq.Array(narrativeKeywordList(terms, surface)), res.CredibilityScore, at).Scan(&ref.ArticleCount)
+		if err != nil {
+			log.Printf("[NARRATIVE] ⚠ Ошибка обновления нарратива %d: %v", n.ID, err)
+			return nil
+		}
+		ref.ID, ref.Label = n.ID, n.Label
+	} else {
+		label := title
+		keywords := narrativeKeywordList(vec, surface)
+		if label == "" {
+			label = strings.Join(keywords[:min(5, len(keywords))], ", ")
+		}
+		termsJSON, _ := json.Marshal(vec)
+		err := database.DB.QueryRow(`
+			INSERT INTO narratives (label, keywords, terms, article_count, sum_score, avg_score, first_seen, last_seen)
+			VALUES ($1, $2, $3, 1, $4, $4, $5, $5)
+			RETURNING id
+		`, label, pq.Array(keywords), termsJSON, res.CredibilityScore, at).Scan(&ref.ID)
+		if err != nil {
+			log.Printf("[NARRATIVE] ⚠ Ошибка создания нарратива: %v", err)
+			return nil
+		}
+		ref.Label, ref.ArticleCount, ref.IsNew, ref.Similarity = label, 1, true, 1
+	}
+
+	if _, err := database.DB.Exec(`
+		INSERT INTO narrative_members (narrative_id, analysis_id, url, domain, title, credibility_score, similarity, added_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+	`, ref.ID, analysisID, rawURL, domain, title, res.CredibilityScore, ref.Similarity, at); err != nil {
+		log.Printf("[NARRATIVE] ⚠ Ошибка добавления статьи в нарратив: %v", err)
+	}
+	database.DB.Exec(`UPDATE analysis_results SET narrative_id = $1 WHERE id = $2`, ref.ID, analysisID)
+	return ref
+}
+
+// narrativeKeywordList — самые весомые термины в читаемом виде.
+func narrativeKeywordList(terms map[string]float64, surface map[string]string) []string {
+	var keywords []string
+	for _, t := range sortedTerms(terms) {
+		if len(keywords) >= narrativeKeywords {
+			break
+		}
+		if w, ok := surface[t]; ok {
+			t = w
+		}
+		keywords = append(keywords, t)
+	}
+	return keywords
+}
+
+// ── Фоновая разметка ─────────────────────────────────────────────────────────
+
+// Notify сообщает, что сохранён новый анализ: фоновый проход разметит его,
+// не задерживая ответ.
+func (c *NarrativeClusterer) Notify() {
+	if c == nil {
+		return
+	}
+	select {
+	case c.kick <- struct{}{}:
+	default: // проход уже запрошен
+	}
+}
+
+// Start размечает анализы без нарратива — сразу, по каждому Notify и
+// каждые Interval (если он задан).
+func (c *NarrativeClusterer) Start() {
+	if c == nil || database.DB == nil {
+		return
+	}
+	var tick <-chan time.Time
+	if c.cfg.Interval > 0 {
+		ticker := time.NewTicker(c.cfg.Interval)
+		tick = ticker.C
+		log.Printf("[NARRATIVE] 🧩 Кластеризация анализов каждые %v (порог %.2f)", c.cfg.Interval, c.cfg.MinSimilarity)
+	}
+	go func() {
+		c.runOnce()
+		for {
+			select {
+			case <-tick:
+			case <-c.kick:
+			}
+			c.runOnce()
+		}
+	}()
+}
+
+func (c *NarrativeClusterer) runOnce() {
+	rows, err := database.DB.Query(`
+		SELECT id, COALESCE(text, ''), COALESCE(url, ''), result, created_at
+		FROM analysis_results
+		WHERE narrative_id IS NULL AND created_at > NOW() - make_interval(secs => $1)
+		ORDER BY id
+		LIMIT $2
+	`, c.cfg.Window.Seconds(), c.cfg.Batch)
+	if err != nil {
+		log.Printf("[NARRATIVE] ⚠ Ошибка выборки анализов: %v", err)
+		return
+	}
+	type pending struct {
+		id       int64
+		text     string
+		url      string
+		res      models.AnalysisResponse
+		analyzed time.Time
+	}
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		var raw []byte
+		if rows.Scan(&p.id, &p.text, &p.url, &raw, &p.analyzed) != nil {
+			continue
+		}
+		json.Unmarshal(raw, &p.res)
+		batch = append(batch, p)
+	}
+	rows.Close()
+
+	assigned := 0
+	for _, p := range batch {
+		if c.Assign(p.id, p.text, p.url, &p.res, p.analyzed) != nil {
+			assigned++
+		}
+	}
+	if len(batch) > 0 {
+		log.Printf("[NARRATIVE] ✓ Размечено анализов: %d из %d", assigned, len(batch))
+	}
+}
+
+// ── Чтение ───────────────────────────────────────────────────────────────────
+
+// TrendingNarratives — нарративы с наибольшим числом статей за период.
+func TrendingNarratives(period time.Duration, limit int) ([]models.Narrative, error) {
+	if database.DB == nil {
+		return nil, errors.New("БД недоступна")
+	}
+	rows, err := database.DB.Query(`
+		SELECT n.id, COALESCE(n.label, ''), n.keywords, n.article_count, COALESCE(n.avg_score, 0),
+		       n.first_seen, n.last_seen,
+		       COUNT(m.analysis_id), COUNT(DISTINCT m.domain)
+		FROM narratives n
+		JOIN narrative_members m ON m.narrative_id = n.id
+		WHERE m.added_at > NOW() - make_interval(secs => $1)
+		GROUP BY n.id
+		ORDER BY COUNT(m.analysis_id) DESC, n.last_seen DESC
+		LIMIT $2
+	`, period.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	narratives := []models.Narrative{}
+	for rows.Next() {
+		var n models.Narrative
+		if err := rows.Scan(&n.ID, &n.Label, pq.Array(&n.Keywords), &n.ArticleCount, &n.AvgScore,
+			&n.FirstSeen, &n.LastSeen, &n.RecentCount, &n.DomainCount); err != nil {
+			continue
+		}
+		n.AvgScore = math.Round(n.AvgScore*10) / 10
+		narratives = append(narratives, n)
+	}
+	return narratives, nil
+}
+
+// GetNarrative — нарратив со статьями (последние memberLimit) и доменами.
+func GetNarrative(id int64, memberLimit int) (*models.Narrative, error) {
+	if database.DB == nil {
+		return nil, errors.New("БД недоступна")
+	}
+	var n models.Narrative
+	err := database.DB.QueryRow(`
+		SELECT id, COALESCE(label, ''), keywords, article_count, COALESCE(avg_score, 0), first_seen, last_seen
+		FROM narratives WHERE id = $1
+	`, id).Scan(&n.ID, &n.Label, pq.Array(&n.Keywords), &n.ArticleCount, &n.AvgScore, &n.FirstSeen, &n.LastSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNarrativeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.AvgScore = math.Round(n.AvgScore*10) / 10
+
+	rows, err := database.DB.Query(`
+		SELECT analysis_id, COALESCE(url, ''), COALESCE(domain, ''), COALESCE(title, ''),
+		       COALESCE(credibility_score, 0), COALESCE(similarity, 0), added_at
+		FROM narrative_members WHERE narrative_id = $1
+		ORDER BY added_at DESC
+		LIMIT $2
+	`, id, memberLimit)
+	if err != nil {
+		return nil, err
+	}
+	n.Members = []models.NarrativeMember{}
+	for rows.Next() {
+		var m models.NarrativeMember
+		if rows.Scan(&m.AnalysisID, &m.URL, &m.Domain, &m.Title, &m.CredibilityScore, &m.Similarity, &m.AddedAt) == nil {
+			n.Members = append(n.Members, m)
+		}
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query(`
+		SELECT domain, COUNT(*), AVG(credibility_score)
+		FROM narrative_members WHERE narrative_id = $1 AND domain IS NOT NULL
+		GROUP BY domain
+		ORDER BY COUNT(*) DESC, domain
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	n.Domains = []models.NarrativeDomain{}
+	for rows.Next() {
+		var d models.NarrativeDomain
+		if rows.Scan(&d.Domain, &d.Articles, &d.AvgScore) == nil {
+			d.AvgScore = math.Round(d.AvgScore*10) / 10
+			n.Domains = append(n.Domains, d)
+		}
+	}
+	n.DomainCount = len(n.Domains)
+	return &n, nil
+}
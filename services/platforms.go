@@ -0,0 +1,224 @@
+package services
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Платформы, на которых публикуют самые разные авторы. Репутация у
+// facebook.com или t.me в целом ничего не говорит, поэтому анализ такой
+// страницы записывается на издателя — страницу, канал или аккаунт:
+// «t.me/channel», «youtube.com/@handle». Платформа — родитель своих
+// издателей: её статистика собирается из их статистики.
+
+type platformRule struct {
+	Host string // каноническое имя платформы
+	// publisher извлекает издателя из пути (и запроса) URL; "" — не удалось
+	publisher func(segs []string, q url.Values) string
+	// Поддомен платформы — сам издатель (user.medium.com)
+	subdomainPublisher func(sub string) string
+}
+
+// platforms — правила по регистрируемому домену (поддомены m., web.,
+// mobile. сводятся к нему же). Алиасы ведут на то же правило.
+var platforms = map[string]*platformRule{}
+
+func init() {
+	telegram := &platformRule{Host: "t.me", publisher: telegramPublisher}
+	facebook := &platformRule{Host: "facebook.com", publisher: facebookPublisher}
+	youtube := &platformRule{Host: "youtube.com", publisher: youtubePublisher}
+	twitter := &platformRule{Host: "x.com", publisher: accountPublisher("i", "home", "search", "hashtag", "intent", "share", "explore", "settings", "notifications", "messages")}
+	shortYoutube := &platformRule{Host: "youtube.com", publisher: func([]string, url.Values) string { return "" }}
+
+	platforms["t.me"] = telegram
+	platforms["telegram.me"] = telegram
+	platforms["facebook.com"] = facebook
+	platforms["fb.com"] = facebook
+	platforms["youtube.com"] = youtube
+	platforms["youtu.be"] = shortYoutube // в коротких ссылках только id видео
+	platforms["x.com"] = twitter
+	platforms["twitter.com"] = twitter
+	platforms["medium.com"] = &platformRule{Host: "medium.com", publisher: mediumPublisher,
+		subdomainPublisher: func(sub string) string { return "@" + sub }}
+	platforms["instagram.com"] = &platformRule{Host: "instagram.com", publisher: instagramPublisher}
+	platforms["tiktok.com"] = &platformRule{Host: "tiktok.com", publisher: handlePublisher}
+	platforms["vk.com"] = &platformRule{Host: "vk.com", publisher: accountPublisher("feed", "video", "away.php", "search", "im", "share.php", "login")}
+}
+
+// platformSubdomains — поддомены, которые означают ту же платформу, а не издателя.
+var platformSubdomains = map[string]bool{"m": true, "mobile": true, "web": true, "mbasic": true, "touch": true}
+
+// platformEntity — издатель на платформе («t.me/channel») или сама
+// платформа («t.me»), если издателя из URL не понять. ok = false — не платформа.
+func platformEntity(host, registrable string, u *url.URL) (entity string, ok bool) {
+	rule := platforms[registrable]
+	if rule == nil {
+		return "", false
+	}
+	if sub := strings.TrimSuffix(host, "."+registrable); sub != host && !platformSubdomains[sub] {
+		if rule.subdomainPublisher != nil {
+			return rule.Host + "/" + rule.subdomainPublisher(sub), true
+		}
+	}
+	var segs []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+	if pub := rule.publisher(segs, u.Query()); pub != "" {
+		return rule.Host + "/" + pub, true
+	}
+	return rule.Host, true
+}
+
+// IsPlatform сообщает, что домен — платформа, статистика которой
+// складывается из статистики её издателей.
+func IsPlatform(domain string) bool {
+	rule := platforms[domain]
+	return rule != nil && rule.Host == domain
+}
+
+// ParentDomain — платформа издателя («t.me» для «t.me/channel»), иначе "".
+func ParentDomain(domain string) string {
+	if i := strings.Index(domain, "/"); i > 0 {
+		return domain[:i]
+	}
+	return ""
+}
+
+var accountNameRe = regexp.MustCompile(`^@?[\p{L}\p{N}_.\-]+$`)
+
+func accountName(s string) string {
+	if !accountNameRe.MatchString(s) {
+		return ""
+	}
+	return strings.ToLower(s)
+}
+
+// accountPublisher — первый сегмент пути, если это не служебный раздел.
+func accountPublisher(reserved ...string) func([]string, url.Values) string {
+	skip := map[string]bool{}
+	for _, r := range reserved {
+		skip[r] = true
+	}
+	return func(segs []string, _ url.Values) string {
+		if len(segs) == 0 || skip[strings.ToLower(segs[0])] {
+			return ""
+		}
+		return accountName(strings.TrimPrefix(segs[0], "@"))
+	}
+}
+
+// handlePublisher — только пути вида /@handle/...
+func handlePublisher(segs []string, _ url.Values) string {
+	if len(segs) == 0 || !strings.HasPrefix(segs[0], "@") {
+		return ""
+	}
+	return accountName(segs[0])
+}
+
+// t.me/channel/123, t.me/s/channel; приглашения (t.me/+..., joinchat)
+// и закрытые каналы (t.me/c/...) издателя не раскрывают.
+func telegramPublisher(segs []string, _ url.Values) string {
+	if len(segs) > 1 && segs[0] == "s" {
+		segs = segs[1:]
+	}
+	if len(segs) == 0 {
+		return ""
+	}
+	switch strings.ToLower(segs[0]) {
+	case "c", "joinchat", "addstickers", "share", "proxy", "iv":
+		return ""
+	}
+	return accountName(segs[0])
+}
+
+// facebook.com/page, /groups/name, /profile.php?id=N, /people/Name/N,
+// /story.php?id=N; прочие служебные пути издателя не раскрывают.
+func facebookPublisher(segs []string, q url.Values) string {
+	if len(segs) == 0 {
+		return ""
+	}
+	switch first := strings.ToLower(segs[0]); first {
+	case "profile.php", "story.php", "permalink.php":
+		return accountName(q.Get("id"))
+	case "groups":
+		if len(segs) > 1 {
+			if name := accountName(segs[1]); name != "" {
+				return "groups/" + name
+			}
+		}
+		return ""
+	case "people", "pages":
+		// /people/Name/<id>, /pages/Name/<id>
+		if len(segs) > 2 {
+			return accountName(segs[2])
+		}
+		return ""
+	case "watch", "share", "sharer", "sharer.php", "photo", "photo.php", "video.php",
+		"events", "hashtag", "login", "reel", "stories", "search", "l.php", "plugins", "dialog":
+		return ""
+	default:
+		return accountName(first)
+	}
+}
+
+// youtube.com/@handle, /channel/<id>, /c/<name>, /user/<name>; у /watch
+// канала в URL нет.
+func youtubePublisher(segs []string, _ url.Values) string {
+	if len(segs) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(segs[0], "@") {
+		return accountName(segs[0])
+	}
+	if len(segs) < 2 {
+		return ""
+	}
+	switch strings.ToLower(segs[0]) {
+	case "channel":
+		// id канала чувствителен к регистру
+		if accountNameRe.MatchString(segs[1]) {
+			return "channel/" + segs[1]
+		}
+	case "c", "user":
+		if name := accountName(segs[1]); name != "" {
+			return strings.ToLower(segs[0]) + "/" + name
+		}
+	}
+	return ""
+}
+
+// medium.com/@user/... или medium.com/<публикация>/<статья>.
+func mediumPublisher(segs []string, _ url.Values) string {
+	if len(segs) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(segs[0], "@") {
+		return accountName(segs[0])
+	}
+	switch strings.ToLower(segs[0]) {
+	case "p", "m", "me", "tag", "topic", "search", "plans", "membership", "about":
+		return ""
+	}
+	return accountName(segs[0])
+}
+
+// instagram.com/account, /stories/account/...; посты (/p/, /reel/) автора в URL не содержат.
+func instagramPublisher(segs []string, _ url.Values) string {
+	if len(segs) == 0 {
+		return ""
+	}
+	switch strings.ToLower(segs[0]) {
+	case "stories":
+		if len(segs) > 1 && segs[1] != "highlights" {
+			return accountName(segs[1])
+		}
+		return ""
+	case "p", "reel", "reels", "tv", "explore", "accounts", "direct":
+		return ""
+	}
+	return accountName(segs[0])
+}
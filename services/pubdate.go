@@ -0,0 +1,118 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Даты публикации и последнего изменения из метаданных страницы:
+// JSON-LD (datePublished / dateModified), meta-теги article:* / og:* / itemprop
+// и первый <time datetime>. Нужны, чтобы понять, кто написал раньше.
+
+var (
+	publishedMetaKeys = []string{
+		"article:published_time", "og:published_time", "datepublished", "pubdate",
+		"publishdate", "publish_date", "date", "dc.date", "dc.date.issued", "sailthru.date",
+	}
+	modifiedMetaKeys = []string{
+		"article:modified_time", "og:updated_time", "datemodified", "dc.date.modified", "last-modified",
+	}
+)
+
+// extractPublishDates возвращает дату публикации и изменения (nil — не найдено).
+func extractPublishDates(htmlStr string) (published, modified *time.Time) {
+	for _, m := range ldJSONRe.FindAllStringSubmatch(htmlStr, -1) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(m[1])), &data); err != nil {
+			continue
+		}
+		collectLDDates(data, &published, &modified)
+	}
+	if published != nil && modified != nil {
+		return published, modified
+	}
+
+	doc, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		return published, modified
+	}
+	meta := map[string]string{}
+	var timeTag string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch strings.ToLower(n.Data) {
+			case "meta":
+				var key, content string
+				for _, attr := range n.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "property", "name", "itemprop", "http-equiv":
+						if key == "" {
+							key = strings.ToLower(attr.Val)
+						}
+					case "content":
+						content = attr.Val
+					}
+				}
+				if key != "" && content != "" {
+					if _, ok := meta[key]; !ok {
+						meta[key] = content
+					}
+				}
+			case "time":
+				for _, attr := range n.Attr {
+					if strings.ToLower(attr.Key) == "datetime" && timeTag == "" {
+						timeTag = attr.Val
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if published == nil {
+		for _, key := range publishedMetaKeys {
+			if t := parseLDDate(meta[key]); t != nil {
+				published = t
+				break
+			}
+		}
+	}
+	if published == nil {
+		published = parseLDDate(timeTag)
+	}
+	if modified == nil {
+		for _, key := range modifiedMetaKeys {
+			if t := parseLDDate(meta[key]); t != nil {
+				modified = t
+				break
+			}
+		}
+	}
+	return published, modified
+}
+
+func collectLDDates(v interface{}, published, modified **time.Time) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			collectLDDates(item, published, modified)
+		}
+	case map[string]interface{}:
+		if *published == nil {
+			*published = parseLDDate(ldString(t["datePublished"]))
+		}
+		if *modified == nil {
+			*modified = parseLDDate(ldString(t["dateModified"]))
+		}
+		if graph, ok := t["@graph"]; ok {
+			collectLDDates(graph, published, modified)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"text-analyzer/models"
+	"time"
+	"unicode/utf8"
+)
+
+// Разметка schema.org/ClaimReview для наших собственных вердиктов —
+// чтобы партнёры-фактчекеры и поисковики могли забирать результаты
+// в стандартном формате.
+
+// ClaimReviewLD — один ClaimReview в формате JSON-LD.
+type ClaimReviewLD struct {
+	Context       string         `json:"@context,omitempty"`
+	Type          string         `json:"@type"`
+	URL           string         `json:"url"`
+	ClaimReviewed string         `json:"claimReviewed"`
+	ReviewBody    string         `json:"reviewBody,omitempty"`
+	DatePublished string         `json:"datePublished"`
+	Author        LDOrganization `json:"author"`
+	ReviewRating  LDRating       `json:"reviewRating"`
+	ItemReviewed  LDClaim        `json:"itemReviewed"`
+}
+
+type LDOrganization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type LDRating struct {
+	Type          string `json:"@type"`
+	RatingValue   int    `json:"ratingValue,omitempty"`
+	BestRating    int    `json:"bestRating,omitempty"`
+	WorstRating   int    `json:"worstRating,omitempty"`
+	AlternateName string `json:"alternateName"`
+}
+
+type LDClaim struct {
+	Type       string           `json:"@type"`
+	Appearance []LDCreativeWork `json:"appearance,omitempty"`
+}
+
+type LDCreativeWork struct {
+	Type string `json:"@type"`
+	URL  string `json:"url"`
+}
+
+// LDDataFeed — лента ClaimReview (schema.org/DataFeed).
+type LDDataFeed struct {
+	Context         string          `json:"@context"`
+	Type            string          `json:"@type"`
+	Name            string          `json:"name"`
+	DateModified    string          `json:"dateModified"`
+	DataFeedElement []ClaimReviewLD `json:"dataFeedElement"`
+}
+
+const (
+	maxClaimReviewClaims = 10 // отдельных утверждений на один результат
+	ldClaimMaxRunes      = 300
+)
+
+// BuildClaimReviews превращает результат анализа в ClaimReview: общий вердикт
+// по тексту и отдельные утверждения без доказательств и мнения, выданные за факты.
+// pageURL — публичная страница результата, publisher — кто публикует проверку.
+func BuildClaimReviews(res *models.AnalysisResponse, pageURL string, published time.Time, publisher LDOrganization) []ClaimReviewLD {
+	publisher.Type = "Organization"
+	var item LDClaim
+	item.Type = "Claim"
+	if res.SourceURL != "" {
+		item.Appearance = []LDCreativeWork{{Type: "CreativeWork", URL: res.SourceURL}}
+	}
+	base := ClaimReviewLD{
+		Type:          "ClaimReview",
+		DatePublished: published.UTC().Format(time.RFC3339),
+		Author:        publisher,
+		ItemReviewed:  item,
+	}
+
+	var reviews []ClaimReviewLD
+	if summary := ldClaimText(res.Summary); summary != "" {
+		overall := base
+		overall.URL = pageURL
+		overall.ClaimReviewed = summary
+		overall.ReviewBody = ldClaimText(res.VerdictExplanation)
+		overall.ReviewRating = LDRating{
+			Type:          "Rating",
+			RatingValue:   min(max(res.CredibilityScore, 1), 10),
+			BestRating:    10,
+			WorstRating:   1,
+			AlternateName: overallRatingName(res),
+		}
+		// Единая шкала 1–5, если вердикт на неё попадает
+		if res.Rating != nil && res.Rating.Value > 0 {
+			overall.ReviewRating.RatingValue = res.Rating.Value
+			overall.ReviewRating.BestRating = 5
+		}
+		reviews = append(reviews, overall)
+	}
+
+	n := 0
+	addClaims := func(claims []string, rating string) {
+		for _, c := range claims {
+			if n >= maxClaimReviewClaims {
+				return
+			}
+			if c = ldClaimText(c); c == "" {
+				continue
+			}
+			n++
+			r := base
+			r.URL = fmt.Sprintf("%s#claim-%d", pageURL, n)
+			r.ClaimReviewed = c
+			r.ReviewRating = LDRating{Type: "Rating", AlternateName: rating}
+			reviews = append(reviews, r)
+		}
+	}
+	addClaims(res.FactCheck.MissingEvidence, "Не подтверждено")
+	addClaims(res.FactCheck.OpinionsAsFacts, "Мнение, выданное за факт")
+	return reviews
+}
+
+// overallRatingName — словесный вердикт: из ответа модели или по оценке.
+func overallRatingName(res *models.AnalysisResponse) string {
+	if v := strings.TrimSpace(res.FinalVerdict); v != "" {
+		return v
+	}
+	if res.Rating != nil && res.Rating.Label != RatingUnknown {
+		return res.Rating.Name
+	}
+	switch {
+	case res.CredibilityScore <= 3:
+		return "Недостоверно"
+	case res.CredibilityScore <= 6:
+		return "Сомнительно"
+	default:
+		return "Достоверно"
+	}
+}
+
+func ldClaimText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > ldClaimMaxRunes {
+		s = string([]rune(s)[:ldClaimMaxRunes]) + "…"
+	}
+	return s
+}
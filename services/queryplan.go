@@ -0,0 +1,514 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"text-analyzer/models"
+)
+
+const (
+	maxPlannedQueries  = 12 // всего запросов на текст вместе с переводами
+	minItemQueries     = 2  // запросов на языке оригинала на текст или утверждение
+	maxItemQueries     = 5
+	maxQueryWords      = 10
+	planTextLimitRunes = 3000
+)
+
+// QueryPlanner составляет поисковые запросы для проверки текста и отдельных
+// утверждений. С AI-клиентом запросы пишет модель (с переводами), иначе —
+// локальная эвристика, сохраняющая имена и числа.
+type QueryPlanner struct {
+	client AIClient // nil — только эвристика
+}
+
+func NewQueryPlanner(client AIClient, useAI bool) *QueryPlanner {
+	if !useAI {
+		client = nil
+	}
+	return &QueryPlanner{client: client}
+}
+
+// PlanText — запросы для проверки текста целиком. Запрос к модели ждёт
+// слота limiter (nil — слот уже занят вызывающим); usage — расход токенов
+// на планирование.
+func (p *QueryPlanner) PlanText(ctx context.Context, limiter AILimiter, text string, langs []string) (queries []models.SearchQuery, usage *models.TokenUsage) {
+	return p.plan(ctx, limiter, text, nil, langs)
+}
+
+// PlanClaims — запросы для проверки отдельных утверждений.
+func (p *QueryPlanner) PlanClaims(ctx context.Context, limiter AILimiter, text string, claims []string, langs []string) (queries []models.SearchQuery, usage *models.TokenUsage) {
+	if len(claims) == 0 {
+		return nil, nil
+	}
+	return p.plan(ctx, limiter, text, claims, langs)
+}
+
+func (p *QueryPlanner) plan(ctx context.Context, limiter AILimiter, text string, claims []string, langs []string) ([]models.SearchQuery, *models.TokenUsage) {
+	var usage *models.TokenUsage
+	if p != nil && p.client != nil {
+		queries, u, err := p.planAI(ctx, limiter, text, claims, langs)
+		usage = u
+		if err == nil && len(queries) > 0 {
+			log.Printf("[PLANNER] 🧭 AI составил %d поисковых запросов", len(queries))
+			return queries, usage
+		}
+		log.Printf("[PLANNER] ⚠ AI-планирование не удалось (%v), использую эвристику", err)
+	}
+	var queries []models.SearchQuery
+	if len(claims) == 0 {
+		queries = heuristicQueries(text, "", langs)
+	} else {
+		for _, c := range claims {
+			queries = append(queries, heuristicQueries(c, c, langs)...)
+		}
+	}
+	queries = dedupQueries(queries)
+	log.Printf("[PLANNER] 🧭 Эвристика составила %d поисковых запросов", len(queries))
+	return queries, usage
+}
+
+// maxAnalysisQueries — сколько запросов может составить планировщик за
+// анализ: по тексту (с переводами на langs-1 языков) и по утверждениям.
+func maxAnalysisQueries(langs int) int {
+	return maxItemQueries + max(langs-1, 0) + maxPlannedQueries
+}
+
+// ── AI ───────────────────────────────────────────────────────────────────────
+
+func (p *QueryPlanner) planAI(ctx context.Context, limiter AILimiter, text string, claims []string, langs []string) ([]models.SearchQuery, *models.TokenUsage, error) {
+	if runes := []rune(text); len(runes) > planTextLimitRunes {
+		text = string(runes[:planTextLimitRunes])
+	}
+	task := "2–5 запросов для проверки главных фактов текста"
+	claimsBlock := ""
+	if len(claims) > 0 {
+		task = "2–5 запросов на каждое утверждение (в поле claim — утверждение дословно)"
+		var sb strings.Builder
+		sb.WriteString("\nУТВЕРЖДЕНИЯ ДЛЯ ПРОВЕРКИ:\n")
+		for _, c := range claims {
+			sb.WriteString("- " + c + "\n")
+		}
+		claimsBlock = sb.String()
+	}
+
+	prompt := fmt.Sprintf(`Составь поисковые запросы для проверки фактов. Верни ТОЛЬКО JSON без markdown.
+
+ТЕКСТ:
+%s
+%s
+Правила:
+- %s;
+- сохраняй имена людей, организации, места, даты и числа;
+- сначала запрос на языке текста, затем его переводы на языки: %s (поле lang — код языка);
+- 4–10 слов, без кавычек и поисковых операторов.
+
+Ответ:
+{"language": "код языка текста", "queries": [{"query": "...", "lang": "ru", "claim": ""}]}`,
+		text, claimsBlock, task, strings.Join(langs, ", "))
+
+	if limiter != nil {
+		release, err := limiter.AcquireAI(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer release()
+	}
+	raw, usage, err := p.client.Analyze(prompt)
+	if err != nil {
+		return nil, usage, err
+	}
+	var plan struct {
+		Language string               `json:"language"`
+		Queries  []models.SearchQuery `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(raw)), &plan); err != nil {
+		return nil, usage, fmt.Errorf("parse plan: %w", err)
+	}
+
+	allowed := map[string]bool{}
+	for _, l := range langs {
+		allowed[l] = true
+	}
+	var queries []models.SearchQuery
+	for _, q := range plan.Queries {
+		q.Query = strings.Join(strings.Fields(strings.Trim(q.Query, `"'«»`)), " ")
+		if q.Query == "" {
+			continue
+		}
+		q.Lang = strings.ToLower(strings.TrimSpace(q.Lang))
+		if !allowed[q.Lang] {
+			q.Lang = ""
+		}
+		q.Source = "ai"
+		queries = append(queries, q)
+	}
+	return dedupQueries(queries), usage, nil
+}
+
+// ── Эвристика ────────────────────────────────────────────────────────────────
+
+// Стоп-слова: служебные слова русского, английского и румынского языков
+// (по спискам Snowball) и предлоги вроде «согласно» / «conform». Румынские
+// слова даны с седилью; варианты с запятой снизу (ș, ț) добавляются в init.
+var queryStopWords = map[string]bool{}
+
+// queryLangStopWords — те же слова по языкам: по ним определяется язык текста.
+var queryLangStopWords = map[string]map[string]bool{}
+
+var queryStopWordLists = map[string]string{
+	"ru": `и в во не что он на я с со как а то все она так его но да ты к у же вы за
+	бы по только ее её мне было вот от меня еще ещё нет о из ему теперь когда даже ну
+	вдруг ли если уже или ни быть был него до вас нибудь опять уж вам ведь там
+	потом себя ничего ей может они тут где есть надо ней для мы тебя их чем была
+	сам чтоб без будто чего раз тоже себе под будет ж тогда кто этот того потому
+	этого какой совсем ним здесь этом один почти мой тем чтобы нее неё сейчас были
+	куда зачем всех никогда можно при наконец два об другой хоть после над
+	больше тот через эти нас про всего них какая много разве три эту моя
+	впрочем хорошо свою этой перед иногда лучше чуть том нельзя такой им более
+	всегда конечно всю между это также который которая которое которые которых
+	является являются однако поэтому свой своих своего согласно благодаря вопреки`,
+	"en": `i me my myself we our ours ourselves you your yours yourself yourselves he
+	him his himself she her hers herself it its itself they them their theirs
+	themselves what which who whom this that these those am is are was were be
+	been being have has had having do does did doing a an the and but if or
+	because as until while of at by for with about against between into through
+	during before after above below to from up down in out on off over under
+	again further then once here there when where why how all any both each few
+	more most other some such no nor not only own same so than too very can
+	will just should now also would could`,
+	"ro": `a abia acea aceasta această aceea aceeaşi acei aceia acel acela acelaşi
+	acele acelea acest acesta aceste acestea acestei acestor acestui aceşti
+	aceştia acolo acum ai aia aici al ale alt alta altceva alte altfel alţi
+	alţii am apoi ar are as aş asta astfel asupra atât atâta atâtea atâţi
+	atâţia atunci au avea avem aveţi avut aşa ba bine ca care cât câte câţi
+	către ce cea ceea cei cel cele celor ceva chiar ci cine cineva cu cum cumva
+	da dacă dar de deci deja deşi despre din dintr dintre doar după ea ei el
+	ele era este eu fi fie fiecare fiind foarte fost fără iar ii îi îl îmi
+	împotriva în înainte între încât încă la le li lor lui mai mult multe
+	mulţi ne nici nimic niciodată noi nostru nouă nu numai o oricare orice pe
+	pentru peste poate pot prea prin sa să se sau sunt spre sub şi tot toate
+	toţi totul tu un una unde unei unor unui unul va vă voi vom vor
+	conform potrivit datorită graţie contrar privind`,
+}
+
+func init() {
+	commaBelow := strings.NewReplacer("ş", "ș", "ţ", "ț")
+	for lang, list := range queryStopWordLists {
+		words := map[string]bool{}
+		for _, w := range strings.Fields(list) {
+			words[w] = true
+			words[commaBelow.Replace(w)] = true
+		}
+		queryLangStopWords[lang] = words
+		for w := range words {
+			queryStopWords[w] = true
+		}
+	}
+}
+
+var (
+	// Последовательности слов с заглавной буквы — имена, организации, места
+	// (в пределах строки: заголовок не склеивается с первым словом текста)
+	entityRe = regexp.MustCompile(`\p{Lu}[\p{L}\-]+(?:[ \t]+\p{Lu}[\p{L}\-]+)*`)
+	// Числа с единицами измерения
+	numberRe  = regexp.MustCompile(`\d[\d\s.,]*\d%?|\d+%?`)
+	wordRe    = regexp.MustCompile(`[\p{L}\-]+`)
+	queryTrim = regexp.MustCompile(`\s+`)
+)
+
+// heuristicQueries — 2–5 запросов на языке текста (заголовок, имена с
+// числами и частыми словами, только имена и числа, только частые слова,
+// первое предложение) и «переводы» на остальные языки поиска. Слова
+// эвристика не переводит: перевод — имена (для латиницы — в транслитерации)
+// и числа, одинаковые на всех языках. claim — утверждение, к которому
+// относятся запросы ("" — текст целиком, тогда первая строка — заголовок).
+func heuristicQueries(text, claim string, langs []string) []models.SearchQuery {
+	lang := detectQueryLang(text, langs)
+	var variants []string
+	if claim == "" {
+		variants = append(variants, titleQuery(text))
+	}
+	variants = append(variants,
+		composeQuery(text, queryEntities|queryNumbers|queryKeywords, false),
+		composeQuery(text, queryEntities|queryNumbers, false),
+		composeQuery(text, queryKeywords, false),
+		leadQuery(text, claim == ""))
+
+	var originals []models.SearchQuery
+	seen := map[string]bool{}
+	for _, v := range variants {
+		if v == "" || seen[normalizeQuery(v)] {
+			continue
+		}
+		seen[normalizeQuery(v)] = true
+		originals = append(originals, models.SearchQuery{Query: v, Lang: lang, Claim: claim, Source: "heuristic"})
+		if len(originals) == maxItemQueries {
+			break
+		}
+	}
+
+	var translations []models.SearchQuery
+	if lang != "" {
+		for _, target := range langs {
+			if target == lang {
+				continue
+			}
+			if q := composeQuery(text, queryEntities|queryNumbers, !cyrillicLangs[target]); q != "" {
+				translations = append(translations, models.SearchQuery{Query: q, Lang: target, Claim: claim, Source: "heuristic"})
+			}
+		}
+	}
+
+	// Сначала два запроса на языке оригинала, затем переводы, затем
+	// остальные: при общем лимите у каждого утверждения остаются и те, и другие
+	n := min(len(originals), minItemQueries)
+	out := append([]models.SearchQuery{}, originals[:n]...)
+	out = append(out, translations...)
+	return append(out, originals[n:]...)
+}
+
+// detectQueryLang — язык текста среди языков поиска по служебным словам;
+// "" — не удалось определить.
+func detectQueryLang(text string, langs []string) string {
+	hits := map[string]int{}
+	for _, w := range wordRe.FindAllString(strings.ToLower(text), -1) {
+		for lang, words := range queryLangStopWords {
+			if words[w] {
+				hits[lang]++
+			}
+		}
+	}
+	best := ""
+	for _, l := range langs {
+		if hits[l] > hits[best] {
+			best = l
+		}
+	}
+	return best
+}
+
+// titleQuery — первая строка текста, если она похожа на заголовок.
+func titleQuery(text string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+	words := strings.Fields(title)
+	if len(words) < 3 || len(words) > 20 || title == strings.TrimSpace(text) {
+		return ""
+	}
+	if len(words) > maxQueryWords+2 {
+		words = words[:maxQueryWords+2]
+	}
+	return strings.Join(words, " ")
+}
+
+// leadQuery — содержательные слова первого предложения (после заголовка,
+// если skipTitle).
+func leadQuery(text string, skipTitle bool) string {
+	text = strings.TrimSpace(text)
+	if _, body, ok := strings.Cut(text, "\n"); skipTitle && ok {
+		text = strings.TrimSpace(body)
+	}
+	if loc := sentenceEndRe.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	var words []string
+	for _, w := range strings.Fields(text) {
+		w = strings.Trim(w, `.,;:!?«»"'()[]—–`)
+		if w == "" || queryStopWords[strings.ToLower(w)] {
+			continue
+		}
+		words = append(words, w)
+		if len(words) == maxQueryWords {
+			break
+		}
+	}
+	if len(words) < 3 {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// Части эвристического запроса.
+const (
+	queryEntities = 1 << iota
+	queryNumbers
+	queryKeywords
+)
+
+// heuristicQuery строит запрос: сначала имена собственные, затем числа,
+// затем частые содержательные слова — не длиннее maxQueryWords слов.
+func heuristicQuery(text string) string {
+	return composeQuery(text, queryEntities|queryNumbers|queryKeywords, false)
+}
+
+// composeQuery собирает запрос из выбранных частей текста (не короче двух
+// слов); translit — имена кириллицей пишутся латиницей.
+func composeQuery(text string, parts int, translit bool) string {
+	var out []string
+	words := 0
+	add := func(s string) bool {
+		if translit {
+			s = transliterate(s)
+		}
+		n := len(strings.Fields(s))
+		if words+n > maxQueryWords {
+			return false
+		}
+		for _, p := range out {
+			if strings.EqualFold(p, s) || strings.Contains(strings.ToLower(p), strings.ToLower(s)) {
+				return true
+			}
+		}
+		out = append(out, s)
+		words += n
+		return true
+	}
+
+	if parts&queryEntities != 0 {
+		for _, e := range topEntities(text, 3) {
+			add(e)
+		}
+	}
+	if parts&queryNumbers != 0 {
+		for i, n := range numberRe.FindAllString(text, -1) {
+			if i >= 2 {
+				break
+			}
+			add(queryTrim.ReplaceAllString(strings.TrimSpace(n), " "))
+		}
+	}
+	if parts&queryKeywords != 0 {
+		for _, w := range topKeywords(text, maxQueryWords) {
+			if !add(w) {
+				break
+			}
+		}
+	}
+	if words < 2 {
+		return ""
+	}
+	return strings.Join(out, " ")
+}
+
+// cyrillicLangs — языки поиска, которые пишут кириллицей.
+var cyrillicLangs = map[string]bool{"ru": true, "uk": true, "be": true, "bg": true, "sr": true, "mk": true}
+
+var cyrillicToLatin = strings.NewReplacer(
+	"а", "a", "б", "b", "в", "v", "г", "g", "д", "d", "е", "e", "ё", "yo", "ж", "zh",
+	"з", "z", "и", "i", "й", "y", "к", "k", "л", "l", "м", "m", "н", "n", "о", "o",
+	"п", "p", "р", "r", "с", "s", "т", "t", "у", "u", "ф", "f", "х", "h", "ц", "ts",
+	"ч", "ch", "ш", "sh", "щ", "sch", "ъ", "", "ы", "y", "ь", "", "э", "e", "ю", "yu", "я", "ya",
+	"А", "A", "Б", "B", "В", "V", "Г", "G", "Д", "D", "Е", "E", "Ё", "Yo", "Ж", "Zh",
+	"З", "Z", "И", "I", "Й", "Y", "К", "K", "Л", "L", "М", "M", "Н", "N", "О", "O",
+	"П", "P", "Р", "R", "С", "S", "Т", "T", "У", "U", "Ф", "F", "Х", "H", "Ц", "Ts",
+	"Ч", "Ch", "Ш", "Sh", "Щ", "Sch", "Ъ", "", "Ы", "Y", "Ь", "", "Э", "E", "Ю", "Yu", "Я", "Ya",
+)
+
+// transliterate пишет кириллицу латиницей без диакритики — так имена
+// находятся и в румынских, и в английских текстах.
+func transliterate(s string) string {
+	return cyrillicToLatin.Replace(s)
+}
+
+// topEntities — самые частые имена собственные. Одиночное слово с заглавной
+// в начале предложения (кроме аббревиатур) считается именем, только если
+// встречается дважды.
+func topEntities(text string, n int) []string {
+	counts := map[string]int{}
+	var order []string
+	for _, loc := range entityRe.FindAllStringIndex(text, -1) {
+		// «The Chisinau» — служебное слово в начале предложения не часть имени
+		for {
+			first, rest, ok := strings.Cut(text[loc[0]:loc[1]], " ")
+			if !ok || !queryStopWords[strings.ToLower(first)] {
+				break
+			}
+			loc[0] = loc[1] - len(strings.TrimLeft(rest, " \t"))
+		}
+		e := text[loc[0]:loc[1]]
+		if queryStopWords[strings.ToLower(e)] || len([]rune(e)) < 3 {
+			continue
+		}
+		if counts[e] == 0 {
+			order = append(order, e)
+		}
+		counts[e]++
+		if strings.Contains(e, " ") || e == strings.ToUpper(e) || !atSentenceStart(text, loc[0]) {
+			counts[e]++
+		}
+	}
+	var entities []string
+	for _, e := range order {
+		if counts[e] >= 2 {
+			entities = append(entities, e)
+		}
+	}
+	sort.SliceStable(entities, func(i, j int) bool { return counts[entities[i]] > counts[entities[j]] })
+	if len(entities) > n {
+		entities = entities[:n]
+	}
+	return entities
+}
+
+func atSentenceStart(text string, pos int) bool {
+	prefix := strings.TrimRight(text[:pos], " \t")
+	return prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], ".!?\n:«\"")
+}
+
+// topKeywords — частые слова длиннее 4 букв без стоп-слов.
+func topKeywords(text string, n int) []string {
+	counts := map[string]int{}
+	var order []string
+	for _, w := range wordRe.FindAllString(strings.ToLower(text), -1) {
+		if len([]rune(w)) <= 4 || queryStopWords[w] {
+			continue
+		}
+		if counts[w] == 0 {
+			order = append(order, w)
+		}
+		counts[w]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	if len(order) > n {
+		order = order[:n]
+	}
+	return order
+}
+
+// dedupQueries убирает повторы и оставляет не больше maxPlannedQueries
+// запросов, беря их у утверждений по очереди: лимит не съедает последние
+// утверждения целиком.
+func dedupQueries(queries []models.SearchQuery) []models.SearchQuery {
+	seen := map[string]bool{}
+	groups := map[string][]models.SearchQuery{}
+	var claims []string
+	for _, q := range queries {
+		key := q.Lang + "|" + normalizeQuery(q.Query)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := groups[q.Claim]; !ok {
+			claims = append(claims, q.Claim)
+		}
+		groups[q.Claim] = append(groups[q.Claim], q)
+	}
+	var out []models.SearchQuery
+	for i := 0; len(out) < maxPlannedQueries; i++ {
+		added := false
+		for _, c := range claims {
+			if i < len(groups[c]) && len(out) < maxPlannedQueries {
+				out = append(out, groups[c][i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return out
+}
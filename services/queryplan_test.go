@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"text-analyzer/models"
+	"time"
+)
+
+func TestHeuristicPlanner(t *testing.T) {
+	p := NewQueryPlanner(nil, false)
+	tests := []struct {
+		name   string
+		text   string
+		claims []string
+		want   []string // должно войти в запросы
+	}{
+		{
+			name: "ru news",
+			text: "Минздрав сообщил о вспышке кори в Бельцах\nМинздрав Молдовы сообщил, что в Бельцах за неделю выявлено 15 случаев кори. " +
+				"По данным ведомства, большинство заболевших — дети, которые не были привиты. В Бельцах открыт дополнительный пункт вакцинации.",
+			want: []string{"Минздрав сообщил о вспышке кори в Бельцах", "Бельцах", "Минздрав Молдовы", "15"},
+		},
+		{
+			name: "en news",
+			text: "City council approves new cycling lanes\nThe Chisinau city council approved 40 kilometres of protected cycling lanes on Thursday. " +
+				"According to the mayor, construction will start in spring and will be funded by a regional grant. Shop owners in Chisinau raised concerns about deliveries.",
+			want: []string{"City council approves new cycling lanes", "Chisinau", "40", "cycling"},
+		},
+		{
+			name: "ro news",
+			text: "Guvernul plafonează prețul la energie\nGuvernul Republicii Moldova a decis că prețul la energia electrică va fi plafonat la 3,5 lei pentru consumatorii casnici. " +
+				"Conform datelor Agenției Naționale pentru Reglementare în Energetică, măsura va costa bugetul 2 miliarde de lei.",
+			want: []string{"Guvernul Republicii Moldova", "3,5"},
+		},
+		{
+			name: "claims",
+			claims: []string{
+				"Potrivit ANRE, prețul la gaz a crescut cu 40% în Chișinău",
+				"NASA confirmed that the asteroid 2024 YR4 will miss the Earth",
+				"Согласно опросу, 60% жителей Кишинёва против повышения тарифов",
+			},
+			want: []string{"ANRE", "Chișinău", "40%", "2024", "asteroid", "60%", "тарифов"},
+		},
+	}
+	for _, tt := range tests {
+		var queries []models.SearchQuery
+		if tt.claims != nil {
+			queries, _ = p.PlanClaims(context.Background(), nil, tt.text, tt.claims, nil)
+		} else {
+			queries, _ = p.PlanText(context.Background(), nil, tt.text, nil)
+		}
+		// 2–5 запросов на текст и на каждое утверждение
+		perItem := map[string]int{}
+		for _, q := range queries {
+			perItem[q.Claim]++
+		}
+		items := tt.claims
+		if items == nil {
+			items = []string{""}
+		}
+		for _, c := range items {
+			if n := perItem[c]; n < minItemQueries || n > maxItemQueries {
+				t.Errorf("%s: %d queries for %q, want %d..%d", tt.name, n, c, minItemQueries, maxItemQueries)
+			}
+		}
+
+		var all []string
+		for i, q := range queries {
+			all = append(all, q.Query)
+			if q.Source != "heuristic" {
+				t.Errorf("%s: source %q", tt.name, q.Source)
+			}
+			if strings.ContainsAny(q.Query, "\n\t") {
+				t.Errorf("%s: query %q spans lines", tt.name, q.Query)
+			}
+			// Заголовок идёт как есть; в остальных запросах нет служебных слов
+			if tt.claims == nil && i == 0 {
+				continue
+			}
+			words := strings.Fields(q.Query)
+			if len(words) > maxQueryWords {
+				t.Errorf("%s: %d words in %q", tt.name, len(words), q.Query)
+			}
+			for _, w := range words {
+				if queryStopWords[strings.ToLower(w)] {
+					t.Errorf("%s: stop word %q in %q", tt.name, w, q.Query)
+				}
+			}
+		}
+		joined := strings.Join(all, " | ")
+		for _, w := range tt.want {
+			if !strings.Contains(joined, w) {
+				t.Errorf("%s: %q missing from %s", tt.name, w, joined)
+			}
+		}
+	}
+}
+
+func TestHeuristicPlannerTranslations(t *testing.T) {
+	p := NewQueryPlanner(nil, false)
+	langs := []string{"ru", "en", "ro"}
+	claims := []string{
+		"Potrivit ANRE, prețul la gaz a crescut cu 40% în Chișinău",
+		"NASA confirmed that the asteroid 2024 YR4 will miss the Earth",
+		"Согласно опросу, 60% жителей Кишинёва против повышения тарифов",
+	}
+	source := map[string]string{claims[0]: "ro", claims[1]: "en", claims[2]: "ru"}
+
+	queries, _ := p.PlanClaims(context.Background(), nil, "", claims, langs)
+	if len(queries) > maxPlannedQueries {
+		t.Errorf("%d queries, limit %d", len(queries), maxPlannedQueries)
+	}
+	byLang := map[string]map[string][]string{}
+	for _, q := range queries {
+		if byLang[q.Claim] == nil {
+			byLang[q.Claim] = map[string][]string{}
+		}
+		byLang[q.Claim][q.Lang] = append(byLang[q.Claim][q.Lang], q.Query)
+	}
+	for _, c := range claims {
+		if n := len(byLang[c][source[c]]); n < minItemQueries || n > maxItemQueries {
+			t.Errorf("%q: %d queries in %s, want %d..%d", c, n, source[c], minItemQueries, maxItemQueries)
+		}
+		for _, l := range langs {
+			if l != source[c] && len(byLang[c][l]) != 1 {
+				t.Errorf("%q: translations to %s = %v, want one", c, l, byLang[c][l])
+			}
+		}
+	}
+
+	// Имена кириллицей в запросах на латинице транслитерируются
+	if got := byLang[claims[2]]["en"]; len(got) == 0 || !strings.Contains(got[0], "Kishinyova") || !strings.Contains(got[0], "60%") {
+		t.Errorf("en translation of a Russian claim = %v", got)
+	}
+	if got := byLang[claims[1]]["ro"]; len(got) == 0 || !strings.Contains(got[0], "NASA") {
+		t.Errorf("ro translation of an English claim = %v", got)
+	}
+
+	// Текст на языке вне языков поиска ищется во всех локалях, без переводов
+	queries, _ = p.PlanText(context.Background(), nil, "Die Regierung hat die Energiepreise für Haushalte eingefroren und 2 Milliarden bereitgestellt", langs)
+	for _, q := range queries {
+		if q.Lang != "" {
+			t.Errorf("query %q tagged %q for an undetected language", q.Query, q.Lang)
+		}
+	}
+}
+
+func TestDetectQueryLang(t *testing.T) {
+	langs := []string{"ru", "en", "ro"}
+	tests := []struct {
+		text  string
+		langs []string
+		want  string
+	}{
+		{"Минздрав сообщил, что в Бельцах выявлено 15 случаев кори", langs, "ru"},
+		{"The city council approved the plan on Thursday", langs, "en"},
+		{"Guvernul a decis că prețul va fi plafonat pentru consumatori", langs, "ro"},
+		{"ANRE 2024", langs, ""},
+		{"Минздрав сообщил о вспышке кори", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := detectQueryLang(tt.text, tt.langs); got != tt.want {
+			t.Errorf("detectQueryLang(%q, %v) = %q, want %q", tt.text, tt.langs, got, tt.want)
+		}
+	}
+}
+
+func TestQueryStopWords(t *testing.T) {
+	for _, w := range []string{"и", "которые", "the", "would", "și", "şi", "în", "conform", "согласно"} {
+		if !queryStopWords[w] {
+			t.Errorf("%q must be a stop word", w)
+		}
+	}
+	for _, w := range []string{"datelor", "guvernul", "prețul", "минздрав", "council", "energie"} {
+		if queryStopWords[w] {
+			t.Errorf("%q is a content word, not a stop word", w)
+		}
+	}
+}
+
+// planTestAI отвечает планом из одного запроса и считает обращения.
+type planTestAI struct{ calls int }
+
+func (a *planTestAI) Analyze(string) (string, *models.TokenUsage, error) {
+	a.calls++
+	return `{"language":"ro","queries":[{"query":"plafonare pret energie Moldova","lang":"ro"}]}`,
+		&models.TokenUsage{PromptTokens: 300, CompletionTokens: 40, TotalTokens: 340}, nil
+}
+
+func TestAIPlannerUsesAnalyzerSlot(t *testing.T) {
+	ai := &planTestAI{}
+	p := NewQueryPlanner(ai, true)
+	analyzer := &AnalyzerService{sem: make(chan struct{}, 1)}
+	text := "Guvernul plafonează prețul la energie până la sfârșitul anului"
+
+	queries, usage := p.PlanText(context.Background(), analyzer, text, []string{"ro"})
+	if len(queries) != 1 || queries[0].Source != "ai" {
+		t.Fatalf("queries = %+v, want the AI plan", queries)
+	}
+	if usage == nil || usage.TotalTokens != 340 {
+		t.Errorf("usage = %+v, want the planner's tokens", usage)
+	}
+	if len(analyzer.sem) != 0 || analyzer.waiting.Load() != 0 {
+		t.Error("analyzer slot not released")
+	}
+
+	// Слот занят другим анализом: планировщик не обращается к модели в обход
+	// очереди, а после отмены ожидания переходит на эвристику
+	analyzer.sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	queries, usage = p.PlanText(ctx, analyzer, text, []string{"ro"})
+	if ai.calls != 1 {
+		t.Errorf("model called %d times, want 1 (second call must wait for the slot)", ai.calls)
+	}
+	if len(queries) == 0 || queries[0].Source != "heuristic" || usage != nil {
+		t.Errorf("queries = %+v usage = %+v, want heuristic fallback", queries, usage)
+	}
+}
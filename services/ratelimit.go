@@ -23,6 +23,10 @@ type RateLimitInfo struct {
 	ResetTokens     string `json:"reset_tokens"`      // e.g. "1m30s"
 	ResetTokensAt   *int64 `json:"reset_tokens_at"`   // unix ms, if parseable
 
+	// Search providers (Serper и т.п.)
+	Credits   *int `json:"credits,omitempty"`    // остаток оплаченных запросов
+	UsedToday int  `json:"used_today,omitempty"` // запросов за сутки (UTC)
+
 	// Derived
 	Throttled   bool   `json:"throttled"`    // true if last response was 429
 	StatusCode  int    `json:"status_code"`  // last HTTP status
@@ -73,8 +77,12 @@ func UpdateRateLimit(provider string, resp *http.Response, statusCode int) {
 		}
 	}
 
+	storeRateLimit(info)
+}
+
+func storeRateLimit(info *RateLimitInfo) {
 	rlMu.Lock()
-	rlStore[provider] = info
+	rlStore[info.Provider] = info
 	rlMu.Unlock()
 }
 
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"text-analyzer/models"
+	"unicode"
+)
+
+// Единая порядковая шкала оценок: от 1 (ложь) до 5 (правда).
+// «Не подтверждено» и «без оценки» на шкалу не попадают (значение 0).
+const (
+	RatingFalse       = "false"
+	RatingMostlyFalse = "mostly_false"
+	RatingMixed       = "mixed"
+	RatingMostlyTrue  = "mostly_true"
+	RatingTrue        = "true"
+	RatingUnproven    = "unproven"
+	RatingUnknown     = "unknown"
+)
+
+var ratingScale = map[string]struct {
+	Value int
+	Name  string
+}{
+	RatingFalse:       {1, "Ложь"},
+	RatingMostlyFalse: {2, "В основном ложь"},
+	RatingMixed:       {3, "Частично правда"},
+	RatingMostlyTrue:  {4, "В основном правда"},
+	RatingTrue:        {5, "Правда"},
+	RatingUnproven:    {0, "Не подтверждено"},
+	RatingUnknown:     {0, "Без оценки"},
+}
+
+// ratingAliases — таблица соответствия: оценки фактчекеров и наши вердикты
+// на разных языках. Сравнение идёт без регистра, диакритики и пунктуации,
+// так что «PARȚIAL ADEVĂRAT» и «partial adevarat» — одно и то же.
+var ratingAliases = map[string][]string{
+	RatingFalse: {
+		// en
+		"false", "false claim", "fake", "pants on fire", "incorrect", "wrong", "fabricated", "hoax", "not true", "untrue", "scam",
+		// ro
+		"fals", "falsa", "fals complet", "inventat", "neadevarat",
+		// ru / uk
+		"ложь", "ложно", "ложное утверждение", "неправда", "фейк", "фальшивка", "выдумка", "дезинформация", "недостоверно", "брехня", "неправда повністю",
+	},
+	RatingMostlyFalse: {
+		"mostly false", "misleading", "manipulated", "distorted", "exaggerated", "out of context", "false context",
+		"majoritar fals", "preponderent fals", "in mare parte fals", "inselator", "manipulare", "manipulat", "scos din context",
+		"в основном ложь", "скорее ложь", "вводит в заблуждение", "манипуляция", "искажение", "преувеличение", "вырвано из контекста",
+	},
+	RatingMixed: {
+		"half true", "partly true", "partly false", "partially true", "partially false", "mixture", "mixed", "missing context", "needs context",
+		"partial adevarat", "partial fals", "adevarat partial", "jumatate adevarat", "lipsa context",
+		"частично правда", "частично ложь", "частично верно", "полуправда", "смешанно", "сомнительно", "нет контекста",
+	},
+	RatingMostlyTrue: {
+		"mostly true", "mostly correct", "largely accurate",
+		"majoritar adevarat", "preponderent adevarat", "in mare parte adevarat",
+		"в основном правда", "скорее правда", "в основном верно",
+	},
+	RatingTrue: {
+		"true", "correct", "accurate", "verified", "confirmed", "real",
+		"adevarat", "corect", "confirmat", "veridic",
+		"правда", "верно", "достоверно", "подтверждено", "соответствует действительности",
+	},
+	RatingUnproven: {
+		"unproven", "unverified", "unsupported", "no evidence", "unsubstantiated", "suspect", "suspicious", "cannot be verified",
+		"nefondat", "neverificat", "nedovedit", "fara dovezi",
+		"не подтверждено", "необоснованно", "не доказано", "нет доказательств", "не проверено", "непроверенно", "мнение выданное за факт",
+	},
+}
+
+var (
+	ratingIndex map[string]string // нормализованный алиас → метка
+	ratingNumRe = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)\s*(?:/|из|out of|din)\s*(\d+)$`)
+)
+
+func init() {
+	ratingIndex = map[string]string{}
+	for label, aliases := range ratingAliases {
+		for _, a := range aliases {
+			ratingIndex[normalizeRatingText(a)] = label
+		}
+	}
+}
+
+var diacriticsReplacer = strings.NewReplacer(
+	"ă", "a", "â", "a", "î", "i", "ș", "s", "ş", "s", "ț", "t", "ţ", "t",
+	"é", "e", "è", "e", "ê", "e", "á", "a", "à", "a", "ó", "o", "ú", "u", "í", "i", "ё", "е",
+)
+
+// normalizeRatingText — нижний регистр, без диакритики, пунктуации и эмодзи.
+func normalizeRatingText(s string) string {
+	s = diacriticsReplacer.Replace(strings.ToLower(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '/' || r == '.' || r == ',' {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func newRating(label string, confidence float64, original string) *models.NormalizedRating {
+	scale := ratingScale[label]
+	return &models.NormalizedRating{
+		Label:      label,
+		Value:      scale.Value,
+		Name:       scale.Name,
+		Confidence: float64(int(confidence*100)) / 100,
+		Original:   original,
+	}
+}
+
+// NormalizeRating переводит оценку фактчекера или наш вердикт на единую шкалу.
+// Порядок: точное совпадение с таблицей → числовая оценка «2/5» → самый
+// длинный алиас внутри текста → похожесть по триграммам.
+func NormalizeRating(text string) *models.NormalizedRating {
+	norm := normalizeRatingText(text)
+	if norm == "" {
+		return newRating(RatingUnknown, 0, text)
+	}
+	if label, ok := ratingIndex[norm]; ok {
+		return newRating(label, 1, text)
+	}
+	if m := ratingNumRe.FindStringSubmatch(norm); m != nil {
+		value, _ := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		best, _ := strconv.ParseFloat(m[2], 64)
+		if best > 0 && value >= 0 && value <= best {
+			return newRating(labelForScore(value/best), 0.9, text)
+		}
+	}
+
+	// «FALS: fotografia este veche», «NEFONDAT/SUSPECT»; отрицание перед
+	// алиасом («Nu este adevărat», «не верно») переворачивает оценку.
+	padded := " " + strings.ReplaceAll(norm, "/", " ") + " "
+	bestAlias, bestLabel, bestAt := "", "", -1
+	for alias, label := range ratingIndex {
+		if len(alias) <= len(bestAlias) {
+			continue
+		}
+		if at := strings.Index(padded, " "+alias+" "); at >= 0 {
+			bestAlias, bestLabel, bestAt = alias, label, at
+		}
+	}
+	if bestLabel != "" {
+		before := strings.Fields(padded[:bestAt])
+		if len(before) > ratingNegationWindow {
+			before = before[len(before)-ratingNegationWindow:]
+		}
+		if hasRatingNegation(before) {
+			return negatedRating(bestLabel, 0.8, text)
+		}
+		return newRating(bestLabel, 0.8, text)
+	}
+
+	// Опечатки и словоформы: «Adevărată», «Fasl»
+	grams := trigrams(norm)
+	bestSim := 0.0
+	for alias, label := range ratingIndex {
+		if sim := trigramSimilarity(grams, trigrams(alias)); sim > bestSim {
+			bestSim, bestLabel = sim, label
+		}
+	}
+	if bestSim >= 0.45 {
+		if hasRatingNegation(strings.Fields(norm)) {
+			return negatedRating(bestLabel, bestSim*0.7, text)
+		}
+		return newRating(bestLabel, bestSim*0.7, text)
+	}
+	return newRating(RatingUnknown, 0, text)
+}
+
+// Отрицания на en / ro / ru; «isn't» после нормализации — «isn t».
+var ratingNegations = map[string]bool{
+	"not": true, "no": true, "isn": true, "aren": true, "wasn": true, "never": true,
+	"nu": true, "nici": true,
+	"не": true, "нет": true, "ни": true,
+}
+
+// ratingNegationWindow — сколько слов перед алиасом проверяется на отрицание
+// («nu este adevarat», «is not entirely accurate»).
+const ratingNegationWindow = 3
+
+func hasRatingNegation(words []string) bool {
+	for _, w := range words {
+		if ratingNegations[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// ratingOpposites — во что превращается оценка под отрицанием.
+var ratingOpposites = map[string]string{
+	RatingTrue:        RatingFalse,
+	RatingFalse:       RatingTrue,
+	RatingMostlyTrue:  RatingMostlyFalse,
+	RatingMostlyFalse: RatingMostlyTrue,
+	RatingMixed:       RatingMixed,
+}
+
+// negatedRating — оценка с отрицанием; «не без оценки» и «не подтверждено»
+// под отрицанием ничего не значат и остаются без оценки.
+func negatedRating(label string, confidence float64, original string) *models.NormalizedRating {
+	if opposite, ok := ratingOpposites[label]; ok {
+		return newRating(opposite, confidence, original)
+	}
+	return newRating(RatingUnknown, 0, original)
+}
+
+// labelForScore — метка для доли от максимальной оценки (0..1).
+func labelForScore(frac float64) string {
+	switch {
+	case frac < 0.3:
+		return RatingFalse
+	case frac < 0.5:
+		return RatingMostlyFalse
+	case frac < 0.7:
+		return RatingMixed
+	case frac < 0.9:
+		return RatingMostlyTrue
+	default:
+		return RatingTrue
+	}
+}
+
+// NormalizeVerdict — наш итоговый вердикт на единой шкале. Если вердикт модели
+// не распознан, шкала выводится из оценки достоверности (1–10).
+func NormalizeVerdict(res *models.AnalysisResponse) *models.NormalizedRating {
+	rating := NormalizeRating(res.FinalVerdict)
+	if rating.Confidence >= 0.5 {
+		return rating
+	}
+	if res.CredibilityScore <= 0 {
+		return rating
+	}
+	return newRating(labelForScore(float64(res.CredibilityScore-1)/9), 0.6, res.FinalVerdict)
+}
+
+// RatingNameForValue — название ближайшей точки шкалы для среднего значения 1–5.
+func RatingNameForValue(avg float64) string {
+	v := int(avg + 0.5)
+	for _, scale := range ratingScale {
+		if scale.Value == min(max(v, 1), 5) {
+			return scale.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package services
+
+import "testing"
+
+func TestNormalizeRating(t *testing.T) {
+	tests := []struct {
+		text  string
+		label string
+	}{
+		// точные совпадения
+		{"False", RatingFalse},
+		{"PARȚIAL ADEVĂRAT", RatingMixed},
+		{"В основном правда", RatingMostlyTrue},
+		{"Не подтверждено", RatingUnproven},
+		{"2/5", RatingMostlyFalse},
+		// алиас внутри текста
+		{"FALS: fotografia este veche", RatingFalse},
+		{"NEFONDAT/SUSPECT", RatingUnproven},
+		{"Verdict: true", RatingTrue},
+		// отрицание переворачивает оценку — en
+		{"Not accurate", RatingFalse},
+		{"not real", RatingFalse},
+		{"This is not correct", RatingFalse},
+		{"isn't accurate", RatingFalse},
+		{"not mostly true", RatingMostlyFalse},
+		{"not false", RatingTrue},
+		// ro
+		{"Nu este adevărat", RatingFalse},
+		{"nu e corect", RatingFalse},
+		{"Nu este fals", RatingTrue},
+		// ru
+		{"не правда", RatingFalse},
+		{"Не верно", RatingFalse},
+		{"это не достоверно", RatingFalse},
+		{"нет, не правда", RatingFalse},
+		// отрицание «не подтверждено» — без оценки
+		{"not unproven", RatingUnknown},
+		// пусто и мусор
+		{"", RatingUnknown},
+		{"🤷", RatingUnknown},
+	}
+	for _, tt := range tests {
+		got := NormalizeRating(tt.text)
+		if got.Label != tt.label {
+			t.Errorf("NormalizeRating(%q) = %s (%.2f), want %s", tt.text, got.Label, got.Confidence, tt.label)
+		}
+	}
+}
+
+func TestNormalizeRatingNegatedNeverTrue(t *testing.T) {
+	for _, text := range []string{"Nu este adevărat", "не правда", "Не верно", "Not accurate", "not real"} {
+		if got := NormalizeRating(text); got.Label == RatingTrue || got.Label == RatingMostlyTrue {
+			t.Errorf("NormalizeRating(%q) = %s, negation must not map to a true rating", text, got.Label)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"log"
+	"math"
+	"text-analyzer/database"
+	"time"
+)
+
+// Репутация домена: байесовское среднее оценок его статей. Каждый домен
+// начинается с априорной оценки Prior, которая весит как Weight анализов,
+// поэтому один анализ нового домена не делает его ни «надёжным», ни
+// «ненадёжным». Старые анализы теряют вес экспоненциально (вдвое за
+// HalfLife), и без новых проверок оценка возвращается к априорной.
+// В domain_stats хранятся затухающие суммы на момент decayed_at:
+// вес W = Σw, S = Σw·x, Q = Σw·x².
+
+// ReputationConfig — параметры сглаживания.
+type ReputationConfig struct {
+	Prior    float64       // априорная оценка (0..10]
+	Weight   float64       // вес априорной оценки в анализах
+	HalfLife time.Duration // за сколько вес анализа падает вдвое
+}
+
+const (
+	reputationPriorSD  = 2.0 // априорный разброс оценок статей домена (им же заполнен Σx² старых строк в database.InitDB)
+	reputationZ        = 1.96
+	reputationTrendMin = 0.5 // меньшее изменение оценки — «стабильно»
+)
+
+var reputation = ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour}
+
+// InitReputation задаёт параметры сглаживания (нулевые поля и оценка вне
+// шкалы — по умолчанию).
+func InitReputation(cfg ReputationConfig) {
+	if cfg.Prior > 0 && cfg.Prior <= 10 {
+		reputation.Prior = cfg.Prior
+	}
+	if cfg.Weight > 0 {
+		reputation.Weight = cfg.Weight
+	}
+	if cfg.HalfLife > 0 {
+		reputation.HalfLife = cfg.HalfLife
+	}
+	log.Printf("[DOMAIN] ⚖ Репутация доменов: априорная оценка %.1f (вес %.0f анализов), полураспад %v",
+		reputation.Prior, reputation.Weight, reputation.HalfLife)
+}
+
+// DomainReputation — сглаженная оценка домена с 95% доверительным интервалом.
+type DomainReputation struct {
+	Score      float64 `json:"score"`
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Weight     float64 `json:"weight"`     // эффективное число анализов с учётом затухания
+	Confidence string  `json:"confidence"` // низкая | средняя | высокая — по весу относительно априорного
+}
+
+// decayFactor — доля веса, оставшаяся у анализов через d.
+func decayFactor(d time.Duration) float64 {
+	if d <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, d.Hours()/reputation.HalfLife.Hours())
+}
+
+// reputationFromSums считает репутацию по затухающим суммам, сохранённым
+// в момент at, на момент now.
+func reputationFromSums(w, s, q float64, at, now time.Time) DomainReputation {
+	f := decayFactor(now.Sub(at))
+	w, s, q = w*f, s*f, q*f
+
+	k := reputation.Weight
+	mean := (k*reputation.Prior + s) / (k + w)
+	variance := reputationPriorSD * reputationPriorSD
+	if w > 0 {
+		sample := math.Max(q/w-(s/w)*(s/w), 0)
+		variance = (k*variance + w*sample) / (k + w)
+	}
+	margin := reputationZ * math.Sqrt(variance/(k+w))
+
+	rep := DomainReputation{
+		Score:  reputationRound(mean),
+		Low:    reputationRound(math.Max(mean-margin, 0)),
+		High:   reputationRound(math.Min(mean+margin, 10)),
+		Weight: reputationRound(w),
+	}
+	// Уверенность — насколько свежие анализы перевешивают априорную оценку
+	switch {
+	case w >= 4*k:
+		rep.Confidence = "высокая"
+	case w >= k:
+		rep.Confidence = "средняя"
+	default:
+		rep.Confidence = "низкая"
+	}
+	return rep
+}
+
+func reputationRound(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// ── История ──────────────────────────────────────────────────────────────────
+
+// DomainHistoryPoint — итоги дня: анализы за день и репутация на конец дня.
+type DomainHistoryPoint struct {
+	Day        string  `json:"day"`
+	Analyses   int     `json:"analyses"`
+	AvgScore   float64 `json:"avg_score"`
+	Reputation float64 `json:"reputation"`
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+}
+
+// DomainTrend — изменение репутации за период.
+type DomainTrend struct {
+	Direction  string  `json:"direction"` // улучшается | ухудшается | стабильно | мало данных
+	Change     float64 `json:"change"`
+	PeriodDays int     `json:"period_days"`
+}
+
+// recordDomainHistory дописывает анализ в дневной ряд домена.
+func recordDomainHistory(domain string, score int, rep DomainReputation) {
+	_, err := database.DB.Exec(`
+		INSERT INTO domain_stats_history (domain, day, analyses, sum_scores, avg_score, reputation, reputation_low, reputation_high)
+		VALUES ($1, CURRENT_DATE, 1, $2, $2, $3, $4, $5)
+		ON CONFLICT (domain, day) DO UPDATE SET
+			analyses        = domain_stats_history.analyses + 1,
+			sum_scores      = domain_stats_history.sum_scores + $2,
+			avg_score       = (domain_stats_history.sum_scores + $2)::float / (domain_stats_history.analyses + 1),
+			reputation      = $3,
+			reputation_low  = $4,
+			reputation_high = $5
+	`, domain, score, rep.Score, rep.Low, rep.High)
+	if err != nil {
+		log.Printf("[DOMAIN] ⚠ Ошибка записи истории %s: %v", domain, err)
+	}
+}
+
+// DomainHistory — дневной ряд домена за последние days дней.
+func DomainHistory(domain string, days int) ([]DomainHistoryPoint, error) {
+	rows, err := database.DB.Query(`
+		SELECT to_char(day, 'YYYY-MM-DD'), analyses, avg_score, reputation,
+			COALESCE(reputation_low, reputation), COALESCE(reputation_high, reputation)
+		FROM domain_stats_history
+		WHERE domain = $1 AND day > CURRENT_DATE - $2::int
+		ORDER BY day
+	`, domain, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []DomainHistoryPoint
+	for rows.Next() {
+		var p DomainHistoryPoint
+		if rows.Scan(&p.Day, &p.Analyses, &p.AvgScore, &p.Reputation, &p.Low, &p.High) == nil {
+			points = append(points, p)
+		}
+	}
+	return points, rows.Err()
+}
+
+// DomainTrendOf сравнивает текущую репутацию с последней точкой ряда
+// не позже чем period дней назад (или с первой точкой, если ряд короче).
+func DomainTrendOf(points []DomainHistoryPoint, current float64, period int) DomainTrend {
+	t := DomainTrend{Direction: "мало данных", PeriodDays: period}
+	if len(points) < 2 {
+		return t
+	}
+	cutoff := time.Now().AddDate(0, 0, -period).Format("2006-01-02")
+	base := points[0]
+	for _, p := range points {
+		if p.Day > cutoff {
+			break
+		}
+		base = p
+	}
+	t.Change = reputationRound(current - base.Reputation)
+	switch {
+	case t.Change >= reputationTrendMin:
+		t.Direction = "улучшается"
+	case t.Change <= -reputationTrendMin:
+		t.Direction = "ухудшается"
+	default:
+		t.Direction = "стабильно"
+	}
+	return t
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// withReputation подменяет параметры сглаживания на время теста.
+func withReputation(t *testing.T, cfg ReputationConfig) {
+	saved := reputation
+	reputation = cfg
+	t.Cleanup(func() { reputation = saved })
+}
+
+func TestInitReputation(t *testing.T) {
+	defaults := ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour}
+	tests := []struct {
+		name string
+		cfg  ReputationConfig
+		want ReputationConfig
+	}{
+		{"zero fields keep defaults", ReputationConfig{}, defaults},
+		{"all set", ReputationConfig{Prior: 6, Weight: 3, HalfLife: time.Hour}, ReputationConfig{Prior: 6, Weight: 3, HalfLife: time.Hour}},
+		{"prior above scale", ReputationConfig{Prior: 11}, defaults},
+		{"negative prior", ReputationConfig{Prior: -1}, defaults},
+		{"prior at top of scale", ReputationConfig{Prior: 10}, ReputationConfig{Prior: 10, Weight: 5, HalfLife: defaults.HalfLife}},
+	}
+	for _, tt := range tests {
+		withReputation(t, defaults)
+		InitReputation(tt.cfg)
+		if reputation != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, reputation, tt.want)
+		}
+	}
+}
+
+func TestDecayFactor(t *testing.T) {
+	withReputation(t, ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour})
+	day := 24 * time.Hour
+	tests := []struct {
+		d    time.Duration
+		want float64
+	}{
+		{-day, 1},
+		{0, 1},
+		{90 * day, 0.5},
+		{180 * day, 0.25},
+		{45 * day, math.Sqrt(0.5)},
+	}
+	for _, tt := range tests {
+		if got := decayFactor(tt.d); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("decayFactor(%v) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
+
+// sumsOf — затухающие суммы для n анализов с одинаковой оценкой x.
+func sumsOf(n int, x float64) (w, s, q float64) {
+	return float64(n), float64(n) * x, float64(n) * x * x
+}
+
+func TestReputationFromSums(t *testing.T) {
+	withReputation(t, ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour})
+	now := time.Now()
+	tests := []struct {
+		name       string
+		n          int
+		score      float64
+		age        time.Duration
+		wantScore  float64
+		confidence string
+	}{
+		{"no analyses", 0, 0, 0, 5, "низкая"},
+		// один плохой анализ сдвигает оценку, но не роняет её до 1
+		{"single low", 1, 1, 0, 4.33, "низкая"},
+		{"single high", 1, 10, 0, 5.83, "низкая"},
+		{"as many as prior weight", 5, 1, 0, 3, "средняя"},
+		{"many", 20, 1, 0, 1.8, "высокая"},
+		// спустя полураспад 20 анализов весят как 10
+		{"decayed", 20, 1, 90 * 24 * time.Hour, 2.33, "средняя"},
+		// за годы без проверок оценка возвращается к априорной
+		{"forgotten", 20, 1, 10 * 365 * 24 * time.Hour, 5, "низкая"},
+	}
+	for _, tt := range tests {
+		w, s, q := sumsOf(tt.n, tt.score)
+		rep := reputationFromSums(w, s, q, now.Add(-tt.age), now)
+		if math.Abs(rep.Score-tt.wantScore) > 0.01 {
+			t.Errorf("%s: score %.2f, want %.2f", tt.name, rep.Score, tt.wantScore)
+		}
+		if rep.Confidence != tt.confidence {
+			t.Errorf("%s: confidence %s, want %s", tt.name, rep.Confidence, tt.confidence)
+		}
+		if rep.Low < 0 || rep.High > 10 || rep.Low > rep.Score || rep.High < rep.Score {
+			t.Errorf("%s: interval [%.2f, %.2f] around %.2f", tt.name, rep.Low, rep.High, rep.Score)
+		}
+	}
+}
+
+func TestReputationIntervalNarrowsWithData(t *testing.T) {
+	withReputation(t, ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour})
+	now := time.Now()
+	prev := math.Inf(1)
+	for _, n := range []int{0, 1, 5, 20, 100} {
+		w, s, q := sumsOf(n, 7)
+		rep := reputationFromSums(w, s, q, now, now)
+		if width := rep.High - rep.Low; width >= prev {
+			t.Errorf("%d analyses: interval width %.2f did not shrink (was %.2f)", n, width, prev)
+		} else {
+			prev = width
+		}
+	}
+}
+
+// Старые строки domain_stats заполняются по среднему с априорным разбросом
+// (database.InitDB): интервал не должен схлопываться как при нулевой дисперсии.
+func TestReputationBackfilledSums(t *testing.T) {
+	withReputation(t, ReputationConfig{Prior: 5, Weight: 5, HalfLife: 90 * 24 * time.Hour})
+	now := time.Now()
+	const n, avg = 100, 7.0
+	w, s := float64(n), float64(n)*avg
+	q := s*avg + w*reputationPriorSD*reputationPriorSD // как в миграции
+
+	rep := reputationFromSums(w, s, q, now, now)
+	want := 2 * reputationZ * reputationPriorSD / math.Sqrt(reputation.Weight+w)
+	if width := rep.High - rep.Low; math.Abs(width-want) > 0.02 {
+		t.Errorf("interval width %.2f, want %.2f (prior variance)", width, want)
+	}
+}
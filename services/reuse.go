@@ -0,0 +1,59 @@
+package services
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Поиск заимствований текста: w-шинглы (последовательности из shingleSize
+// слов) и доля общих шинглов между двумя текстами.
+
+const shingleSize = 5
+
+// textShingles — множество хешей шинглов текста без учёта регистра и пунктуации.
+func textShingles(text string) map[uint64]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := map[uint64]bool{}
+	if len(words) < shingleSize {
+		if len(words) > 0 {
+			out[hashWords(words)] = true
+		}
+		return out
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		out[hashWords(words[i:i+shingleSize])] = true
+	}
+	return out
+}
+
+func hashWords(words []string) uint64 {
+	h := fnv.New64a()
+	for _, w := range words {
+		h.Write([]byte(w))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// shingleContainment — какая доля шинглов a встречается в b и наоборот.
+// Короткий пересказ, целиком собранный из кусков длинной статьи, даст
+// aInB близкое к 1 при небольшом bInA.
+func shingleContainment(a, b map[uint64]bool) (aInB, bInA float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	small, large := a, b
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	common := 0
+	for h := range small {
+		if large[h] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)), float64(common) / float64(len(b))
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text-analyzer/models"
+	"time"
+)
+
+// SearchResult — один результат веб-поиска, общий для всех провайдеров.
+type SearchResult struct {
+	Title    string `json:"title"`
+	Link     string `json:"link"`
+	Snippet  string `json:"snippet"`
+	Date     string `json:"date,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Query    string `json:"query,omitempty"` // запрос, по которому найден результат
+	Claim    string `json:"claim,omitempty"` // утверждение, для проверки которого был запрос
+	// Заполняются RankResults
+	Tier        string   `json:"tier,omitempty"`         // уровень доверия к источнику
+	DomainScore *float64 `json:"domain_score,omitempty"` // сглаженная оценка домена по domain_stats
+}
+
+// SearchLocale — регион и язык поиска.
+type SearchLocale struct {
+	Gl   string // страна (md, us, ...)
+	Hl   string // язык (ru, en, ro, ...)
+	Name string // для логов
+}
+
+// SearchProvider — поисковый бэкенд (Serper, SearXNG, Brave, ...).
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, locale SearchLocale, num int) ([]SearchResult, error)
+}
+
+// Общий HTTP-клиент для API поисковиков
+var searchHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// DefaultSearchLocales — локали по умолчанию (переопределяются SEARCH_LOCALES).
+var DefaultSearchLocales = []SearchLocale{
+	{"md", "ru", "Русский (Молдова)"},
+	{"us", "en", "English (USA)"},
+	{"md", "ro", "Română (Moldova)"},
+}
+
+// DefaultSearchTimeout — общий таймаут многоязычного поиска.
+const DefaultSearchTimeout = 10 * time.Second
+
+// ParseSearchLocales разбирает список локалей вида "md:ru,us:en:English".
+// Формат элемента — страна:язык[:название].
+func ParseSearchLocales(spec string) ([]SearchLocale, error) {
+	var locales []SearchLocale
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) < 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("неверная локаль %q, ожидается страна:язык", item)
+		}
+		loc := SearchLocale{
+			Gl: strings.ToLower(strings.TrimSpace(parts[0])),
+			Hl: strings.ToLower(strings.TrimSpace(parts[1])),
+		}
+		if len(parts) == 3 {
+			loc.Name = strings.TrimSpace(parts[2])
+		}
+		if loc.Name == "" {
+			loc.Name = loc.Hl + "-" + strings.ToUpper(loc.Gl)
+		}
+		locales = append(locales, loc)
+	}
+	return locales, nil
+}
+
+func (l SearchLocale) key() string { return l.Gl + ":" + l.Hl }
+
+// SearchConfig — локали, таймаут, кэш и бюджет поиска.
+type SearchConfig struct {
+	Locales       []SearchLocale // пусто — DefaultSearchLocales
+	Timeout       time.Duration  // 0 — DefaultSearchTimeout
+	CacheTTL      time.Duration  // 0 — без кэша
+	MaxPerRequest int            // обращений к провайдерам на один анализ (0 — без ограничения, <0 — сколько нужно плану запросов)
+	MaxPerDay     int            // обращений за сутки на весь сервис (0 — без ограничения)
+	// Списки доменов для ранжирования источников (точное совпадение или поддомен)
+	TrustedDomains   []string // проверенные СМИ
+	BlockedDomains   []string // результаты отбрасываются
+	FactCheckDomains []string // фактчекинговые проекты сверх DefaultFactCheckDomains
+}
+
+// SearchService опрашивает все настроенные провайдеры и объединяет
+// результаты без дублей. nil-сервис означает, что поиск отключён.
+type SearchService struct {
+	providers []SearchProvider
+	cfg       SearchConfig
+	session   *searchSession // nil вне анализа — считается только дневной бюджет
+}
+
+// NewSearchService возвращает nil, если ни один провайдер не настроен.
+func NewSearchService(cfg SearchConfig, providers ...SearchProvider) *SearchService {
+	var active []SearchProvider
+	for _, p := range providers {
+		if p != nil {
+			active = append(active, p)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	if len(cfg.Locales) == 0 {
+		cfg.Locales = DefaultSearchLocales
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSearchTimeout
+	}
+	cfg.FactCheckDomains = append(append([]string{}, DefaultFactCheckDomains...), cfg.FactCheckDomains...)
+	return &SearchService{providers: active, cfg: cfg}
+}
+
+// Enabled сообщает, доступен ли поиск.
+func (s *SearchService) Enabled() bool {
+	return s != nil && len(s.providers) > 0
+}
+
+// ProviderNames — имена подключённых провайдеров.
+func (s *SearchService) ProviderNames() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, len(s.providers))
+	for i, p := range s.providers {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// Locales — локали, по которым идёт поиск.
+func (s *SearchService) Locales() []SearchLocale {
+	if s == nil {
+		return nil
+	}
+	return s.cfg.Locales
+}
+
+// WithLocales возвращает копию сервиса с другим набором локалей
+// (для запроса, в котором клиент указал свои). Пустой список — без изменений.
+func (s *SearchService) WithLocales(locales []SearchLocale) *SearchService {
+	if s == nil || len(locales) == 0 {
+		return s
+	}
+	c := *s
+	c.cfg.Locales = locales
+	return &c
+}
+
+// Search ищет в основной локали.
+func (s *SearchService) Search(query string) ([]SearchResult, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("поиск не настроен")
+	}
+	res := s.search(query, s.cfg.Locales[:1], 10)
+	return res.Results, res.Err()
+}
+
+// SearchError — ошибка одного провайдера в одной локали.
+type SearchError struct {
+	Locale   string `json:"locale"`
+	Provider string `json:"provider"`
+	Query    string `json:"query,omitempty"`
+	Error    string `json:"error"`
+	Budget   bool   `json:"budget,omitempty"` // поиск не выполнен: исчерпан бюджет
+}
+
+// searchBudgetError — отказ в поиске из-за исчерпанного бюджета.
+type searchBudgetError string
+
+func (e searchBudgetError) Error() string { return string(e) }
+
+// MultiSearchResult — объединённые результаты и ошибки по локалям.
+type MultiSearchResult struct {
+	Results []SearchResult
+	Errors  []SearchError
+	Calls   int // сколько запросов к провайдерам сделано
+}
+
+// Err возвращает ошибку, только если не удалось ни одно обращение.
+func (r *MultiSearchResult) Err() error {
+	if len(r.Errors) == 0 || len(r.Errors) < r.Calls {
+		return nil
+	}
+	var parts []string
+	for _, e := range r.Errors {
+		parts = append(parts, fmt.Sprintf("%s/%s: %s", e.Provider, e.Locale, e.Error))
+	}
+	return fmt.Errorf("поиск недоступен: %s", strings.Join(parts, "; "))
+}
+
+// search параллельно опрашивает все провайдеры во всех локалях с общим таймаутом.
+// Результаты идут в порядке локалей, внутри локали — по очереди от провайдеров.
+func (s *SearchService) search(query string, locales []SearchLocale, num int) *MultiSearchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	type reply struct {
+		results []SearchResult
+		err     error
+	}
+	replies := make([][]reply, len(locales))
+	var wg sync.WaitGroup
+	for li, locale := range locales {
+		replies[li] = make([]reply, len(s.providers))
+		for pi, p := range s.providers {
+			key := searchCacheKey(p.Name(), locale, num, query)
+			if cached, ok := loadCachedSearch(key); ok {
+				if s.session != nil {
+					s.session.cacheHits.Add(1)
+				}
+				replies[li][pi] = reply{results: cached}
+				continue
+			}
+			if reason := s.reserveCall(); reason != "" {
+				replies[li][pi] = reply{err: searchBudgetError(reason)}
+				continue
+			}
+			wg.Add(1)
+			go func(li, pi int, p SearchProvider, locale SearchLocale, key string) {
+				defer wg.Done()
+				results, err := p.Search(ctx, query, locale, num)
+				if err == nil && ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				if err == nil {
+					storeCachedSearch(key, results, s.cfg.CacheTTL)
+				}
+				replies[li][pi] = reply{results, err}
+			}(li, pi, p, locale, key)
+		}
+	}
+	wg.Wait()
+
+	out := &MultiSearchResult{Calls: len(locales) * len(s.providers)}
+	var lists [][]SearchResult
+	for li, locale := range locales {
+		var perLocale [][]SearchResult
+		for pi, p := range s.providers {
+			r := replies[li][pi]
+			if r.err != nil {
+				msg := r.err.Error()
+				if errors.Is(r.err, context.DeadlineExceeded) {
+					msg = fmt.Sprintf("таймаут %v", s.cfg.Timeout)
+				}
+				var budget searchBudgetError
+				log.Printf("[SEARCH] ⚠ %s (%s): %s", p.Name(), locale.Name, msg)
+				out.Errors = append(out.Errors, SearchError{Locale: locale.key(), Provider: p.Name(), Error: msg, Budget: errors.As(r.err, &budget)})
+				continue
+			}
+			for i := range r.results {
+				r.results[i].Provider = p.Name()
+			}
+			perLocale = append(perLocale, r.results)
+		}
+		merged := mergeSearchResults(perLocale...)
+		if len(locales) > 1 {
+			log.Printf("[SEARCH] ✓ %s: найдено %d результатов", locale.Name, len(merged))
+		}
+		lists = append(lists, merged)
+	}
+
+	var all []SearchResult
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	out.Results = mergeSearchResults(all)
+	return out
+}
+
+// SearchAll — поиск по всем локалям с подробностями об ошибках.
+func (s *SearchService) SearchAll(query string) *MultiSearchResult {
+	log.Printf("[SEARCH] 🌍 Многоязычный поиск: \"%s\"", query)
+	if !s.Enabled() {
+		return &MultiSearchResult{Errors: []SearchError{{Error: "поиск не настроен"}}}
+	}
+	res := s.search(query, s.cfg.Locales, 5)
+	log.Printf("[SEARCH] ✅ Всего найдено результатов: %d", len(res.Results))
+	return res
+}
+
+// SearchMultiLanguage — поиск по всем локалям. Ошибка — только если не ответил никто.
+func (s *SearchService) SearchMultiLanguage(query string) ([]SearchResult, error) {
+	res := s.SearchAll(query)
+	return res.Results, res.Err()
+}
+
+// Langs — языки локалей поиска (без повторов).
+func (s *SearchService) Langs() []string {
+	var langs []string
+	seen := map[string]bool{}
+	for _, l := range s.Locales() {
+		if !seen[l.Hl] {
+			seen[l.Hl] = true
+			langs = append(langs, l.Hl)
+		}
+	}
+	return langs
+}
+
+// localesFor — локали для запроса на языке lang; пустой язык — все локали.
+func (s *SearchService) localesFor(lang string) []SearchLocale {
+	if lang == "" {
+		return s.cfg.Locales
+	}
+	var out []SearchLocale
+	for _, l := range s.cfg.Locales {
+		if l.Hl == lang {
+			out = append(out, l)
+		}
+	}
+	if len(out) == 0 {
+		return s.cfg.Locales
+	}
+	return out
+}
+
+// SearchQueries выполняет запланированные запросы параллельно. Каждый
+// результат помечен запросом, который его нашёл.
+func (s *SearchService) SearchQueries(queries []models.SearchQuery, num int) *MultiSearchResult {
+	out := &MultiSearchResult{}
+	if !s.Enabled() || len(queries) == 0 {
+		return out
+	}
+	replies := make([]*MultiSearchResult, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q models.SearchQuery) {
+			defer wg.Done()
+			log.Printf("[SEARCH] 🔑 Запрос: \"%s\" (%s)", q.Query, q.Lang)
+			res := s.search(q.Query, s.localesFor(q.Lang), num)
+			for j := range res.Results {
+				res.Results[j].Query = q.Query
+				res.Results[j].Claim = q.Claim
+			}
+			for j := range res.Errors {
+				res.Errors[j].Query = q.Query
+			}
+			replies[i] = res
+		}(i, q)
+	}
+	wg.Wait()
+
+	var lists [][]SearchResult
+	for _, r := range replies {
+		lists = append(lists, r.Results)
+		out.Errors = append(out.Errors, r.Errors...)
+		out.Calls += r.Calls
+	}
+	out.Results = mergeSearchResults(lists...)
+	log.Printf("[SEARCH] ✅ %d запросов · найдено результатов: %d", len(queries), len(out.Results))
+	return out
+}
+
+// FormatSearchContext форматирует результаты поиска для контекста модели.
+func FormatSearchContext(results []SearchResult, limit int) string {
+	if len(results) == 0 {
+		return "Результаты поиска не найдены"
+	}
+	var builder strings.Builder
+	builder.WriteString("🌐 РЕЗУЛЬТАТЫ ПОИСКА В ИНТЕРНЕТЕ:\n")
+	builder.WriteString("(у каждого результата указана надёжность источника: факт-чекеры и проверенные СМИ весомее, " +
+		"«ненадёжные» источники не подтверждают факты)\n\n")
+	for i, result := range results {
+		if i >= limit {
+			break
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Title))
+		builder.WriteString(fmt.Sprintf("   🔗 %s\n", result.Link))
+		if result.Tier != "" {
+			builder.WriteString(fmt.Sprintf("   🏷 Источник: %s\n", tierLabel(result)))
+		}
+		if result.Snippet != "" {
+			builder.WriteString(fmt.Sprintf("   📝 %s\n", result.Snippet))
+		}
+		if result.Date != "" {
+			builder.WriteString(fmt.Sprintf("   📅 %s\n", result.Date))
+		}
+		if result.Query != "" {
+			builder.WriteString(fmt.Sprintf("   🔑 найдено по запросу: %s\n", result.Query))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// mergeSearchResults объединяет списки по очереди (первый из каждого, второй
+// из каждого, ...), чтобы ни один провайдер не вытеснял остальных, и убирает дубли.
+func mergeSearchResults(lists ...[]SearchResult) []SearchResult {
+	seen := map[string]bool{}
+	var merged []SearchResult
+	for i := 0; ; i++ {
+		added := false
+		for _, l := range lists {
+			if i >= len(l) {
+				continue
+			}
+			added = true
+			key := searchDedupKey(l[i].Link)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, l[i])
+		}
+		if !added {
+			return merged
+		}
+	}
+}
+
+// Параметры отслеживания, которые не меняют страницу
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "yclid": true, "ref": true, "ocid": true}
+
+// searchDedupKey приводит ссылку к каноническому виду: без схемы, www./m.,
+// фрагмента, трекинговых параметров, /amp и «/» в конце.
+func searchDedupKey(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return link
+	}
+	q := u.Query()
+	for k := range q {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "utm_") || trackingParams[lk] {
+			q.Del(k)
+		}
+	}
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	path = strings.TrimSuffix(path, "/amp")
+	key := host + path
+	if enc := q.Encode(); enc != "" {
+		key += "?" + enc
+	}
+	return key
+}
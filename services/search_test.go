@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeSearchProvider отдаёт заранее заданные результаты.
+type fakeSearchProvider struct {
+	name    string
+	results map[string][]SearchResult // по запросу; ключ "" — для любого запроса
+	err     error
+	queries []string // все полученные запросы
+
+	mu sync.Mutex
+}
+
+func (f *fakeSearchProvider) Name() string { return f.name }
+
+func (f *fakeSearchProvider) Search(_ context.Context, query string, _ SearchLocale, num int) ([]SearchResult, error) {
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	results, ok := f.results[query]
+	if !ok {
+		results = f.results[""]
+	}
+	if num > 0 && len(results) > num {
+		results = results[:num]
+	}
+	return append([]SearchResult(nil), results...), nil
+}
+
+func links(results []SearchResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.Link
+	}
+	return out
+}
+
+func results(links ...string) []SearchResult {
+	out := make([]SearchResult, len(links))
+	for i, l := range links {
+		out[i] = SearchResult{Link: l}
+	}
+	return out
+}
+
+func TestMergeSearchResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists [][]SearchResult
+		want  []string
+	}{
+		{
+			name: "round robin",
+			lists: [][]SearchResult{
+				results("https://a.md/1", "https://a.md/2", "https://a.md/3"),
+				results("https://b.md/1"),
+				results("https://c.md/1", "https://c.md/2"),
+			},
+			want: []string{"https://a.md/1", "https://b.md/1", "https://c.md/1", "https://a.md/2", "https://c.md/2", "https://a.md/3"},
+		},
+		{
+			name: "dedup across providers keeps first",
+			lists: [][]SearchResult{
+				results("https://news.md/story", "https://a.md/2"),
+				results("http://www.news.md/story/?utm_source=x&fbclid=y", "https://b.md/2"),
+				results("https://m.news.md/story/amp#top"),
+			},
+			want: []string{"https://news.md/story", "https://a.md/2", "https://b.md/2"},
+		},
+		{
+			name: "query params that change the page are kept",
+			lists: [][]SearchResult{
+				results("https://news.md/view?id=1"),
+				results("https://news.md/view?id=2"),
+			},
+			want: []string{"https://news.md/view?id=1", "https://news.md/view?id=2"},
+		},
+		{
+			name:  "empty",
+			lists: [][]SearchResult{nil, {}},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		got := links(mergeSearchResults(tt.lists...))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDedupKey(t *testing.T) {
+	tests := []struct{ link, want string }{
+		{"https://www.News.md/a/", "news.md/a"},
+		{"http://m.news.md/a/amp", "news.md/a"},
+		{"https://news.md/a?utm_campaign=x&gclid=1#frag", "news.md/a"},
+		{"https://news.md/a?b=2&a=1", "news.md/a?a=1&b=2"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := searchDedupKey(tt.link); got != tt.want {
+			t.Errorf("searchDedupKey(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestSearchProviderErrorFallback(t *testing.T) {
+	resetDailySearches()
+	good := &fakeSearchProvider{name: "good", results: map[string][]SearchResult{
+		"": results("https://a.md/1", "https://a.md/2"),
+	}}
+	broken := &fakeSearchProvider{name: "broken", err: errors.New("HTTP 500")}
+	s := NewSearchService(SearchConfig{Locales: DefaultSearchLocales[:1]}, broken, good)
+
+	res := s.search("plafonare preturi", s.Locales(), 10)
+	if err := res.Err(); err != nil {
+		t.Fatalf("one provider answered, want no error: %v", err)
+	}
+	if got := links(res.Results); !reflect.DeepEqual(got, []string{"https://a.md/1", "https://a.md/2"}) {
+		t.Errorf("results = %v", got)
+	}
+	for _, r := range res.Results {
+		if r.Provider != "good" {
+			t.Errorf("result %s marked with provider %q", r.Link, r.Provider)
+		}
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Provider != "broken" {
+		t.Errorf("errors = %+v, want one from broken", res.Errors)
+	}
+
+	// Если не ответил никто — ошибка
+	s = NewSearchService(SearchConfig{Locales: DefaultSearchLocales[:1]}, broken)
+	if err := s.search("plafonare preturi", s.Locales(), 10).Err(); err == nil {
+		t.Error("all providers failed, want error")
+	}
+}
+
+func TestSearchCacheKey(t *testing.T) {
+	md := SearchLocale{Gl: "md", Hl: "ro"}
+	base := searchCacheKey("serper", md, 10, "Plafonare prețuri energie")
+
+	if got := searchCacheKey("serper", md, 10, "  plafonare   PREȚURI energie "); got != base {
+		t.Errorf("case and whitespace must not change the key: %s vs %s", got, base)
+	}
+	different := map[string]string{
+		"provider": searchCacheKey("brave", md, 10, "Plafonare prețuri energie"),
+		"locale":   searchCacheKey("serper", SearchLocale{Gl: "md", Hl: "ru"}, 10, "Plafonare prețuri energie"),
+		"num":      searchCacheKey("serper", md, 5, "Plafonare prețuri energie"),
+		"query":    searchCacheKey("serper", md, 10, "Plafonare prețuri gaz"),
+	}
+	for what, key := range different {
+		if key == base {
+			t.Errorf("key must depend on %s", what)
+		}
+	}
+}
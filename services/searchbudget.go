@@ -0,0 +1,220 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text-analyzer/cache"
+	"time"
+)
+
+// ── Кэш результатов ──────────────────────────────────────────────────────────
+
+// normalizeQuery — регистр и пробелы не влияют на ключ кэша.
+func normalizeQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+func searchCacheKey(provider string, locale SearchLocale, num int, query string) string {
+	sum := sha256.Sum256([]byte(normalizeQuery(query)))
+	return fmt.Sprintf("search:%s:%s:%d:%s", provider, locale.key(), num, hex.EncodeToString(sum[:]))
+}
+
+func loadCachedSearch(key string) ([]SearchResult, bool) {
+	raw, err := cache.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	var results []SearchResult
+	if err := json.Unmarshal([]byte(raw), &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+func storeCachedSearch(key string, results []SearchResult, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if data, err := json.Marshal(results); err == nil {
+		cache.Set(key, string(data), ttl)
+	}
+}
+
+// ── Бюджет запросов ──────────────────────────────────────────────────────────
+
+// searchSession считает обращения к провайдерам в рамках одного анализа.
+type searchSession struct {
+	calls     atomic.Int32
+	cacheHits atomic.Int32
+}
+
+// Дневной счётчик в памяти — если Redis недоступен.
+var (
+	dailySearchMu    sync.Mutex
+	dailySearchDay   string
+	dailySearchCount int64
+)
+
+func searchDayKey() string {
+	return "search:calls:" + time.Now().UTC().Format("2006-01-02")
+}
+
+// incrDailySearches увеличивает счётчик запросов за сутки (UTC).
+func incrDailySearches() int64 {
+	key := searchDayKey()
+	if n, err := cache.Incr(key, 48*time.Hour); err == nil {
+		return n
+	}
+	dailySearchMu.Lock()
+	defer dailySearchMu.Unlock()
+	if dailySearchDay != key {
+		dailySearchDay, dailySearchCount = key, 0
+	}
+	dailySearchCount++
+	return dailySearchCount
+}
+
+// decrDailySearches откатывает incrDailySearches, если запрос не состоялся.
+func decrDailySearches() {
+	key := searchDayKey()
+	if _, err := cache.Decr(key); err == nil {
+		return
+	}
+	dailySearchMu.Lock()
+	defer dailySearchMu.Unlock()
+	if dailySearchDay == key && dailySearchCount > 0 {
+		dailySearchCount--
+	}
+}
+
+// DailySearchCalls — сколько запросов к поисковикам сделано сегодня (UTC).
+func DailySearchCalls() int64 {
+	key := searchDayKey()
+	if raw, err := cache.Get(key); err == nil {
+		var n int64
+		fmt.Sscan(raw, &n)
+		return n
+	}
+	dailySearchMu.Lock()
+	defer dailySearchMu.Unlock()
+	if dailySearchDay != key {
+		return 0
+	}
+	return dailySearchCount
+}
+
+// reserveCall резервирует запрос в бюджете запроса и суток. Счётчик
+// сначала увеличивается, а при превышении откатывается — так параллельные
+// поиски не проходят проверку одновременно. Возвращает причину отказа.
+func (s *SearchService) reserveCall() string {
+	if s.session != nil {
+		if n, limit := s.session.calls.Add(1), s.maxPerRequest(); limit > 0 && int(n) > limit {
+			s.session.calls.Add(-1)
+			return fmt.Sprintf("исчерпан бюджет запроса (%d поисков)", limit)
+		}
+	}
+	if n := incrDailySearches(); s.cfg.MaxPerDay > 0 && n > int64(s.cfg.MaxPerDay) {
+		decrDailySearches()
+		if s.session != nil {
+			s.session.calls.Add(-1)
+		}
+		return fmt.Sprintf("исчерпан дневной бюджет (%d поисков)", s.cfg.MaxPerDay)
+	}
+	return ""
+}
+
+// maxPerRequest — бюджет одного анализа. При отрицательном MaxPerRequest
+// это столько поисков, сколько нужно самому длинному плану запросов при
+// текущих локалях и провайдерах.
+func (s *SearchService) maxPerRequest() int {
+	if s.cfg.MaxPerRequest >= 0 {
+		return s.cfg.MaxPerRequest
+	}
+	langs := len(s.Langs())
+	if langs == 0 {
+		return 0
+	}
+	localesPerLang := (len(s.cfg.Locales) + langs - 1) / langs
+	return maxAnalysisQueries(langs) * localesPerLang * len(s.providers)
+}
+
+// NewSession возвращает копию сервиса со своим счётчиком запросов —
+// одна сессия на анализ или цепочку.
+func (s *SearchService) NewSession() *SearchService {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.session = &searchSession{}
+	return &c
+}
+
+// Stats — сколько обращений к провайдерам и попаданий в кэш было в сессии.
+func (s *SearchService) Stats() (calls, cacheHits int) {
+	if s == nil || s.session == nil {
+		return 0, 0
+	}
+	return int(s.session.calls.Load()), int(s.session.cacheHits.Load())
+}
+
+// ── Остаток кредитов провайдеров ─────────────────────────────────────────────
+
+// CreditsReporter — провайдер, который умеет сообщать остаток оплаченных запросов.
+type CreditsReporter interface {
+	Credits() (int, error)
+}
+
+var (
+	searchLimitsMu      sync.Mutex
+	searchLimitsUpdated time.Time
+)
+
+// RefreshLimits обновляет остаток кредитов провайдеров на /api/limits
+// (не чаще раза в 5 минут).
+func (s *SearchService) RefreshLimits() {
+	if !s.Enabled() {
+		return
+	}
+	searchLimitsMu.Lock()
+	if time.Since(searchLimitsUpdated) < 5*time.Minute {
+		searchLimitsMu.Unlock()
+		return
+	}
+	searchLimitsUpdated = time.Now()
+	searchLimitsMu.Unlock()
+
+	used := int(DailySearchCalls())
+	for _, p := range s.providers {
+		info := &RateLimitInfo{
+			Provider:          p.Name(),
+			LimitRequests:     -1,
+			RemainingRequests: -1,
+			LimitTokens:       -1,
+			RemainingTokens:   -1,
+			UsedToday:         used,
+			StatusCode:        200,
+			UpdatedAt:         time.Now().UnixMilli(),
+		}
+		if s.cfg.MaxPerDay > 0 {
+			info.LimitRequests = s.cfg.MaxPerDay
+			info.RemainingRequests = max(s.cfg.MaxPerDay-used, 0)
+			info.ResetRequests = "00:00 UTC"
+		}
+		if cr, ok := p.(CreditsReporter); ok {
+			credits, err := cr.Credits()
+			if err != nil {
+				log.Printf("[SEARCH] ⚠ Не удалось узнать остаток кредитов %s: %v", p.Name(), err)
+				info.StatusCode = 0
+			} else {
+				info.Credits = &credits
+			}
+		}
+		storeRateLimit(info)
+	}
+}
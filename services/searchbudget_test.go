@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text-analyzer/models"
+)
+
+// resetDailySearches обнуляет дневной счётчик в памяти (Redis в тестах не подключён).
+func resetDailySearches() {
+	dailySearchMu.Lock()
+	dailySearchDay, dailySearchCount = "", 0
+	dailySearchMu.Unlock()
+}
+
+// reserveConcurrently вызывает reserveCall из n горутин и считает успешные.
+func reserveConcurrently(s *SearchService, n int) int {
+	var granted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.reserveCall() == "" {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(granted.Load())
+}
+
+func TestReserveCallPerRequestConcurrent(t *testing.T) {
+	resetDailySearches()
+	s := (&SearchService{cfg: SearchConfig{MaxPerRequest: 5}}).NewSession()
+
+	if got := reserveConcurrently(s, 100); got != 5 {
+		t.Errorf("granted %d calls, want 5", got)
+	}
+	if calls, _ := s.Stats(); calls != 5 {
+		t.Errorf("session counter = %d, want 5", calls)
+	}
+	if got := DailySearchCalls(); got != 5 {
+		t.Errorf("daily counter = %d, want 5", got)
+	}
+}
+
+func TestReserveCallDailyConcurrent(t *testing.T) {
+	resetDailySearches()
+	s := &SearchService{cfg: SearchConfig{MaxPerDay: 7}}
+
+	if got := reserveConcurrently(s, 100); got != 7 {
+		t.Errorf("granted %d calls, want 7", got)
+	}
+	if got := DailySearchCalls(); got != 7 {
+		t.Errorf("daily counter = %d, want 7 (denied calls must be rolled back)", got)
+	}
+
+	// Отказ по дневному бюджету не расходует бюджет запроса
+	session := s.NewSession()
+	if reason := session.reserveCall(); reason == "" {
+		t.Fatal("daily budget must be exhausted")
+	}
+	if calls, _ := session.Stats(); calls != 0 {
+		t.Errorf("session counter = %d after daily denial, want 0", calls)
+	}
+}
+
+func TestSearchBudgetDenialReported(t *testing.T) {
+	resetDailySearches()
+	provider := &fakeSearchProvider{name: "fake", results: map[string][]SearchResult{
+		"": results("https://a.md/1"),
+	}}
+	s := NewSearchService(SearchConfig{Locales: DefaultSearchLocales[:1], MaxPerRequest: 1}, provider).NewSession()
+
+	res := s.SearchQueries([]models.SearchQuery{{Query: "first"}, {Query: "second"}}, 5)
+	if len(provider.queries) != 1 {
+		t.Fatalf("provider got %d queries, want 1", len(provider.queries))
+	}
+	if len(res.Errors) != 1 {
+		t.Fatalf("errors = %+v, want one budget denial", res.Errors)
+	}
+	e := res.Errors[0]
+	if !e.Budget || e.Query == "" || e.Query == provider.queries[0] {
+		t.Errorf("denial = %+v, want Budget with the skipped query", e)
+	}
+}
+
+func TestDerivedSearchBudgetCoversPlan(t *testing.T) {
+	resetDailySearches()
+	provider := &fakeSearchProvider{name: "fake", results: map[string][]SearchResult{
+		"": results("https://a.md/1"),
+	}}
+	s := NewSearchService(SearchConfig{MaxPerRequest: -1}, provider).NewSession()
+	p := NewQueryPlanner(nil, false)
+
+	text := "Guvernul plafonează prețul la energie\nGuvernul Republicii Moldova a decis că prețul la energia electrică va fi plafonat la 3,5 lei. " +
+		"Conform ANRE, măsura va costa bugetul 2 miliarde de lei."
+	claims := []string{
+		"Potrivit ANRE, prețul la gaz a crescut cu 40% în Chișinău",
+		"NASA confirmed that the asteroid 2024 YR4 will miss the Earth",
+		"Согласно опросу, 60% жителей Кишинёва против повышения тарифов",
+	}
+	textQueries, _ := p.PlanText(context.Background(), nil, text, s.Langs())
+	claimQueries, _ := p.PlanClaims(context.Background(), nil, text, claims, s.Langs())
+
+	for _, queries := range [][]models.SearchQuery{textQueries, claimQueries} {
+		for _, e := range s.SearchQueries(queries, 5).Errors {
+			if e.Budget {
+				t.Fatalf("default budget %d denied %q", s.maxPerRequest(), e.Query)
+			}
+		}
+	}
+	if s.maxPerRequest() < 15 {
+		t.Errorf("default budget = %d, want at least 15", s.maxPerRequest())
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearxngClient — self-hosted SearXNG через JSON API.
+// В settings.yml инстанса должен быть включён формат json (search.formats).
+type SearxngClient struct {
+	BaseURL string
+}
+
+type searxngResponse struct {
+	Results []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		Content       string `json:"content"`
+		PublishedDate string `json:"publishedDate"`
+	} `json:"results"`
+}
+
+func NewSearxngClient(baseURL string) *SearxngClient {
+	return &SearxngClient{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *SearxngClient) Name() string { return "searxng" }
+
+func (s *SearxngClient) Search(ctx context.Context, query string, locale SearchLocale, num int) ([]SearchResult, error) {
+	log.Printf("[SEARXNG] 🔍 Поиск: \"%s\" (%s)", query, locale.Name)
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("categories", "general,news")
+	if locale.Hl != "" {
+		params.Set("language", locale.Hl)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := searchHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SearXNG вернул %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var sr searxngResponse
+	if err := json.Unmarshal(body, &sr); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	var results []SearchResult
+	for _, r := range sr.Results {
+		if num > 0 && len(results) >= num {
+			break
+		}
+		results = append(results, SearchResult{Title: r.Title, Link: r.URL, Snippet: r.Content, Date: r.PublishedDate})
+	}
+	log.Printf("[SEARXNG] ✓ Найдено результатов: %d", len(results))
+	return results, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/bits"
+	"text-analyzer/database"
+	"text-analyzer/models"
+	"time"
+)
+
+// Повторное использование результатов для почти одинаковых текстов.
+// Ключ кэша Redis — sha256 точного текста, поэтому лишний пробел, рекламный
+// баннер или другой способ извлечения текста запускают анализ заново.
+// Simhash по шинглам текста меняется на несколько бит при мелких правках:
+// сохранённый анализ с расстоянием Хэмминга в пределах порога отдаётся
+// вместо нового вызова модели.
+
+// ReuseConfig — когда можно отдать готовый результат почти такого же текста.
+type ReuseConfig struct {
+	MinSimilarity float64       // доля совпадающих бит simhash (0..1), 0 — выключено
+	MaxAge        time.Duration // более старые анализы не переиспользуются
+}
+
+const simhashMinShingles = 20 // у коротких текстов simhash слишком неустойчив
+
+// textSimhash — 64-битный simhash множества шинглов текста.
+// ok = false, если текст слишком короткий для надёжного отпечатка.
+func textSimhash(text string) (hash uint64, ok bool) {
+	shingles := textShingles(text)
+	if len(shingles) < simhashMinShingles {
+		return 0, false
+	}
+	var weights [64]int
+	for h := range shingles {
+		for i := range weights {
+			if h&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash, true
+}
+
+// simhashSimilarity — доля совпадающих бит двух отпечатков.
+func simhashSimilarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// findNearDuplicate ищет самый похожий сохранённый анализ с simhash в пределах
+// порога. Возвращает сведения о совпадении и сохранённый результат.
+func findNearDuplicate(hash uint64, cfg ReuseConfig) (*models.AnalysisMatch, *models.AnalysisResponse) {
+	if database.DB == nil || cfg.MinSimilarity <= 0 {
+		return nil, nil
+	}
+	maxDistance := int(math.Floor((1 - cfg.MinSimilarity) * 64))
+	rows, err := database.DB.Query(`
+		SELECT id, simhash, result, created_at FROM analysis_results
+		WHERE simhash IS NOT NULL AND reused_from IS NULL
+		  AND created_at > NOW() - make_interval(secs => $2)
+		  AND length(replace(((simhash # $1)::bit(64))::text, '0', '')) <= $3
+		ORDER BY created_at DESC
+		LIMIT 20
+	`, int64(hash), cfg.MaxAge.Seconds(), maxDistance)
+	if err != nil {
+		log.Printf("[REUSE] ⚠ Ошибка поиска похожих анализов: %v", err)
+		return nil, nil
+	}
+	defer rows.Close()
+
+	var match *models.AnalysisMatch
+	var raw []byte
+	best := 0.0
+	for rows.Next() {
+		var id, stored int64
+		var res []byte
+		var at time.Time
+		if rows.Scan(&id, &stored, &res, &at) != nil {
+			continue
+		}
+		sim := simhashSimilarity(hash, uint64(stored))
+		if sim > best {
+			best, raw = sim, res
+			match = &models.AnalysisMatch{AnalysisID: id, Similarity: math.Round(sim*1000) / 10, AnalyzedAt: at}
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+	var response models.AnalysisResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, nil
+	}
+	return match, &response
+}
+
+// reusedResponse переносит из сохранённого анализа только оценку текста.
+// Источник (URL, снимок, ссылки статьи), расход токенов и поисковые запросы
+// относятся к прошлому анализу, а не к этому запросу: источник заново
+// задаёт applySource, остальное остаётся пустым.
+func reusedResponse(stored *models.AnalysisResponse, match *models.AnalysisMatch) *models.AnalysisResponse {
+	return &models.AnalysisResponse{
+		Summary:            stored.Summary,
+		ClaimReviews:       stored.ClaimReviews,
+		MatchedAnalysis:    match,
+		FactCheck:          stored.FactCheck,
+		Manipulations:      stored.Manipulations,
+		LogicalIssues:      stored.LogicalIssues,
+		CredibilityScore:   stored.CredibilityScore,
+		ScoreBreakdown:     stored.ScoreBreakdown,
+		FinalVerdict:       stored.FinalVerdict,
+		Rating:             stored.Rating,
+		VerdictExplanation: stored.VerdictExplanation,
+		Reasoning:          stored.Reasoning,
+		Sources:            stored.Sources,
+		Verification:       stored.Verification,
+	}
+}
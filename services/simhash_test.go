@@ -0,0 +1,126 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"text-analyzer/models"
+)
+
+const simhashArticle = `Guvernul a anunțat marți că prețul la energia electrică pentru consumatorii casnici va fi plafonat până la sfârșitul anului.
+Ministrul energiei a declarat că măsura va costa bugetul aproximativ două miliarde de lei și va fi finanțată din fondul de rezervă.
+Opoziția a criticat decizia, afirmând că plafonarea nu rezolvă problema prețurilor mari și doar amână creșterile pentru anul viitor.
+Experții din domeniu avertizează că furnizorii ar putea reduce investițiile în rețea dacă diferența de preț nu este compensată la timp.`
+
+const simhashUnrelated = `The city council approved a new cycling lane network on Thursday after months of public consultation with local residents.
+The project will add forty kilometres of protected lanes connecting the university campus, the central station and the riverside parks.
+Construction is expected to begin next spring and will be funded partly by a regional transport grant and partly by parking revenue.
+Shop owners on the main avenue raised concerns about deliveries, and the council promised dedicated loading zones on side streets.`
+
+func TestTextSimhashShortText(t *testing.T) {
+	if _, ok := textSimhash("Prea scurt pentru o amprentă stabilă."); ok {
+		t.Error("short text must not get a simhash")
+	}
+}
+
+func TestSimhashSimilarity(t *testing.T) {
+	base, ok := textSimhash(simhashArticle)
+	if !ok {
+		t.Fatal("article must get a simhash")
+	}
+	tests := []struct {
+		name    string
+		text    string
+		minSim  float64
+		maxSim  float64
+		reusing bool // проходит порог REUSE_MIN_SIMILARITY по умолчанию (90%)
+	}{
+		{"identical", simhashArticle, 1, 1, true},
+		{"whitespace", strings.Join(strings.Fields(simhashArticle), "  "), 1, 1, true},
+		{"trailer", simhashArticle + " Sursa: agenția", 0.9, 1, true},
+		{"unrelated", simhashUnrelated, 0, 0.85, false},
+	}
+	for _, tt := range tests {
+		h, ok := textSimhash(tt.text)
+		if !ok {
+			t.Fatalf("%s: no simhash", tt.name)
+		}
+		sim := simhashSimilarity(base, h)
+		if sim < tt.minSim || sim > tt.maxSim {
+			t.Errorf("%s: similarity %.3f, want %.2f..%.2f", tt.name, sim, tt.minSim, tt.maxSim)
+		}
+		if got := sim >= 0.9; got != tt.reusing {
+			t.Errorf("%s: reuse at 90%% = %v, want %v (similarity %.3f)", tt.name, got, tt.reusing, sim)
+		}
+	}
+}
+
+func TestSimhashSimilarityBits(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want float64
+	}{
+		{0, 0, 1},
+		{0, 1, 1 - 1.0/64},
+		{0, 0xFF, 1 - 8.0/64},
+		{0, ^uint64(0), 0},
+	}
+	for _, tt := range tests {
+		if got := simhashSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("simhashSimilarity(%x, %x) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// Текст без URL совпал с анализом страницы: ни источник той страницы, ни
+// её расход и поисковые запросы не должны попасть в новый ответ.
+func TestReusedResponseTextMatchesURLAnalysis(t *testing.T) {
+	stored := &models.AnalysisResponse{
+		AnalysisID:       42,
+		Summary:          "Плафонирование цен",
+		SourceURL:        "https://example.md/news/1",
+		FetchFallback:    "amp",
+		FetchedFrom:      "https://example.md/amp/news/1",
+		ArchivedAt:       "20240101000000",
+		SnapshotID:       "snap-1",
+		StealthEdit:      &models.EditEvent{},
+		Citations:        &models.CitationReport{Total: 3},
+		Coordinated:      &models.CoordinatedMatch{ClusterID: 7},
+		SourceDomain:     &models.DomainCuration{Domain: "example.md"},
+		CredibilityScore: 4,
+		FinalVerdict:     "В основном ложь",
+		SearchQueries:    []models.SearchQuery{{}},
+		Usage:            &models.TokenUsage{PromptTokens: 1000},
+		RawResponse:      "{...}",
+	}
+	match := &models.AnalysisMatch{AnalysisID: 42, Similarity: 96.9}
+
+	response := reusedResponse(stored, match)
+	analyzeInput{Text: simhashArticle}.applySource(response)
+
+	if response.SourceURL != "" || response.FetchFallback != "" || response.FetchedFrom != "" ||
+		response.ArchivedAt != "" || response.SnapshotID != "" {
+		t.Errorf("source of the matched page leaked: %+v", response)
+	}
+	if response.StealthEdit != nil || response.Citations != nil || response.SourceDomain != nil {
+		t.Error("page-specific reports leaked into a text analysis")
+	}
+	if response.AnalysisID != 0 || response.Coordinated != nil {
+		t.Error("stored analysis identity leaked")
+	}
+	if response.Usage != nil || response.SearchQueries != nil || response.RawResponse != "" {
+		t.Error("usage or search queries of the matched analysis leaked")
+	}
+	if response.CredibilityScore != 4 || response.FinalVerdict != stored.FinalVerdict || response.Summary != stored.Summary {
+		t.Error("assessment must be carried over")
+	}
+	if response.MatchedAnalysis != match {
+		t.Error("match must be reported")
+	}
+
+	// URL-запрос получает свой источник, а не источник найденного анализа
+	response = reusedResponse(stored, match)
+	analyzeInput{Text: simhashArticle, URL: "https://other.md/a"}.applySource(response)
+	if response.SourceURL != "https://other.md/a" || response.SnapshotID != "" {
+		t.Errorf("URL input: source_url=%q snapshot=%q", response.SourceURL, response.SnapshotID)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"text-analyzer/blobstore"
+	"text-analyzer/database"
+	"time"
+)
+
+// Snapshot — сохранённая копия страницы, на которой основан вердикт.
+// ID — sha256 сырого HTML (или текста, если HTML недоступен), поэтому
+// одинаковые страницы хранятся один раз.
+type Snapshot struct {
+	ID          string      `json:"id"`
+	URL         string      `json:"url"`
+	FinalURL    string      `json:"final_url,omitempty"`
+	FetchedAt   time.Time   `json:"fetched_at"`
+	StatusCode  int         `json:"status_code,omitempty"`
+	Headers     http.Header `json:"headers,omitempty"`
+	Fallback    string      `json:"fetch_fallback,omitempty"`
+	ArchivedAt  string      `json:"archived_at,omitempty"`
+	HasRawHTML  bool        `json:"has_raw_html"`
+	TextSHA256  string      `json:"text_sha256"`
+	Text        string      `json:"text"`
+	RawHTMLSize int         `json:"raw_html_size"`
+}
+
+// Заголовки, которые не нужны как доказательство и могут содержать чужие данные.
+var snapshotSkipHeaders = []string{"Set-Cookie", "Cookie", "Authorization"}
+
+func snapshotMetaKey(id string) string { return "snapshots/" + id + "/meta.json" }
+func snapshotRawKey(id string) string  { return "snapshots/" + id + "/page.html" }
+
+// SaveSnapshot сохраняет снимок загруженной страницы в хранилище и в таблицу snapshots.
+// Возвращает ID снимка или "" если хранилище не настроено.
+func SaveSnapshot(pageURL string, fetched *FetchResult) string {
+	store := blobstore.Default
+	if store == nil || fetched == nil {
+		return ""
+	}
+
+	textSum := sha256.Sum256([]byte(fetched.Text))
+	snap := Snapshot{
+		URL:        pageURL,
+		FinalURL:   fetched.URL,
+		FetchedAt:  time.Now().UTC(),
+		Fallback:   fetched.Fallback,
+		ArchivedAt: fetched.ArchivedAt,
+		TextSHA256: hex.EncodeToString(textSum[:]),
+		Text:       fetched.Text,
+	}
+
+	var raw []byte
+	if page := fetched.page; page != nil {
+		raw = page.Body
+		snap.FetchedAt = page.FetchedAt.UTC()
+		snap.StatusCode = page.StatusCode
+		snap.Headers = page.Header.Clone()
+		for _, h := range snapshotSkipHeaders {
+			snap.Headers.Del(h)
+		}
+	}
+	if len(raw) > 0 {
+		sum := sha256.Sum256(raw)
+		snap.ID = hex.EncodeToString(sum[:])
+		snap.HasRawHTML = true
+		snap.RawHTMLSize = len(raw)
+	} else {
+		snap.ID = snap.TextSHA256
+	}
+
+	exists, err := store.Exists(snapshotMetaKey(snap.ID))
+	if err != nil {
+		log.Printf("[SNAPSHOT] ⚠ Хранилище недоступно: %v", err)
+		return ""
+	}
+	if !exists {
+		if snap.HasRawHTML {
+			if err := store.Put(snapshotRawKey(snap.ID), raw, "text/html; charset=utf-8"); err != nil {
+				log.Printf("[SNAPSHOT] ⚠ Не удалось сохранить HTML: %v", err)
+				return ""
+			}
+		}
+		meta, _ := json.Marshal(snap)
+		if err := store.Put(snapshotMetaKey(snap.ID), meta, "application/json"); err != nil {
+			log.Printf("[SNAPSHOT] ⚠ Не удалось сохранить снимок: %v", err)
+			return ""
+		}
+		log.Printf("[SNAPSHOT] 📸 Снимок %s сохранён (%d байт HTML, %d символов текста)", snap.ID[:12], len(raw), len(snap.Text))
+	}
+
+	if database.DB != nil {
+		_, err := database.DB.Exec(`
+			INSERT INTO snapshots (id, url, final_url, text_sha256, fetched_at, last_seen_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (id) DO UPDATE SET last_seen_at = NOW()
+		`, snap.ID, snap.URL, snap.FinalURL, snap.TextSHA256, snap.FetchedAt)
+		if err != nil {
+			log.Printf("[SNAPSHOT] ⚠ Ошибка записи в БД: %v", err)
+		}
+	}
+	return snap.ID
+}
+
+// GetSnapshot читает метаданные и текст снимка.
+func GetSnapshot(id string) (*Snapshot, error) {
+	if blobstore.Default == nil {
+		return nil, fmt.Errorf("хранилище снимков не настроено")
+	}
+	data, err := blobstore.Default.Get(snapshotMetaKey(id))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("повреждённый снимок: %w", err)
+	}
+	return &snap, nil
+}
+
+// GetSnapshotRaw читает сырой HTML снимка.
+func GetSnapshotRaw(id string) ([]byte, error) {
+	if blobstore.Default == nil {
+		return nil, fmt.Errorf("хранилище снимков не настроено")
+	}
+	return blobstore.Default.Get(snapshotRawKey(id))
+}
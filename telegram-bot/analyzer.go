@@ -21,7 +21,12 @@ type AnalysisResult struct {
 	Summary          string   `json:"summary"`
 	Manipulations    []string `json:"manipulations"`
 	LogicalIssues    []string `json:"logical_issues"`
-	FactCheck        *struct {
+	Rating           *struct {
+		Label string `json:"label"`
+		Value int    `json:"value"`
+		Name  string `json:"name"`
+	} `json:"rating"`
+	FactCheck *struct {
 		MissingEvidence []string `json:"missing_evidence"`
 		OpinionsAsFacts []string `json:"opinions_as_facts"`
 		FoundEvidence   []string `json:"found_evidence"`
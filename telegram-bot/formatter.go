@@ -35,6 +35,20 @@ func FormatResult(r *AnalysisResult, sourceLabel string) string {
 		emoji = "🟢"
 		label = "ДОСТОВЕРНО"
 	}
+	// Вердикт на единой шкале (ложь … правда), если бэкенд его прислал
+	if r.Rating != nil && r.Rating.Name != "" && r.Rating.Label != "unknown" {
+		label = strings.ToUpper(r.Rating.Name)
+		switch {
+		case r.Rating.Value == 0:
+			emoji = "❓"
+		case r.Rating.Value <= 2:
+			emoji = "🔴"
+		case r.Rating.Value == 3:
+			emoji = "🟡"
+		default:
+			emoji = "🟢"
+		}
+	}
 
 	var b strings.Builder
 